@@ -3,17 +3,26 @@ package main
 import (
 	"flag"
 	"fmt"
+	"log"
 
 	"github.com/smallinsky/mtf/pkg/fswatch"
 )
 
 var (
-	addr = flag.String("addr", "host.docker.internal:4441", "watcher address")
-	dir  = flag.String("dir", ".", "dir path to watch")
+	addr     = flag.String("addr", "host.docker.internal:4441", "watcher address")
+	dir      = flag.String("dir", ".", "dir path to watch")
+	protocol = flag.String("protocol", "stream", "events protocol, stream or unary")
 )
 
 func main() {
 	flag.Parse()
 	fmt.Println("[INFO] watcher remote addr: ", *addr)
-	fswatch.Monitor(*addr, *dir)
+	switch *protocol {
+	case "stream":
+		fswatch.MonitorStream(*addr, *dir)
+	case "unary":
+		fswatch.Monitor(*addr, *dir)
+	default:
+		log.Fatalf("[ERR] unsupported %q protocol", *protocol)
+	}
 }
@@ -2,9 +2,41 @@
 
 addgroup -S $FTP_USER
 adduser -D -G $FTP_USER -h /ftp/ -s /bin/false  $FTP_USER
-
 echo "$FTP_USER:$FTP_PASS" | /usr/sbin/chpasswd
+
+# Additional users share the main user group and /ftp root dir.
+for entry in $FTP_USERS; do
+    user=${entry%%:*}
+    adduser -D -G $FTP_USER -h /ftp/ -s /bin/false $user
+    echo "$user:${entry#*:}" | /usr/sbin/chpasswd
+done
+
 chown $FTP_USER:$FTP_USER /ftp/ -R
+chmod 775 /ftp/
 
 /go/bin/fswatch --dir /ftp --addr $DOCKER_HOST_ADDR:4441 &
-/usr/sbin/vsftpd /etc/vsftpd/vsftpd.conf
+
+if [ "$FTP_PROTOCOL" = "sftp" ]; then
+    ssh-keygen -A
+    exec /usr/sbin/sshd -D -e -f /etc/ssh/sshd_config_sftp
+fi
+
+cat >> /etc/vsftpd/vsftpd.conf <<CONF
+pasv_min_port=${PASV_MIN_PORT:-21100}
+pasv_max_port=${PASV_MAX_PORT:-21110}
+CONF
+
+if [ "$FTP_PROTOCOL" = "ftps" ]; then
+    cat >> /etc/vsftpd/vsftpd.conf <<CONF
+ssl_enable=YES
+allow_anon_ssl=NO
+force_local_data_ssl=NO
+force_local_logins_ssl=NO
+require_ssl_reuse=NO
+ssl_ciphers=HIGH
+rsa_cert_file=/etc/vsftpd/cert/server.crt
+rsa_private_key_file=/etc/vsftpd/cert/server.key
+CONF
+fi
+
+exec /usr/sbin/vsftpd /etc/vsftpd/vsftpd.conf
@@ -34,8 +34,8 @@ type SuiteTest struct {
 
 func (st *SuiteTest) TestGCStorage(t *testing.T) {
 	st.gcsPort.Receive(t, &port.StorageGetRequest{
-		Bucket: "bucket/path",
-		Object: "file.txt",
+		Bucket: "bucket",
+		Object: "path/file.txt",
 	})
 
 	st.gcsPort.Send(t, &port.StorageGetResponse{
@@ -43,8 +43,8 @@ func (st *SuiteTest) TestGCStorage(t *testing.T) {
 	})
 
 	st.gcsPort.Receive(t, &port.StorageInsertRequest{
-		Bucket:  "bucket/path/bak",
-		Object:  "file.txt.bak",
+		Bucket:  "bucket",
+		Object:  "path/bak/file.txt.bak",
 		Content: []byte("awesomefile.txt file content"),
 	})
 
@@ -13,12 +13,12 @@ func main() {
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
 	defer cancel()
 
-	buff, err := read(ctx, "bucket/path", "file.txt")
+	buff, err := read(ctx, "bucket", "path/file.txt")
 	if err != nil {
 		log.Fatalf("[ERROR]f failed to read from bucket %v", err)
 	}
 
-	if err := write(ctx, "bucket/path/bak", "file.txt.bak", buff); err != nil {
+	if err := write(ctx, "bucket", "path/bak/file.txt.bak", buff); err != nil {
 		log.Fatalf("[ERROR]f failed to write to bucket %v", err)
 	}
 }
@@ -28,7 +28,7 @@ func read(ctx context.Context, bucket, file string) ([]byte, error) {
 	if err != nil {
 		return nil, err
 	}
-	object := c.Bucket("bucket/path").Object("file.txt")
+	object := c.Bucket(bucket).Object(file)
 	r, err := object.NewReader(ctx)
 	if err != nil {
 		return nil, err
@@ -0,0 +1,170 @@
+package fakegcs
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// ErrObjectNotExist is returned by handlers when requested object doesn't exist.
+var ErrObjectNotExist = &Error{Code: http.StatusNotFound, Message: "No such object"}
+
+// Error is a GCS JSON API error returned to the client.
+type Error struct {
+	// Code is a HTTP status code of the response.
+	Code int
+	// Message is a human readable error description.
+	Message string
+	// Reason is a GCS error reason, if empty it is derived from Code.
+	Reason string
+	// XMLCode is a XML API error code, if empty it is derived from Code.
+	XMLCode string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("gcs error %d: %s", e.Code, e.Message)
+}
+
+func (e *Error) reason() string {
+	if e.Reason != "" {
+		return e.Reason
+	}
+	switch e.Code {
+	case http.StatusBadRequest:
+		return "invalid"
+	case http.StatusUnauthorized:
+		return "required"
+	case http.StatusForbidden:
+		return "forbidden"
+	case http.StatusNotFound:
+		return "notFound"
+	case http.StatusConflict:
+		return "conflict"
+	case http.StatusPreconditionFailed:
+		return "conditionNotMet"
+	case http.StatusTooManyRequests:
+		return "rateLimitExceeded"
+	default:
+		return "backendError"
+	}
+}
+
+type errorItem struct {
+	Domain  string `json:"domain"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+type errorResource struct {
+	Error struct {
+		Code    int         `json:"code"`
+		Message string      `json:"message"`
+		Errors  []errorItem `json:"errors"`
+	} `json:"error"`
+}
+
+// writeError responds with GCS JSON error matching handler error.
+func writeError(w http.ResponseWriter, err error) {
+	gerr, ok := err.(*Error)
+	if !ok {
+		gerr = &Error{Code: http.StatusInternalServerError, Message: err.Error()}
+	}
+
+	var res errorResource
+	res.Error.Code = gerr.Code
+	res.Error.Message = gerr.Message
+	res.Error.Errors = []errorItem{{
+		Domain:  "global",
+		Reason:  gerr.reason(),
+		Message: gerr.Message,
+	}}
+
+	w.Header().Set("Content-Type", "application/json; charset=UTF-8")
+	w.WriteHeader(gerr.Code)
+	json.NewEncoder(w).Encode(&res)
+}
+
+// Conditions are write request preconditions, nil value means that condition wasn't set.
+type Conditions struct {
+	IfGenerationMatch        *int64
+	IfGenerationNotMatch     *int64
+	IfMetagenerationMatch    *int64
+	IfMetagenerationNotMatch *int64
+}
+
+func (c Conditions) empty() bool {
+	return c.IfGenerationMatch == nil && c.IfGenerationNotMatch == nil &&
+		c.IfMetagenerationMatch == nil && c.IfMetagenerationNotMatch == nil
+}
+
+// Check validates conditions against current object attributes, attrs is nil when
+// object doesn't exist.
+func (c Conditions) Check(attrs *ObjectAttrs) error {
+	var gen, metagen int64
+	if attrs != nil {
+		gen, metagen = attrs.Generation, attrs.Metageneration
+	}
+	failed := func(name string) error {
+		return &Error{
+			Code:    http.StatusPreconditionFailed,
+			Message: fmt.Sprintf("At least one of the pre-conditions you specified did not hold: %s", name),
+		}
+	}
+
+	if c.IfGenerationMatch != nil && *c.IfGenerationMatch != gen {
+		return failed("ifGenerationMatch")
+	}
+	if c.IfGenerationNotMatch != nil && attrs != nil && *c.IfGenerationNotMatch == gen {
+		return failed("ifGenerationNotMatch")
+	}
+	if c.IfMetagenerationMatch != nil && (attrs == nil || *c.IfMetagenerationMatch != metagen) {
+		return failed("ifMetagenerationMatch")
+	}
+	if c.IfMetagenerationNotMatch != nil && attrs != nil && *c.IfMetagenerationNotMatch == metagen {
+		return failed("ifMetagenerationNotMatch")
+	}
+	return nil
+}
+
+func parseConditions(q url.Values) (Conditions, error) {
+	var c Conditions
+	for _, v := range []struct {
+		name string
+		dst  **int64
+	}{
+		{"ifGenerationMatch", &c.IfGenerationMatch},
+		{"ifGenerationNotMatch", &c.IfGenerationNotMatch},
+		{"ifMetagenerationMatch", &c.IfMetagenerationMatch},
+		{"ifMetagenerationNotMatch", &c.IfMetagenerationNotMatch},
+	} {
+		s := q.Get(v.name)
+		if s == "" {
+			continue
+		}
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return c, &Error{Code: http.StatusBadRequest, Message: fmt.Sprintf("invalid %s value %q", v.name, s)}
+		}
+		*v.dst = &n
+	}
+	return c, nil
+}
+
+// checkConditions validates request preconditions using OnPreconditions handler.
+func (f *GCStorage) checkConditions(bo BucketObject, c Conditions) error {
+	if c.empty() || f.OnPreconditions == nil {
+		return nil
+	}
+	return f.OnPreconditions(bo, c)
+}
+
+// checkRequestConditions validates preconditions passed in request query.
+func (f *GCStorage) checkRequestConditions(r *http.Request, bo BucketObject) error {
+	c, err := parseConditions(r.URL.Query())
+	if err != nil {
+		return err
+	}
+	return f.checkConditions(bo, c)
+}
@@ -1,11 +1,15 @@
 package fakegcs
 
 import (
+	"crypto/rsa"
 	"encoding/json"
 	"io"
+	"io/ioutil"
 	"mime"
 	"mime/multipart"
 	"net/http"
+	"strconv"
+	"sync"
 
 	"github.com/gorilla/mux"
 )
@@ -17,8 +21,28 @@ var (
 )
 
 type GCStorage struct {
-	OnObjectInsert func(BucketObject, io.Reader) error
-	OnObjectGet    func(BucketObject, io.Writer) error
+	// OnObjectInsert receives attrs of the uploaded object and its content, returned attrs
+	// e.g. with generation assigned by a store are sent to the client. When nil attrs are
+	// returned the received ones are sent.
+	OnObjectInsert func(ObjectAttrs, io.Reader) (*ObjectAttrs, error)
+	// OnObjectInsertIf is used instead of OnObjectInsert when set, it receives write preconditions
+	// not checked with OnPreconditions, so a store can check them and write the object atomically.
+	OnObjectInsertIf func(ObjectAttrs, Conditions, io.Reader) (*ObjectAttrs, error)
+	OnObjectGet      func(BucketObject, io.Writer) error
+	OnObjectList     func(ListQuery) ([]ObjectAttrs, error)
+	OnObjectAttrs    func(BucketObject) (*ObjectAttrs, error)
+	OnObjectDelete   func(BucketObject) error
+	OnObjectCopy     func(src, dst BucketObject) (*ObjectAttrs, error)
+	OnObjectCompose  func(dst BucketObject, srcs []BucketObject) (*ObjectAttrs, error)
+	// OnPreconditions validates write request preconditions, when not set preconditions are ignored.
+	OnPreconditions func(BucketObject, Conditions) error
+	// SigningKey returns public key used to verify signed URLs issued by the service account
+	// access id. When not set or nil key is returned signed URLs are rejected, since without
+	// the signature the URL method can't be verified.
+	SigningKey func(accessID string) *rsa.PublicKey
+
+	sessionsMtx sync.Mutex
+	sessions    map[string]*uploadSession
 }
 
 func (f *GCStorage) handleInsert(w http.ResponseWriter, r *http.Request) {
@@ -26,17 +50,23 @@ func (f *GCStorage) handleInsert(w http.ResponseWriter, r *http.Request) {
 	switch vars["uploadType"] {
 	case "multipart":
 		if err := f.handleMultipart(w, r); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			writeError(w, err)
 			return
 		}
-		w.WriteHeader(http.StatusNoContent)
-
+	case "media":
+		f.handleMedia(w, r)
+	case "resumable":
+		f.handleResumable(w, r)
 	default:
 		http.Error(w, "not supported content", http.StatusBadRequest)
 	}
 }
 
 func (f *GCStorage) handleMultipart(rw http.ResponseWriter, r *http.Request) error {
+	conds, err := parseConditions(r.URL.Query())
+	if err != nil {
+		return err
+	}
 	_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
 	if err != nil {
 		return err
@@ -52,37 +82,210 @@ func (f *GCStorage) handleMultipart(rw http.ResponseWriter, r *http.Request) err
 	if err := json.NewDecoder(partMeta).Decode(&obj); err != nil {
 		return err
 	}
+	bo := BucketObject{
+		Object: obj.Object,
+		Bucket: obj.Bucket,
+	}
 	partContent, err := reader.NextPart()
 	if err != nil {
 		if err == io.EOF {
+			f.insertObject(rw, bo, obj.ContentType, nil, conds)
 			return nil
 		}
 		return err
 	}
 
 	defer partContent.Close()
-	if f.OnObjectInsert != nil {
-		f.OnObjectInsert(BucketObject{
-			Object: obj.Object,
-			Bucket: obj.Bucket,
-		}, partContent)
+	content, err := ioutil.ReadAll(partContent)
+	if err != nil {
+		return err
 	}
+	f.insertObject(rw, bo, partContent.Header.Get("Content-Type"), content, conds)
 	return nil
 }
 
 type meta struct {
-	Bucket string `json:"bucket"`
-	Object string `json:"name"`
+	Bucket      string `json:"bucket"`
+	Object      string `json:"name"`
+	ContentType string `json:"contentType"`
 }
 
 func (f *GCStorage) handleGet(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
 	if f.OnObjectGet != nil {
-		f.OnObjectGet(BucketObject{
+		err := f.OnObjectGet(BucketObject{
 			Bucket: vars["bucket"],
 			Object: vars["object"],
 		}, w)
+		if err != nil {
+			writeError(w, err)
+		}
+	}
+}
+
+func (f *GCStorage) handleList(w http.ResponseWriter, r *http.Request) {
+	if f.OnObjectList == nil {
+		http.Error(w, "objects.list is not supported", http.StatusNotImplemented)
+		return
+	}
+	q := r.URL.Query()
+	query := ListQuery{
+		Bucket:    mux.Vars(r)["bucket"],
+		Prefix:    q.Get("prefix"),
+		Delimiter: q.Get("delimiter"),
+		PageToken: q.Get("pageToken"),
+	}
+	if v := q.Get("maxResults"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil {
+			http.Error(w, "invalid maxResults value", http.StatusBadRequest)
+			return
+		}
+		query.MaxResults = n
+	}
+
+	objects, err := f.OnObjectList(query)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	for i := range objects {
+		objects[i].Bucket = query.Bucket
+	}
+	writeJSON(w, FilterObjects(objects, query).toResource())
+}
+
+func (f *GCStorage) handleAttrs(w http.ResponseWriter, r *http.Request) {
+	if f.OnObjectAttrs == nil {
+		http.Error(w, "objects.get is not supported", http.StatusNotImplemented)
+		return
+	}
+	bo := objectFromVars(r, "bucket", "object")
+	attrs, err := f.OnObjectAttrs(bo)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if attrs == nil {
+		writeError(w, ErrObjectNotExist)
+		return
+	}
+	writeJSON(w, attrs.withLocation(bo).toResource())
+}
+
+func (f *GCStorage) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if f.OnObjectDelete == nil {
+		http.Error(w, "objects.delete is not supported", http.StatusNotImplemented)
+		return
+	}
+	bo := objectFromVars(r, "bucket", "object")
+	if err := f.checkRequestConditions(r, bo); err != nil {
+		writeError(w, err)
+		return
+	}
+	if err := f.OnObjectDelete(bo); err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *GCStorage) copyObject(w http.ResponseWriter, r *http.Request) (*ObjectAttrs, bool) {
+	if f.OnObjectCopy == nil {
+		http.Error(w, "objects.copy is not supported", http.StatusNotImplemented)
+		return nil, false
+	}
+	dst := objectFromVars(r, "dstBucket", "dstObject")
+	if err := f.checkRequestConditions(r, dst); err != nil {
+		writeError(w, err)
+		return nil, false
+	}
+	attrs, err := f.OnObjectCopy(objectFromVars(r, "srcBucket", "srcObject"), dst)
+	if err != nil {
+		writeError(w, err)
+		return nil, false
+	}
+	if attrs == nil {
+		attrs = &ObjectAttrs{}
+	}
+	return attrs.withLocation(dst), true
+}
+
+func (f *GCStorage) handleCopy(w http.ResponseWriter, r *http.Request) {
+	if attrs, ok := f.copyObject(w, r); ok {
+		writeJSON(w, attrs.toResource())
+	}
+}
+
+func (f *GCStorage) handleRewrite(w http.ResponseWriter, r *http.Request) {
+	attrs, ok := f.copyObject(w, r)
+	if !ok {
+		return
+	}
+	size := strconv.FormatInt(attrs.Size, 10)
+	writeJSON(w, rewriteResource{
+		Kind:                "storage#rewriteResponse",
+		TotalBytesRewritten: size,
+		ObjectSize:          size,
+		Done:                true,
+		Resource:            attrs.toResource(),
+	})
+}
+
+func (f *GCStorage) handleCompose(w http.ResponseWriter, r *http.Request) {
+	if f.OnObjectCompose == nil {
+		http.Error(w, "objects.compose is not supported", http.StatusNotImplemented)
+		return
+	}
+	defer r.Body.Close()
+	var req composeRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	dst := objectFromVars(r, "bucket", "object")
+	if err := f.checkRequestConditions(r, dst); err != nil {
+		writeError(w, err)
+		return
+	}
+	var srcs []BucketObject
+	for _, so := range req.SourceObjects {
+		srcs = append(srcs, BucketObject{Bucket: dst.Bucket, Object: so.Name})
+	}
+	attrs, err := f.OnObjectCompose(dst, srcs)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if attrs == nil {
+		attrs = &ObjectAttrs{}
+	}
+	writeJSON(w, attrs.withLocation(dst).toResource())
+}
+
+func objectFromVars(r *http.Request, bucket, object string) BucketObject {
+	vars := mux.Vars(r)
+	return BucketObject{
+		Bucket: vars[bucket],
+		Object: vars[object],
+	}
+}
+
+func (o *ObjectAttrs) withLocation(bo BucketObject) *ObjectAttrs {
+	out := *o
+	out.Bucket = bo.Bucket
+	if out.Name == "" {
+		out.Name = bo.Object
+	}
+	return &out
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
 	}
 }
 
@@ -93,7 +296,7 @@ type tokenJSON struct {
 	ExpiresIn    int64  `json:"expires_in"`
 }
 
-func (f GCStorage) handleToken(w http.ResponseWriter, r *http.Request) {
+func (f *GCStorage) handleToken(w http.ResponseWriter, r *http.Request) {
 	tj := tokenJSON{
 		AccessToken:  "access-token",
 		TokenType:    "Bearer",
@@ -107,10 +310,56 @@ func (f GCStorage) handleToken(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func (f GCStorage) AddMuxRoute(r *mux.Router) *mux.Router {
-	r.Host(StorageHost).Path("/{bucket:.+}/{object}").Methods(http.MethodGet).HandlerFunc(f.handleGet)
-	r.Host(StorageHost).Path("/b/{bucket:.+}/o").Queries("uploadType", "{uploadType}").Methods(http.MethodPost).HandlerFunc(f.handleInsert)
-	r.Host(GoogleAPIHost).Path("/upload/storage/v1/b/{bucket:.+}/o").Queries("uploadType", "{uploadType}").Methods(http.MethodPost).HandlerFunc(f.handleInsert)
+// jsonAPIBases is a list of host and path prefix pairs under which JSON API is served.
+func jsonAPIBases() []struct{ host, prefix string } {
+	return []struct{ host, prefix string }{
+		{StorageHost, ""},
+		{StorageHost, "/storage/v1"},
+		{GoogleAPIHost, "/storage/v1"},
+	}
+}
+
+func (f *GCStorage) addJSONAPIRoutes(r *mux.Router) {
+	const (
+		object   = "/b/{bucket:.+?}/o/{object:.+}"
+		src      = "/b/{srcBucket:.+?}/o/{srcObject:.+?}"
+		dst      = "/b/{dstBucket:.+?}/o/{dstObject:.+}"
+		compose  = "/b/{bucket:.+?}/o/{object:.+?}/compose"
+		copyTo   = src + "/copyTo" + dst
+		rewrite  = src + "/rewriteTo" + dst
+		listPath = "/b/{bucket:.+}/o"
+	)
+	for _, api := range jsonAPIBases() {
+		r.Host(api.host).Path(api.prefix + copyTo).Methods(http.MethodPost).HandlerFunc(f.handleCopy)
+		r.Host(api.host).Path(api.prefix + rewrite).Methods(http.MethodPost).HandlerFunc(f.handleRewrite)
+		r.Host(api.host).Path(api.prefix + compose).Methods(http.MethodPost).HandlerFunc(f.handleCompose)
+		r.Host(api.host).Path(api.prefix+object).Queries("alt", "media").Methods(http.MethodGet).HandlerFunc(f.handleGet)
+		r.Host(api.host).Path(api.prefix + object).Methods(http.MethodGet).HandlerFunc(f.handleAttrs)
+		r.Host(api.host).Path(api.prefix + object).Methods(http.MethodDelete).HandlerFunc(f.handleDelete)
+		r.Host(api.host).Path(api.prefix + listPath).Methods(http.MethodGet).HandlerFunc(f.handleList)
+	}
+}
+
+func (f *GCStorage) addXMLAPIRoutes(r *mux.Router) {
+	const object = "/{bucket:[^/]+}/{object:.+}"
+	r.Host(StorageHost).Path(object).Methods(http.MethodGet).HandlerFunc(f.handleXMLGet)
+	r.Host(StorageHost).Path(object).Methods(http.MethodHead).HandlerFunc(f.handleXMLHead)
+	r.Host(StorageHost).Path(object).Methods(http.MethodPut).HandlerFunc(f.handleXMLPut)
+	r.Host(StorageHost).Path(object).Methods(http.MethodDelete).HandlerFunc(f.handleXMLDelete)
+}
+
+func (f *GCStorage) AddMuxRoute(r *mux.Router) *mux.Router {
+	f.addJSONAPIRoutes(r)
+	for _, up := range []struct{ host, path string }{
+		{StorageHost, "/b/{bucket:.+}/o"},
+		{StorageHost, "/upload/storage/v1/b/{bucket:.+}/o"},
+		{GoogleAPIHost, "/upload/storage/v1/b/{bucket:.+}/o"},
+	} {
+		r.Host(up.host).Path(up.path).Queries("uploadType", "{uploadType}").Methods(http.MethodPost).HandlerFunc(f.handleInsert)
+		r.Host(up.host).Path(up.path).Queries("upload_id", "{uploadID}").Methods(http.MethodPut).HandlerFunc(f.handleChunk)
+	}
+	// XML API object path matches any other path so it has to be registered after JSON API routes.
+	f.addXMLAPIRoutes(r)
 	r.Host(OAuth2Host).Path("/token").Methods(http.MethodPost).HandlerFunc(f.handleToken)
 	return r
 }
@@ -8,6 +8,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
@@ -15,15 +16,17 @@ import (
 	"cloud.google.com/go/storage"
 	"github.com/gorilla/mux"
 	"golang.org/x/oauth2"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/iterator"
 	"google.golang.org/api/option"
 )
 
 func TestStorageInsert(t *testing.T) {
 	sync := make(chan struct{})
 	fakeStorage := &GCStorage{
-		OnObjectInsert: func(o BucketObject, r io.Reader) error {
+		OnObjectInsert: func(attrs ObjectAttrs, r io.Reader) (*ObjectAttrs, error) {
 			close(sync)
-			return nil
+			return nil, nil
 		},
 	}
 	r := mux.NewRouter()
@@ -136,3 +139,383 @@ func (ts *tokenSupplier) Token() (*oauth2.Token, error) {
 		Expiry:       time.Now().Add(time.Hour),
 	}, nil
 }
+
+func newTestClient(t *testing.T, fakeStorage *GCStorage) (*storage.Client, func()) {
+	r := mux.NewRouter()
+	StorageHost = "{[0-9]:.+}"
+	r = fakeStorage.AddMuxRoute(r)
+	cst := httptest.NewServer(r)
+
+	readHostEnv := strings.Replace(cst.URL, "http://", "", -1)
+	if err := os.Setenv("STORAGE_EMULATOR_HOST", readHostEnv); err != nil {
+		t.Fatalf("failed to set readHost env: %v", err)
+	}
+
+	hc := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: new(tokenSupplier),
+		},
+	}
+	opts := []option.ClientOption{option.WithHTTPClient(hc), option.WithEndpoint(cst.URL)}
+	sc, err := storage.NewClient(context.Background(), opts...)
+	if err != nil {
+		t.Fatalf("Failed to create storage client: %v", err)
+	}
+	return sc, func() {
+		sc.Close()
+		cst.Close()
+	}
+}
+
+func TestObjectList(t *testing.T) {
+	fakeStorage := &GCStorage{
+		OnObjectList: func(q ListQuery) ([]ObjectAttrs, error) {
+			if got, want := q.Bucket, "bucket"; got != want {
+				t.Errorf("bucket mismatch, got: %v want: %v", got, want)
+			}
+			return []ObjectAttrs{
+				{Name: "in/a.txt", Size: 1},
+				{Name: "in/b.txt", Size: 2},
+				{Name: "in/sub/c.txt", Size: 3},
+				{Name: "out/d.txt", Size: 4},
+			}, nil
+		},
+	}
+	sc, cleanup := newTestClient(t, fakeStorage)
+	defer cleanup()
+
+	it := sc.Bucket("bucket").Objects(context.Background(), &storage.Query{
+		Prefix:    "in/",
+		Delimiter: "/",
+	})
+	var got []string
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			t.Fatalf("got list error: %v", err)
+		}
+		if attrs.Prefix != "" {
+			got = append(got, attrs.Prefix)
+			continue
+		}
+		got = append(got, attrs.Name)
+	}
+
+	if want := []string{"in/a.txt", "in/b.txt", "in/sub/"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("list mismatch, got: %v want: %v", got, want)
+	}
+}
+
+func TestFilterObjectsPaging(t *testing.T) {
+	objects := []ObjectAttrs{{Name: "a"}, {Name: "b/1"}, {Name: "b/2"}, {Name: "c"}}
+	q := ListQuery{Delimiter: "/", MaxResults: 2}
+
+	page := FilterObjects(objects, q)
+	if got, want := len(page.Objects)+len(page.Prefixes), 2; got != want {
+		t.Fatalf("page size mismatch, got: %v want: %v", got, want)
+	}
+	if got, want := page.NextPageToken, "b/"; got != want {
+		t.Fatalf("page token mismatch, got: %v want: %v", got, want)
+	}
+
+	q.PageToken = page.NextPageToken
+	page = FilterObjects(objects, q)
+	if len(page.Objects) != 1 || page.Objects[0].Name != "c" || page.NextPageToken != "" {
+		t.Fatalf("unexpected second page: %+v", page)
+	}
+}
+
+func TestObjectAttrsAndDelete(t *testing.T) {
+	var deleted BucketObject
+	fakeStorage := &GCStorage{
+		OnObjectAttrs: func(bo BucketObject) (*ObjectAttrs, error) {
+			if bo.Object != "dir/file.txt" {
+				return nil, nil
+			}
+			return &ObjectAttrs{
+				ContentType: "text/plain",
+				Size:        42,
+				MD5:         []byte("0123456789abcdef"),
+				Generation:  7,
+			}, nil
+		},
+		OnObjectDelete: func(bo BucketObject) error {
+			deleted = bo
+			return nil
+		},
+	}
+	sc, cleanup := newTestClient(t, fakeStorage)
+	defer cleanup()
+	ctx := context.Background()
+
+	attrs, err := sc.Bucket("bucket").Object("dir/file.txt").Attrs(ctx)
+	if err != nil {
+		t.Fatalf("got attrs error: %v", err)
+	}
+	if attrs.Size != 42 || attrs.ContentType != "text/plain" || attrs.Generation != 7 || string(attrs.MD5) != "0123456789abcdef" {
+		t.Fatalf("unexpected attrs: %+v", attrs)
+	}
+
+	if _, err := sc.Bucket("bucket").Object("missing.txt").Attrs(ctx); err != storage.ErrObjectNotExist {
+		t.Fatalf("expected object not exist error, got: %v", err)
+	}
+
+	if err := sc.Bucket("bucket").Object("dir/file.txt").Delete(ctx); err != nil {
+		t.Fatalf("got delete error: %v", err)
+	}
+	if want := (BucketObject{Bucket: "bucket", Object: "dir/file.txt"}); deleted != want {
+		t.Fatalf("delete mismatch, got: %v want: %v", deleted, want)
+	}
+}
+
+func TestObjectCopyAndCompose(t *testing.T) {
+	var copied [2]BucketObject
+	var composed []BucketObject
+	fakeStorage := &GCStorage{
+		OnObjectCopy: func(src, dst BucketObject) (*ObjectAttrs, error) {
+			copied = [2]BucketObject{src, dst}
+			return &ObjectAttrs{Size: 10}, nil
+		},
+		OnObjectCompose: func(dst BucketObject, srcs []BucketObject) (*ObjectAttrs, error) {
+			composed = srcs
+			return &ObjectAttrs{Size: 20}, nil
+		},
+	}
+	sc, cleanup := newTestClient(t, fakeStorage)
+	defer cleanup()
+	ctx := context.Background()
+
+	dst := sc.Bucket("archive").Object("out/file.txt")
+	attrs, err := dst.CopierFrom(sc.Bucket("inbox").Object("in/file.txt")).Run(ctx)
+	if err != nil {
+		t.Fatalf("got copy error: %v", err)
+	}
+	if attrs.Size != 10 || attrs.Bucket != "archive" || attrs.Name != "out/file.txt" {
+		t.Fatalf("unexpected copy attrs: %+v", attrs)
+	}
+	want := [2]BucketObject{{Bucket: "inbox", Object: "in/file.txt"}, {Bucket: "archive", Object: "out/file.txt"}}
+	if copied != want {
+		t.Fatalf("copy mismatch, got: %v want: %v", copied, want)
+	}
+
+	b := sc.Bucket("bucket")
+	attrs, err = b.Object("all.txt").ComposerFrom(b.Object("part1"), b.Object("part2")).Run(ctx)
+	if err != nil {
+		t.Fatalf("got compose error: %v", err)
+	}
+	if attrs.Size != 20 {
+		t.Fatalf("unexpected compose attrs: %+v", attrs)
+	}
+	if got := len(composed); got != 2 || composed[1].Object != "part2" {
+		t.Fatalf("unexpected compose sources: %v", composed)
+	}
+}
+
+func TestResumableUpload(t *testing.T) {
+	var got []byte
+	fakeStorage := &GCStorage{
+		OnObjectInsert: func(attrs ObjectAttrs, r io.Reader) (*ObjectAttrs, error) {
+			var err error
+			got, err = ioutil.ReadAll(r)
+			return nil, err
+		},
+	}
+	sc, cleanup := newTestClient(t, fakeStorage)
+	defer cleanup()
+
+	content := []byte(strings.Repeat("0123456789", 60*1024))
+	w := sc.Bucket("bucket").Object("large.bin").NewWriter(context.Background())
+	w.ChunkSize = 256 * 1024
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("got write error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("got close error: %v", err)
+	}
+
+	if !reflect.DeepEqual(got, content) {
+		t.Fatalf("content mismatch, got %d bytes want %d bytes", len(got), len(content))
+	}
+	if got, want := w.Attrs().Size, int64(len(content)); got != want {
+		t.Fatalf("size mismatch, got: %v want: %v", got, want)
+	}
+}
+
+func TestResumableUploadStatusQuery(t *testing.T) {
+	var got []byte
+	fakeStorage := &GCStorage{
+		OnObjectInsert: func(attrs ObjectAttrs, r io.Reader) (*ObjectAttrs, error) {
+			var err error
+			got, err = ioutil.ReadAll(r)
+			return nil, err
+		},
+	}
+	r := mux.NewRouter()
+	StorageHost = "{[0-9]:.+}"
+	cst := httptest.NewServer(fakeStorage.AddMuxRoute(r))
+	defer cst.Close()
+
+	resp, err := http.Post(cst.URL+"/upload/storage/v1/b/bucket/o?uploadType=resumable&name=file.txt", "application/json", nil)
+	if err != nil {
+		t.Fatalf("failed to initiate upload: %v", err)
+	}
+	session := resp.Header.Get("Location")
+	if resp.StatusCode != http.StatusOK || session == "" {
+		t.Fatalf("unexpected initiate response: %v %q", resp.Status, session)
+	}
+
+	put := func(body, contentRange string) *http.Response {
+		req, _ := http.NewRequest(http.MethodPut, session, strings.NewReader(body))
+		req.Header.Set("Content-Range", contentRange)
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("failed to upload chunk: %v", err)
+		}
+		return resp
+	}
+
+	resp = put("hello ", "bytes 0-5/*")
+	if resp.StatusCode != http.StatusPermanentRedirect || resp.Header.Get("Range") != "bytes=0-5" {
+		t.Fatalf("unexpected chunk response: %v %q", resp.Status, resp.Header.Get("Range"))
+	}
+	resp = put("", "bytes */*")
+	if resp.StatusCode != http.StatusPermanentRedirect || resp.Header.Get("Range") != "bytes=0-5" {
+		t.Fatalf("unexpected status response: %v %q", resp.Status, resp.Header.Get("Range"))
+	}
+	resp = put(" world", "bytes 5-10/11")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected final response: %v", resp.Status)
+	}
+	if got, want := string(got), "hello world"; got != want {
+		t.Fatalf("content mismatch, got: %q want: %q", got, want)
+	}
+}
+
+func TestMediaUpload(t *testing.T) {
+	var (
+		gotObject BucketObject
+		got       []byte
+	)
+	fakeStorage := &GCStorage{
+		OnObjectInsert: func(attrs ObjectAttrs, r io.Reader) (*ObjectAttrs, error) {
+			gotObject = BucketObject{Bucket: attrs.Bucket, Object: attrs.Name}
+			var err error
+			got, err = ioutil.ReadAll(r)
+			return nil, err
+		},
+	}
+	r := mux.NewRouter()
+	StorageHost = "{[0-9]:.+}"
+	cst := httptest.NewServer(fakeStorage.AddMuxRoute(r))
+	defer cst.Close()
+
+	resp, err := http.Post(cst.URL+"/upload/storage/v1/b/bucket/o?uploadType=media&name=dir%2Ffile.txt", "text/plain", strings.NewReader("media content"))
+	if err != nil {
+		t.Fatalf("failed to upload: %v", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("unexpected response status: %v", resp.Status)
+	}
+	if want := (BucketObject{Bucket: "bucket", Object: "dir/file.txt"}); gotObject != want {
+		t.Fatalf("object mismatch, got: %v want: %v", gotObject, want)
+	}
+	if got, want := string(got), "media content"; got != want {
+		t.Fatalf("content mismatch, got: %q want: %q", got, want)
+	}
+}
+
+func TestWritePreconditions(t *testing.T) {
+	store := NewStore()
+	attrs := store.Put("bucket", "file.txt", "text/plain", []byte("v1"))
+	fakeStorage := &GCStorage{
+		OnObjectInsert: func(attrs ObjectAttrs, r io.Reader) (*ObjectAttrs, error) {
+			content, err := ioutil.ReadAll(r)
+			if err != nil {
+				return nil, err
+			}
+			stored := store.Put(attrs.Bucket, attrs.Name, attrs.ContentType, content)
+			return &stored, nil
+		},
+		OnPreconditions: store.CheckConditions,
+	}
+	sc, cleanup := newTestClient(t, fakeStorage)
+	defer cleanup()
+	ctx := context.Background()
+
+	write := func(content string, cond storage.Conditions) (*storage.ObjectAttrs, error) {
+		w := sc.Bucket("bucket").Object("file.txt").If(cond).NewWriter(ctx)
+		w.ContentType = "text/csv"
+		if _, err := w.Write([]byte(content)); err != nil {
+			return nil, err
+		}
+		if err := w.Close(); err != nil {
+			return nil, err
+		}
+		return w.Attrs(), nil
+	}
+
+	_, err := write("v2", storage.Conditions{DoesNotExist: true})
+	if e, ok := err.(*googleapi.Error); !ok || e.Code != http.StatusPreconditionFailed {
+		t.Fatalf("expected precondition failed error, got: %v", err)
+	}
+	written, err := write("v2", storage.Conditions{GenerationMatch: attrs.Generation})
+	if err != nil {
+		t.Fatalf("got write error: %v", err)
+	}
+	obj, _ := store.Object("bucket", "file.txt")
+	if got, want := string(obj.Content), "v2"; got != want {
+		t.Fatalf("content mismatch, got: %q want: %q", got, want)
+	}
+	if obj.Attrs.ContentType != "text/csv" {
+		t.Fatalf("content type mismatch, got: %q", obj.Attrs.ContentType)
+	}
+	if written.Generation != obj.Attrs.Generation || written.Metageneration != 1 {
+		t.Fatalf("returned generation mismatch, got: %v/%v want: %v/1", written.Generation, written.Metageneration, obj.Attrs.Generation)
+	}
+	// Generation returned by the insert call can be used as the next write precondition.
+	if _, err := write("v3", storage.Conditions{GenerationMatch: written.Generation}); err != nil {
+		t.Fatalf("got write error: %v", err)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	fakeStorage := &GCStorage{
+		OnObjectAttrs: func(bo BucketObject) (*ObjectAttrs, error) {
+			return nil, &Error{Code: http.StatusForbidden, Message: "access denied"}
+		},
+	}
+	sc, cleanup := newTestClient(t, fakeStorage)
+	defer cleanup()
+
+	_, err := sc.Bucket("bucket").Object("file.txt").Attrs(context.Background())
+	e, ok := err.(*googleapi.Error)
+	if !ok {
+		t.Fatalf("expected googleapi error, got: %T %v", err, err)
+	}
+	if e.Code != http.StatusForbidden || e.Message != "access denied" || len(e.Errors) != 1 || e.Errors[0].Reason != "forbidden" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+}
+
+func TestNotSupported(t *testing.T) {
+	srv := newTestServer(&GCStorage{})
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		method string
+		url    string
+	}{
+		{http.MethodGet, "https://storage.googleapis.com/storage/v1/b/bucket/o"},
+		{http.MethodDelete, "https://storage.googleapis.com/storage/v1/b/bucket/o/file.txt"},
+		{http.MethodDelete, "https://storage.googleapis.com/bucket/file.txt"},
+	} {
+		resp := do(t, srv, tc.method, tc.url, nil, nil)
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusNotImplemented {
+			t.Fatalf("%s %s: expected not implemented status, got: %v", tc.method, tc.url, resp.Status)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+package fakegcs
+
+import (
+	"encoding/base64"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/smallinsky/mtf/fake/internal/listing"
+)
+
+const defaultMaxResults = 1000
+
+// ObjectAttrs describes bucket object metadata returned to the client.
+type ObjectAttrs struct {
+	Bucket         string
+	Name           string
+	ContentType    string
+	Size           int64
+	MD5            []byte
+	CRC32C         uint32
+	Generation     int64
+	Metageneration int64
+	Metadata       map[string]string
+	Created        time.Time
+	Updated        time.Time
+}
+
+// ListQuery is a objects.list call query.
+type ListQuery struct {
+	Bucket     string
+	Prefix     string
+	Delimiter  string
+	PageToken  string
+	MaxResults int
+}
+
+// ObjectList is a single page of objects.list call.
+type ObjectList struct {
+	Objects       []ObjectAttrs
+	Prefixes      []string
+	NextPageToken string
+}
+
+// FilterObjects applies query prefix, delimiter and paging rules to objects collection.
+func FilterObjects(objects []ObjectAttrs, q ListQuery) ObjectList {
+	max := q.MaxResults
+	if max <= 0 {
+		max = defaultMaxResults
+	}
+	names := make([]string, len(objects))
+	for i, o := range objects {
+		names[i] = o.Name
+	}
+	page := listing.List(names, listing.Query{
+		Prefix:    q.Prefix,
+		Delimiter: q.Delimiter,
+		After:     q.PageToken,
+		Max:       max,
+	})
+
+	out := ObjectList{
+		Prefixes:      page.Prefixes,
+		NextPageToken: page.Next,
+	}
+	for _, i := range page.Objects {
+		out.Objects = append(out.Objects, objects[i])
+	}
+	return out
+}
+
+type objectResource struct {
+	Kind           string            `json:"kind"`
+	ID             string            `json:"id"`
+	Name           string            `json:"name"`
+	Bucket         string            `json:"bucket"`
+	Generation     string            `json:"generation,omitempty"`
+	Metageneration string            `json:"metageneration,omitempty"`
+	ContentType    string            `json:"contentType,omitempty"`
+	Size           string            `json:"size"`
+	MD5Hash        string            `json:"md5Hash,omitempty"`
+	CRC32C         string            `json:"crc32c,omitempty"`
+	Metadata       map[string]string `json:"metadata,omitempty"`
+	StorageClass   string            `json:"storageClass"`
+	TimeCreated    string            `json:"timeCreated,omitempty"`
+	Updated        string            `json:"updated,omitempty"`
+}
+
+func (o ObjectAttrs) toResource() objectResource {
+	res := objectResource{
+		Kind:         "storage#object",
+		ID:           fmt.Sprintf("%s/%s/%d", o.Bucket, o.Name, o.Generation),
+		Name:         o.Name,
+		Bucket:       o.Bucket,
+		ContentType:  o.ContentType,
+		Size:         strconv.FormatInt(o.Size, 10),
+		Metadata:     o.Metadata,
+		StorageClass: "STANDARD",
+	}
+	if o.Generation != 0 {
+		res.Generation = strconv.FormatInt(o.Generation, 10)
+	}
+	if o.Metageneration != 0 {
+		res.Metageneration = strconv.FormatInt(o.Metageneration, 10)
+	}
+	if len(o.MD5) != 0 {
+		res.MD5Hash = base64.StdEncoding.EncodeToString(o.MD5)
+	}
+	if o.CRC32C != 0 {
+		b := []byte{byte(o.CRC32C >> 24), byte(o.CRC32C >> 16), byte(o.CRC32C >> 8), byte(o.CRC32C)}
+		res.CRC32C = base64.StdEncoding.EncodeToString(b)
+	}
+	if !o.Created.IsZero() {
+		res.TimeCreated = o.Created.UTC().Format(time.RFC3339Nano)
+	}
+	if !o.Updated.IsZero() {
+		res.Updated = o.Updated.UTC().Format(time.RFC3339Nano)
+	}
+	return res
+}
+
+type listResource struct {
+	Kind          string           `json:"kind"`
+	Items         []objectResource `json:"items,omitempty"`
+	Prefixes      []string         `json:"prefixes,omitempty"`
+	NextPageToken string           `json:"nextPageToken,omitempty"`
+}
+
+func (l ObjectList) toResource() listResource {
+	res := listResource{
+		Kind:          "storage#objects",
+		Prefixes:      l.Prefixes,
+		NextPageToken: l.NextPageToken,
+	}
+	for _, o := range l.Objects {
+		res.Items = append(res.Items, o.toResource())
+	}
+	return res
+}
+
+type rewriteResource struct {
+	Kind                string         `json:"kind"`
+	TotalBytesRewritten string         `json:"totalBytesRewritten"`
+	ObjectSize          string         `json:"objectSize"`
+	Done                bool           `json:"done"`
+	Resource            objectResource `json:"resource"`
+}
+
+type composeRequest struct {
+	SourceObjects []struct {
+		Name string `json:"name"`
+	} `json:"sourceObjects"`
+}
@@ -0,0 +1,193 @@
+package fakegcs
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	signedURLDateFormat = "20060102T150405Z"
+	// maxSignedURLExpires is a maximum V4 signed URL lifetime in seconds.
+	maxSignedURLExpires = 604800
+)
+
+func signedURLError(code int, xmlCode, format string, args ...interface{}) error {
+	return &Error{
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+		XMLCode: xmlCode,
+	}
+}
+
+// checkSignedURL validates V2 and V4 signed URL query parameters. Request without
+// signature is passed as it is. Signed URL is rejected when SigningKey doesn't return
+// key for the URL access id, since the URL method is covered only by the signature and
+// e.g. URL signed for GET would be accepted for PUT.
+func (f *GCStorage) checkSignedURL(r *http.Request) error {
+	q := r.URL.Query()
+	switch {
+	case q.Get("X-Goog-Signature") != "":
+		return f.checkSignedURLV4(r, q)
+	case q.Get("Signature") != "":
+		return f.checkSignedURLV2(r, q)
+	default:
+		return nil
+	}
+}
+
+func (f *GCStorage) checkSignedURLV4(r *http.Request, q url.Values) error {
+	if alg := q.Get("X-Goog-Algorithm"); alg != "GOOG4-RSA-SHA256" {
+		return signedURLError(http.StatusBadRequest, "InvalidArgument", "unsupported X-Goog-Algorithm %q", alg)
+	}
+	date, err := time.Parse(signedURLDateFormat, q.Get("X-Goog-Date"))
+	if err != nil {
+		return signedURLError(http.StatusBadRequest, "InvalidArgument", "invalid X-Goog-Date %q", q.Get("X-Goog-Date"))
+	}
+	expires, err := strconv.ParseInt(q.Get("X-Goog-Expires"), 10, 64)
+	if err != nil || expires > maxSignedURLExpires {
+		return signedURLError(http.StatusBadRequest, "InvalidArgument", "invalid X-Goog-Expires %q", q.Get("X-Goog-Expires"))
+	}
+	if time.Now().After(date.Add(time.Duration(expires) * time.Second)) {
+		return signedURLError(http.StatusBadRequest, "ExpiredToken", "Invalid argument: signed URL expired")
+	}
+
+	credential := strings.SplitN(q.Get("X-Goog-Credential"), "/", 2)
+	if len(credential) != 2 {
+		return signedURLError(http.StatusBadRequest, "InvalidArgument", "invalid X-Goog-Credential %q", q.Get("X-Goog-Credential"))
+	}
+	if err := checkSignedHeaders(r, q.Get("X-Goog-SignedHeaders")); err != nil {
+		return err
+	}
+	key, err := f.signingKey(credential[0])
+	if err != nil {
+		return err
+	}
+
+	sig, err := hex.DecodeString(q.Get("X-Goog-Signature"))
+	if err != nil {
+		return signedURLError(http.StatusBadRequest, "InvalidArgument", "invalid X-Goog-Signature")
+	}
+	sum := sha256.Sum256(canonicalRequestV4(r, q))
+	buff := &bytes.Buffer{}
+	fmt.Fprintf(buff, "GOOG4-RSA-SHA256\n%s\n%s\n%s", q.Get("X-Goog-Date"), credential[1], hex.EncodeToString(sum[:]))
+	return verifySignature(key, buff.Bytes(), sig)
+}
+
+// checkSignedHeaders checks that the request has all headers the V4 URL was signed
+// with, host header is required by GCS in each V4 signed URL.
+func checkSignedHeaders(r *http.Request, signedHeaders string) error {
+	var host bool
+	for _, name := range strings.Split(signedHeaders, ";") {
+		if name == "host" {
+			host = true
+			continue
+		}
+		if len(r.Header[http.CanonicalHeaderKey(name)]) == 0 {
+			return signedURLError(http.StatusForbidden, "SignatureDoesNotMatch", "signed header %q is missing in the request", name)
+		}
+	}
+	if !host {
+		return signedURLError(http.StatusBadRequest, "InvalidArgument", "X-Goog-SignedHeaders %q doesn't include host", signedHeaders)
+	}
+	return nil
+}
+
+// canonicalRequestV4 builds V4 canonical request in the same form as it is build by the GCS client.
+func canonicalRequestV4(r *http.Request, q url.Values) []byte {
+	query := url.Values{}
+	for k, v := range q {
+		if k != "X-Goog-Signature" {
+			query[k] = v
+		}
+	}
+
+	signedHeaders := strings.Split(q.Get("X-Goog-SignedHeaders"), ";")
+	sort.Strings(signedHeaders)
+	var headers []string
+	for _, name := range signedHeaders {
+		value := strings.Join(r.Header[http.CanonicalHeaderKey(name)], ",")
+		if name == "host" {
+			value = r.Host
+		}
+		headers = append(headers, name+":"+strings.TrimSpace(value))
+	}
+
+	buff := &bytes.Buffer{}
+	fmt.Fprintf(buff, "%s\n", r.Method)
+	fmt.Fprintf(buff, "%s\n", r.URL.EscapedPath())
+	fmt.Fprintf(buff, "%s\n", query.Encode())
+	fmt.Fprintf(buff, "%s\n\n", strings.Join(headers, "\n"))
+	fmt.Fprintf(buff, "%s\n", strings.Join(signedHeaders, ";"))
+	fmt.Fprint(buff, "UNSIGNED-PAYLOAD")
+	return buff.Bytes()
+}
+
+func (f *GCStorage) checkSignedURLV2(r *http.Request, q url.Values) error {
+	expires, err := strconv.ParseInt(q.Get("Expires"), 10, 64)
+	if err != nil {
+		return signedURLError(http.StatusBadRequest, "InvalidArgument", "invalid Expires %q", q.Get("Expires"))
+	}
+	if time.Now().After(time.Unix(expires, 0)) {
+		return signedURLError(http.StatusBadRequest, "ExpiredToken", "Invalid argument: signed URL expired")
+	}
+	key, err := f.signingKey(q.Get("GoogleAccessId"))
+	if err != nil {
+		return err
+	}
+
+	sig, err := base64.StdEncoding.DecodeString(q.Get("Signature"))
+	if err != nil {
+		return signedURLError(http.StatusBadRequest, "InvalidArgument", "invalid Signature")
+	}
+	var headers []string
+	for name, values := range r.Header {
+		name = strings.ToLower(name)
+		if strings.HasPrefix(name, "x-goog-") {
+			headers = append(headers, name+":"+strings.Join(values, ","))
+		}
+	}
+	sort.Strings(headers)
+
+	buff := &bytes.Buffer{}
+	fmt.Fprintf(buff, "%s\n", r.Method)
+	fmt.Fprintf(buff, "%s\n", r.Header.Get("Content-MD5"))
+	fmt.Fprintf(buff, "%s\n", r.Header.Get("Content-Type"))
+	fmt.Fprintf(buff, "%d\n", expires)
+	for _, h := range headers {
+		fmt.Fprintf(buff, "%s\n", h)
+	}
+	fmt.Fprint(buff, r.URL.EscapedPath())
+	return verifySignature(key, buff.Bytes(), sig)
+}
+
+func (f *GCStorage) signingKey(accessID string) (*rsa.PublicKey, error) {
+	var key *rsa.PublicKey
+	if f.SigningKey != nil {
+		key = f.SigningKey(accessID)
+	}
+	if key == nil {
+		return nil, signedURLError(http.StatusForbidden, "AccessDenied",
+			"no signing key of %q access id, signed URL can't be verified", accessID)
+	}
+	return key, nil
+}
+
+func verifySignature(key *rsa.PublicKey, data, sig []byte) error {
+	sum := sha256.Sum256(data)
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], sig); err != nil {
+		return signedURLError(http.StatusForbidden, "SignatureDoesNotMatch",
+			"The request signature we calculated does not match the signature you provided.")
+	}
+	return nil
+}
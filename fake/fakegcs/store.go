@@ -0,0 +1,198 @@
+package fakegcs
+
+import (
+	"bytes"
+	"crypto/md5"
+	"hash/crc32"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// Object is a bucket object kept in the Store.
+type Object struct {
+	Attrs   ObjectAttrs
+	Content []byte
+}
+
+// Store is an in-memory buckets storage that allows to keep GCS state
+// between SUT calls without test interaction.
+type Store struct {
+	mtx     sync.Mutex
+	buckets map[string]map[string]*Object
+	gen     int64
+}
+
+func NewStore() *Store {
+	return &Store{
+		buckets: make(map[string]map[string]*Object),
+	}
+}
+
+// Put creates or overrides bucket object.
+func (s *Store) Put(bucket, name, contentType string, content []byte) ObjectAttrs {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.put(bucket, name, contentType, content)
+}
+
+// PutIf creates or overrides bucket object if the write preconditions are met, they are
+// checked and the object is written under single lock, so concurrent writes can't both pass.
+func (s *Store) PutIf(bucket, name string, conds Conditions, contentType string, content []byte) (ObjectAttrs, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var attrs *ObjectAttrs
+	if obj, ok := s.buckets[bucket][name]; ok {
+		attrs = &obj.Attrs
+	}
+	if err := conds.Check(attrs); err != nil {
+		return ObjectAttrs{}, err
+	}
+	return s.put(bucket, name, contentType, content), nil
+}
+
+func (s *Store) put(bucket, name, contentType string, content []byte) ObjectAttrs {
+	b, ok := s.buckets[bucket]
+	if !ok {
+		b = make(map[string]*Object)
+		s.buckets[bucket] = b
+	}
+
+	now := time.Now()
+	created := now
+	if prev, ok := b[name]; ok {
+		created = prev.Attrs.Created
+	}
+
+	sum := md5.Sum(content)
+	obj := &Object{
+		Attrs: ObjectAttrs{
+			Bucket:         bucket,
+			Name:           name,
+			ContentType:    contentType,
+			Size:           int64(len(content)),
+			MD5:            sum[:],
+			CRC32C:         crc32.Checksum(content, crc32.MakeTable(crc32.Castagnoli)),
+			Generation:     s.nextGeneration(now),
+			Metageneration: 1,
+			Created:        created,
+			Updated:        now,
+		},
+		Content: append([]byte(nil), content...),
+	}
+	b[name] = obj
+	return obj.Attrs
+}
+
+// nextGeneration returns microsecond timestamp based generation that is unique within the store.
+func (s *Store) nextGeneration(now time.Time) int64 {
+	gen := now.UnixNano() / int64(time.Microsecond)
+	if gen <= s.gen {
+		gen = s.gen + 1
+	}
+	s.gen = gen
+	return gen
+}
+
+// Object returns copy of bucket object.
+func (s *Store) Object(bucket, name string) (Object, bool) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	obj, ok := s.buckets[bucket][name]
+	if !ok {
+		return Object{}, false
+	}
+	return Object{
+		Attrs:   obj.Attrs,
+		Content: append([]byte(nil), obj.Content...),
+	}, true
+}
+
+// Objects returns attributes of all objects stored in the bucket.
+func (s *Store) Objects(bucket string) []ObjectAttrs {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var out []ObjectAttrs
+	for _, obj := range s.buckets[bucket] {
+		out = append(out, obj.Attrs)
+	}
+	return out
+}
+
+// CheckConditions validates write preconditions against current state of the object.
+func (s *Store) CheckConditions(bo BucketObject, c Conditions) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var attrs *ObjectAttrs
+	if obj, ok := s.buckets[bo.Bucket][bo.Object]; ok {
+		attrs = &obj.Attrs
+	}
+	return c.Check(attrs)
+}
+
+// Delete removes bucket object.
+func (s *Store) Delete(bucket, name string) error {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	if _, ok := s.buckets[bucket][name]; !ok {
+		return ErrObjectNotExist
+	}
+	delete(s.buckets[bucket], name)
+	return nil
+}
+
+// Copy copies src object content to dst object.
+func (s *Store) Copy(src, dst BucketObject) (ObjectAttrs, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	obj, ok := s.buckets[src.Bucket][src.Object]
+	if !ok {
+		return ObjectAttrs{}, ErrObjectNotExist
+	}
+	return s.put(dst.Bucket, dst.Object, obj.Attrs.ContentType, obj.Content), nil
+}
+
+// Compose concatenates srcs objects content into dst object.
+func (s *Store) Compose(dst BucketObject, srcs []BucketObject) (ObjectAttrs, error) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	var (
+		buff        bytes.Buffer
+		contentType string
+	)
+	for _, src := range srcs {
+		obj, ok := s.buckets[src.Bucket][src.Object]
+		if !ok {
+			return ObjectAttrs{}, ErrObjectNotExist
+		}
+		if contentType == "" {
+			contentType = obj.Attrs.ContentType
+		}
+		buff.Write(obj.Content)
+	}
+	return s.put(dst.Bucket, dst.Object, contentType, buff.Bytes()), nil
+}
+
+// LoadDir seeds the bucket with dir files, object names are file paths relative to the dir.
+func (s *Store) LoadDir(bucket, dir string) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		s.Put(bucket, filepath.ToSlash(rel), "", content)
+		return nil
+	})
+}
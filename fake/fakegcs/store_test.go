@@ -0,0 +1,103 @@
+package fakegcs
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+)
+
+func TestStoreCopyCompose(t *testing.T) {
+	s := NewStore()
+	first := s.Put("bucket", "a.txt", "text/plain", []byte("hello "))
+	s.Put("bucket", "b.txt", "text/plain", []byte("world"))
+
+	attrs, err := s.Copy(BucketObject{Bucket: "bucket", Object: "a.txt"}, BucketObject{Bucket: "archive", Object: "a.txt"})
+	if err != nil {
+		t.Fatalf("got copy error: %v", err)
+	}
+	if attrs.Generation <= first.Generation {
+		t.Fatalf("expected new generation, got: %v previous: %v", attrs.Generation, first.Generation)
+	}
+
+	_, err = s.Compose(BucketObject{Bucket: "bucket", Object: "all.txt"}, []BucketObject{
+		{Bucket: "bucket", Object: "a.txt"},
+		{Bucket: "bucket", Object: "b.txt"},
+	})
+	if err != nil {
+		t.Fatalf("got compose error: %v", err)
+	}
+	obj, ok := s.Object("bucket", "all.txt")
+	if !ok {
+		t.Fatalf("composed object not found")
+	}
+	if got, want := string(obj.Content), "hello world"; got != want {
+		t.Fatalf("content mismatch, got: %q want: %q", got, want)
+	}
+
+	if err := s.Delete("bucket", "missing.txt"); err != ErrObjectNotExist {
+		t.Fatalf("expected ErrObjectNotExist, got: %v", err)
+	}
+}
+
+func TestStorePutIfConcurrent(t *testing.T) {
+	s := NewStore()
+	var doesNotExist int64
+	conds := Conditions{IfGenerationMatch: &doesNotExist}
+
+	var (
+		wg        sync.WaitGroup
+		mtx       sync.Mutex
+		succeeded int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := s.PutIf("bucket", "lock", conds, "text/plain", []byte("owner"))
+			if err == nil {
+				mtx.Lock()
+				succeeded++
+				mtx.Unlock()
+				return
+			}
+			if gerr, ok := err.(*Error); !ok || gerr.Code != http.StatusPreconditionFailed {
+				t.Errorf("expected precondition error, got: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if succeeded != 1 {
+		t.Fatalf("expected exactly one successful write, got: %d", succeeded)
+	}
+}
+
+func TestStoreLoadDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fakegcs")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "in"), os.ModePerm); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "in", "file.txt"), []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	s := NewStore()
+	if err := s.LoadDir("bucket", dir); err != nil {
+		t.Fatalf("got load dir error: %v", err)
+	}
+	obj, ok := s.Object("bucket", "in/file.txt")
+	if !ok {
+		t.Fatalf("seeded object not found, got: %v", s.Objects("bucket"))
+	}
+	if got, want := string(obj.Content), "content"; got != want {
+		t.Fatalf("content mismatch, got: %q want: %q", got, want)
+	}
+}
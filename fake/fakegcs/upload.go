@@ -0,0 +1,282 @@
+package fakegcs
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// uploadSession collects resumable upload chunks until whole content is received.
+type uploadSession struct {
+	object      BucketObject
+	contentType string
+	conds       Conditions
+	buff        bytes.Buffer
+}
+
+func (f *GCStorage) handleMedia(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	conds, err := parseConditions(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	bo := BucketObject{
+		Bucket: mux.Vars(r)["bucket"],
+		Object: r.URL.Query().Get("name"),
+	}
+	if bo.Object == "" {
+		http.Error(w, "object name is required", http.StatusBadRequest)
+		return
+	}
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	f.insertObject(w, bo, r.Header.Get("Content-Type"), content, conds)
+}
+
+// handleResumable initiates resumable upload session or handles uploaded chunk
+// when session id is provided.
+func (f *GCStorage) handleResumable(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("upload_id") != "" {
+		f.handleChunk(w, r)
+		return
+	}
+	defer r.Body.Close()
+
+	conds, err := parseConditions(r.URL.Query())
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	var obj meta
+	if err := json.NewDecoder(r.Body).Decode(&obj); err != nil && err != io.EOF {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	session := &uploadSession{
+		object: BucketObject{
+			Bucket: mux.Vars(r)["bucket"],
+			Object: obj.Object,
+		},
+		contentType: r.Header.Get("X-Upload-Content-Type"),
+		conds:       conds,
+	}
+	if session.object.Object == "" {
+		session.object.Object = r.URL.Query().Get("name")
+	}
+	if session.object.Object == "" {
+		http.Error(w, "object name is required", http.StatusBadRequest)
+		return
+	}
+	if session.contentType == "" {
+		session.contentType = obj.ContentType
+	}
+
+	id, err := newUploadID()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	f.sessionsMtx.Lock()
+	if f.sessions == nil {
+		f.sessions = make(map[string]*uploadSession)
+	}
+	f.sessions[id] = session
+	f.sessionsMtx.Unlock()
+
+	w.Header().Set("Location", sessionURL(r, id))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *GCStorage) handleChunk(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	id := r.URL.Query().Get("upload_id")
+
+	f.sessionsMtx.Lock()
+	session, ok := f.sessions[id]
+	f.sessionsMtx.Unlock()
+	if !ok {
+		http.Error(w, "upload session not found", http.StatusNotFound)
+		return
+	}
+
+	cr, err := parseContentRange(r.Header.Get("Content-Range"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if !cr.statusQuery {
+		received := int64(session.buff.Len())
+		if cr.start > received {
+			http.Error(w, fmt.Sprintf("missing bytes %d-%d", received, cr.start-1), http.StatusBadRequest)
+			return
+		}
+		// Skip part of the chunk that was already persisted.
+		if _, err := io.CopyN(ioutil.Discard, r.Body, received-cr.start); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if _, err := io.Copy(&session.buff, r.Body); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+
+	if cr.total < 0 || int64(session.buff.Len()) < cr.total {
+		writeResumeIncomplete(w, r, int64(session.buff.Len()))
+		return
+	}
+
+	f.sessionsMtx.Lock()
+	delete(f.sessions, id)
+	f.sessionsMtx.Unlock()
+
+	f.insertObject(w, session.object, session.contentType, session.buff.Bytes(), session.conds)
+}
+
+// insertObject passes whole object content to OnObjectInsert handler and responds
+// with the created object resource.
+func (f *GCStorage) insertObject(w http.ResponseWriter, bo BucketObject, contentType string, content []byte, conds Conditions) {
+	attrs, err := f.storeObject(bo, contentType, content, conds)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, attrs.toResource())
+}
+
+// storeObject validates preconditions and passes object content to OnObjectInsertIf or
+// OnObjectInsert handler.
+func (f *GCStorage) storeObject(bo BucketObject, contentType string, content []byte, conds Conditions) (*ObjectAttrs, error) {
+	if f.OnObjectInsertIf == nil {
+		if err := f.checkConditions(bo, conds); err != nil {
+			return nil, err
+		}
+	}
+	sum := md5.Sum(content)
+	attrs := &ObjectAttrs{
+		Bucket:      bo.Bucket,
+		Name:        bo.Object,
+		ContentType: contentType,
+		Size:        int64(len(content)),
+		MD5:         sum[:],
+		CRC32C:      crc32.Checksum(content, crc32.MakeTable(crc32.Castagnoli)),
+	}
+	var (
+		stored *ObjectAttrs
+		err    error
+	)
+	switch {
+	case f.OnObjectInsertIf != nil:
+		stored, err = f.OnObjectInsertIf(*attrs, conds, bytes.NewReader(content))
+	case f.OnObjectInsert != nil:
+		stored, err = f.OnObjectInsert(*attrs, bytes.NewReader(content))
+	default:
+		return attrs, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if stored != nil {
+		return stored, nil
+	}
+	return attrs, nil
+}
+
+// writeResumeIncomplete informs the client that upload session expects more data.
+func writeResumeIncomplete(w http.ResponseWriter, r *http.Request, received int64) {
+	if received > 0 {
+		w.Header().Set("Range", fmt.Sprintf("bytes=0-%d", received-1))
+	}
+	if r.Header.Get("X-GUploader-No-308") == "yes" {
+		w.Header().Set("X-Http-Status-Code-Override", "308")
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+	w.WriteHeader(http.StatusPermanentRedirect)
+}
+
+type contentRange struct {
+	start       int64
+	total       int64
+	statusQuery bool
+}
+
+// parseContentRange parses "bytes start-end/total", "bytes start-end/*" and "bytes */total" header
+// values, unknown total size is returned as -1.
+func parseContentRange(v string) (contentRange, error) {
+	cr := contentRange{total: -1}
+	if v == "" {
+		return cr, nil
+	}
+	if !strings.HasPrefix(v, "bytes ") {
+		return cr, fmt.Errorf("invalid Content-Range %q", v)
+	}
+	ss := strings.Split(strings.TrimPrefix(v, "bytes "), "/")
+	if len(ss) != 2 {
+		return cr, fmt.Errorf("invalid Content-Range %q", v)
+	}
+	if ss[1] != "*" {
+		total, err := strconv.ParseInt(ss[1], 10, 64)
+		if err != nil {
+			return cr, fmt.Errorf("invalid Content-Range %q: %v", v, err)
+		}
+		cr.total = total
+	}
+	if ss[0] == "*" {
+		cr.statusQuery = true
+		return cr, nil
+	}
+	rng := strings.Split(ss[0], "-")
+	if len(rng) != 2 {
+		return cr, fmt.Errorf("invalid Content-Range %q", v)
+	}
+	start, err := strconv.ParseInt(rng[0], 10, 64)
+	if err != nil {
+		return cr, fmt.Errorf("invalid Content-Range %q: %v", v, err)
+	}
+	cr.start = start
+	return cr, nil
+}
+
+func sessionURL(r *http.Request, id string) string {
+	q := url.Values{}
+	q.Set("uploadType", "resumable")
+	q.Set("upload_id", id)
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	u := url.URL{
+		Scheme:   scheme,
+		Host:     r.Host,
+		Path:     r.URL.Path,
+		RawPath:  r.URL.RawPath,
+		RawQuery: q.Encode(),
+	}
+	return u.String()
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,178 @@
+package fakegcs
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+type xmlError struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+func (e *Error) xmlCode() string {
+	if e.XMLCode != "" {
+		return e.XMLCode
+	}
+	switch e.Code {
+	case http.StatusBadRequest:
+		return "InvalidArgument"
+	case http.StatusUnauthorized, http.StatusForbidden:
+		return "AccessDenied"
+	case http.StatusNotFound:
+		return "NoSuchKey"
+	case http.StatusPreconditionFailed:
+		return "PreconditionFailed"
+	case http.StatusTooManyRequests:
+		return "SlowDown"
+	default:
+		return "InternalError"
+	}
+}
+
+// writeXMLError responds with GCS XML API error matching handler error.
+func writeXMLError(w http.ResponseWriter, err error) {
+	gerr, ok := err.(*Error)
+	if !ok {
+		gerr = &Error{Code: http.StatusInternalServerError, Message: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/xml; charset=UTF-8")
+	w.WriteHeader(gerr.Code)
+	w.Write([]byte(xml.Header))
+	xml.NewEncoder(w).Encode(xmlError{
+		Code:    gerr.xmlCode(),
+		Message: gerr.Message,
+	})
+}
+
+// xmlConditions reads XML API preconditions passed in x-goog-if-* headers.
+func xmlConditions(h http.Header) (Conditions, error) {
+	q := url.Values{}
+	for header, param := range map[string]string{
+		"X-Goog-If-Generation-Match":     "ifGenerationMatch",
+		"X-Goog-If-Metageneration-Match": "ifMetagenerationMatch",
+	} {
+		if v := h.Get(header); v != "" {
+			q.Set(param, v)
+		}
+	}
+	return parseConditions(q)
+}
+
+func (f *GCStorage) handleXMLPut(w http.ResponseWriter, r *http.Request) {
+	defer r.Body.Close()
+	if err := f.checkSignedURL(r); err != nil {
+		writeXMLError(w, err)
+		return
+	}
+	conds, err := xmlConditions(r.Header)
+	if err != nil {
+		writeXMLError(w, err)
+		return
+	}
+	content, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		writeXMLError(w, &Error{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	attrs, err := f.storeObject(objectFromVars(r, "bucket", "object"), r.Header.Get("Content-Type"), content, conds)
+	if err != nil {
+		writeXMLError(w, err)
+		return
+	}
+	writeXMLHeaders(w, attrs)
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *GCStorage) handleXMLGet(w http.ResponseWriter, r *http.Request) {
+	if err := f.checkSignedURL(r); err != nil {
+		writeXMLError(w, err)
+		return
+	}
+	if f.OnObjectGet != nil {
+		if err := f.OnObjectGet(objectFromVars(r, "bucket", "object"), w); err != nil {
+			writeXMLError(w, err)
+		}
+	}
+}
+
+func (f *GCStorage) handleXMLHead(w http.ResponseWriter, r *http.Request) {
+	if err := f.checkSignedURL(r); err != nil {
+		writeXMLError(w, err)
+		return
+	}
+	if f.OnObjectAttrs == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	bo := objectFromVars(r, "bucket", "object")
+	attrs, err := f.OnObjectAttrs(bo)
+	if err != nil {
+		writeXMLError(w, err)
+		return
+	}
+	if attrs == nil {
+		writeXMLError(w, ErrObjectNotExist)
+		return
+	}
+	attrs = attrs.withLocation(bo)
+	writeXMLHeaders(w, attrs)
+	if attrs.ContentType != "" {
+		w.Header().Set("Content-Type", attrs.ContentType)
+	}
+	w.Header().Set("Content-Length", strconv.FormatInt(attrs.Size, 10))
+	if !attrs.Updated.IsZero() {
+		w.Header().Set("Last-Modified", attrs.Updated.UTC().Format(http.TimeFormat))
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *GCStorage) handleXMLDelete(w http.ResponseWriter, r *http.Request) {
+	if err := f.checkSignedURL(r); err != nil {
+		writeXMLError(w, err)
+		return
+	}
+	if f.OnObjectDelete == nil {
+		w.WriteHeader(http.StatusNotImplemented)
+		return
+	}
+	bo := objectFromVars(r, "bucket", "object")
+	conds, err := xmlConditions(r.Header)
+	if err == nil {
+		err = f.checkConditions(bo, conds)
+	}
+	if err == nil {
+		err = f.OnObjectDelete(bo)
+	}
+	if err != nil {
+		writeXMLError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// writeXMLHeaders sets XML API object metadata response headers.
+func writeXMLHeaders(w http.ResponseWriter, attrs *ObjectAttrs) {
+	h := w.Header()
+	if len(attrs.MD5) > 0 {
+		h.Set("ETag", strconv.Quote(hex.EncodeToString(attrs.MD5)))
+		h.Add("X-Goog-Hash", "md5="+base64.StdEncoding.EncodeToString(attrs.MD5))
+	}
+	crc := make([]byte, 4)
+	binary.BigEndian.PutUint32(crc, attrs.CRC32C)
+	h.Add("X-Goog-Hash", "crc32c="+base64.StdEncoding.EncodeToString(crc))
+	if attrs.Generation != 0 {
+		h.Set("X-Goog-Generation", strconv.FormatInt(attrs.Generation, 10))
+	}
+	if attrs.Metageneration != 0 {
+		h.Set("X-Goog-Metageneration", strconv.FormatInt(attrs.Metageneration, 10))
+	}
+	h.Set("X-Goog-Stored-Content-Length", strconv.FormatInt(attrs.Size, 10))
+}
@@ -0,0 +1,227 @@
+package fakegcs
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"cloud.google.com/go/storage"
+	"github.com/gorilla/mux"
+)
+
+func newTestServer(fakeStorage *GCStorage) *httptest.Server {
+	StorageHost = "{[0-9]:.+}"
+	return httptest.NewServer(fakeStorage.AddMuxRoute(mux.NewRouter()))
+}
+
+// do sends request to the test server keeping storage.googleapis.com Host header,
+// so signed URLs match their signature.
+func do(t *testing.T, srv *httptest.Server, method, rawurl string, body []byte, header http.Header) *http.Response {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		t.Fatalf("failed to parse url: %v", err)
+	}
+	host := u.Host
+	u.Scheme, u.Host = "http", strings.TrimPrefix(srv.URL, "http://")
+	req, err := http.NewRequest(method, u.String(), bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Host = host
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	return resp
+}
+
+func TestXMLAPI(t *testing.T) {
+	store := NewStore()
+	fakeStorage := &GCStorage{
+		OnObjectInsert: func(attrs ObjectAttrs, r io.Reader) (*ObjectAttrs, error) {
+			b, _ := ioutil.ReadAll(r)
+			stored := store.Put(attrs.Bucket, attrs.Name, attrs.ContentType, b)
+			return &stored, nil
+		},
+		OnObjectGet: func(bo BucketObject, w io.Writer) error {
+			obj, ok := store.Object(bo.Bucket, bo.Object)
+			if !ok {
+				return ErrObjectNotExist
+			}
+			_, err := w.Write(obj.Content)
+			return err
+		},
+		OnObjectAttrs: func(bo BucketObject) (*ObjectAttrs, error) {
+			obj, ok := store.Object(bo.Bucket, bo.Object)
+			if !ok {
+				return nil, ErrObjectNotExist
+			}
+			return &obj.Attrs, nil
+		},
+		OnObjectDelete: func(bo BucketObject) error {
+			return store.Delete(bo.Bucket, bo.Object)
+		},
+	}
+	srv := newTestServer(fakeStorage)
+	defer srv.Close()
+
+	const u = "https://storage.googleapis.com/bucket/file.txt"
+	resp := do(t, srv, http.MethodPut, u, []byte("content"), http.Header{"Content-Type": {"text/plain"}})
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == "" {
+		t.Fatalf("unexpected put response: %v %v", resp.Status, resp.Header)
+	}
+
+	resp = do(t, srv, http.MethodHead, u, nil, nil)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Length") != "7" || resp.Header.Get("X-Goog-Generation") == "" {
+		t.Fatalf("unexpected head response: %v %v", resp.Status, resp.Header)
+	}
+
+	resp = do(t, srv, http.MethodGet, u, nil, nil)
+	b, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if got, want := string(b), "content"; got != want {
+		t.Fatalf("content mismatch, got: %q want: %q", got, want)
+	}
+
+	resp = do(t, srv, http.MethodDelete, u, nil, nil)
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("unexpected delete status: %v", resp.Status)
+	}
+
+	resp = do(t, srv, http.MethodGet, u, nil, nil)
+	b, _ = ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound || !strings.Contains(string(b), "<Code>NoSuchKey</Code>") {
+		t.Fatalf("unexpected get response: %v %s", resp.Status, b)
+	}
+}
+
+func TestSignedURL(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	var inserted int
+	fakeStorage := &GCStorage{
+		OnObjectInsert: func(attrs ObjectAttrs, r io.Reader) (*ObjectAttrs, error) {
+			inserted++
+			got := BucketObject{Bucket: attrs.Bucket, Object: attrs.Name}
+			if want := (BucketObject{Bucket: "bucket", Object: "dir/file.txt"}); got != want {
+				t.Errorf("inserted object mismatch, got: %+v want: %+v", got, want)
+			}
+			return nil, nil
+		},
+		SigningKey: func(accessID string) *rsa.PublicKey {
+			if accessID != "sa@project.iam.gserviceaccount.com" {
+				return nil
+			}
+			return &key.PublicKey
+		},
+	}
+	srv := newTestServer(fakeStorage)
+	defer srv.Close()
+
+	signedURL := func(scheme storage.SigningScheme, method string, expires time.Duration) string {
+		u, err := storage.SignedURL("bucket", "dir/file.txt", &storage.SignedURLOptions{
+			GoogleAccessID: "sa@project.iam.gserviceaccount.com",
+			PrivateKey:     pemKey,
+			Method:         method,
+			Expires:        time.Now().Add(expires),
+			ContentType:    "text/plain",
+			Scheme:         scheme,
+		})
+		if err != nil {
+			t.Fatalf("failed to sign url: %v", err)
+		}
+		return u
+	}
+	header := http.Header{"Content-Type": {"text/plain"}}
+
+	for _, tc := range []struct {
+		name   string
+		scheme storage.SigningScheme
+		signed string
+		method string
+		ttl    time.Duration
+		code   int
+	}{
+		{"v4", storage.SigningSchemeV4, http.MethodPut, http.MethodPut, time.Hour, http.StatusOK},
+		{"v2", storage.SigningSchemeV2, http.MethodPut, http.MethodPut, time.Hour, http.StatusOK},
+		{"v4 method mismatch", storage.SigningSchemeV4, http.MethodGet, http.MethodPut, time.Hour, http.StatusForbidden},
+		{"v2 method mismatch", storage.SigningSchemeV2, http.MethodGet, http.MethodPut, time.Hour, http.StatusForbidden},
+		{"v4 expired", storage.SigningSchemeV4, http.MethodPut, http.MethodPut, -time.Minute, http.StatusBadRequest},
+		{"v2 expired", storage.SigningSchemeV2, http.MethodPut, http.MethodPut, -time.Minute, http.StatusBadRequest},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := do(t, srv, tc.method, signedURL(tc.scheme, tc.signed, tc.ttl), []byte("content"), header)
+			resp.Body.Close()
+			if resp.StatusCode != tc.code {
+				t.Fatalf("status mismatch, got: %v want: %v", resp.StatusCode, tc.code)
+			}
+		})
+	}
+	if inserted != 2 {
+		t.Fatalf("expected 2 inserted objects, got: %v", inserted)
+	}
+}
+
+func TestSignedURLWithoutKey(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate key: %v", err)
+	}
+	pemKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+
+	fakeStorage := &GCStorage{
+		OnObjectInsert: func(attrs ObjectAttrs, r io.Reader) (*ObjectAttrs, error) {
+			return nil, nil
+		},
+	}
+	srv := newTestServer(fakeStorage)
+	defer srv.Close()
+
+	u, err := storage.SignedURL("bucket", "file.txt", &storage.SignedURLOptions{
+		GoogleAccessID: "sa@project.iam.gserviceaccount.com",
+		PrivateKey:     pemKey,
+		Method:         http.MethodPut,
+		Expires:        time.Now().Add(time.Hour),
+		ContentType:    "text/plain",
+		Scheme:         storage.SigningSchemeV4,
+	})
+	if err != nil {
+		t.Fatalf("failed to sign url: %v", err)
+	}
+
+	// URL method can't be verified without the key, so the URL is always rejected.
+	for _, tc := range []struct {
+		name   string
+		header http.Header
+		code   int
+	}{
+		{"signed headers", http.Header{"Content-Type": {"text/plain"}}, http.StatusForbidden},
+		{"missing signed header", nil, http.StatusForbidden},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp := do(t, srv, http.MethodPut, u, []byte("content"), tc.header)
+			resp.Body.Close()
+			if resp.StatusCode != tc.code {
+				t.Fatalf("status mismatch, got: %v want: %v", resp.StatusCode, tc.code)
+			}
+		})
+	}
+}
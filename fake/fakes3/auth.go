@@ -0,0 +1,105 @@
+package fakes3
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	sigV4Algorithm    = "AWS4-HMAC-SHA256"
+	sigV4DateFormat   = "20060102T150405Z"
+	streamingPayload  = "STREAMING-AWS4-HMAC-SHA256-PAYLOAD"
+	maxPresignExpires = 604800
+)
+
+func authError(format string, args ...interface{}) error {
+	return &Error{
+		Code:    http.StatusBadRequest,
+		S3Code:  "InvalidRequest",
+		Message: fmt.Sprintf(format, args...),
+	}
+}
+
+// checkAuth accepts anonymous and SigV4 signed requests. Signature itself isn't verified,
+// only Authorization header format and presigned URL expiry are validated.
+func checkAuth(r *http.Request) error {
+	if auth := r.Header.Get("Authorization"); auth != "" {
+		if !strings.HasPrefix(auth, sigV4Algorithm+" ") {
+			return authError("The authorization mechanism you have provided is not supported. Please use %s.", sigV4Algorithm)
+		}
+		for _, field := range []string{"Credential=", "SignedHeaders=", "Signature="} {
+			if !strings.Contains(auth, field) {
+				return &Error{
+					Code:    http.StatusBadRequest,
+					S3Code:  "AuthorizationHeaderMalformed",
+					Message: fmt.Sprintf("The authorization header is malformed; missing %s", strings.TrimSuffix(field, "=")),
+				}
+			}
+		}
+		return nil
+	}
+
+	q := r.URL.Query()
+	if q.Get("X-Amz-Signature") == "" {
+		return nil
+	}
+	if alg := q.Get("X-Amz-Algorithm"); alg != sigV4Algorithm {
+		return authError("unsupported X-Amz-Algorithm %q", alg)
+	}
+	date, err := time.Parse(sigV4DateFormat, q.Get("X-Amz-Date"))
+	if err != nil {
+		return authError("invalid X-Amz-Date %q", q.Get("X-Amz-Date"))
+	}
+	expires, err := strconv.ParseInt(q.Get("X-Amz-Expires"), 10, 64)
+	if err != nil || expires > maxPresignExpires {
+		return authError("invalid X-Amz-Expires %q", q.Get("X-Amz-Expires"))
+	}
+	if time.Now().After(date.Add(time.Duration(expires) * time.Second)) {
+		return &Error{Code: http.StatusForbidden, S3Code: "AccessDenied", Message: "Request has expired"}
+	}
+	return nil
+}
+
+// readBody reads request payload decoding aws-chunked encoding used by streaming SigV4 uploads.
+func readBody(r *http.Request) ([]byte, error) {
+	defer r.Body.Close()
+	if r.Header.Get("X-Amz-Content-Sha256") != streamingPayload {
+		return ioutil.ReadAll(r.Body)
+	}
+	return decodeChunked(r.Body)
+}
+
+// decodeChunked decodes "<hex size>;chunk-signature=<sig>\r\n<data>\r\n" chunks
+// until the final zero length chunk.
+func decodeChunked(r io.Reader) ([]byte, error) {
+	var (
+		br  = bufio.NewReader(r)
+		out bytes.Buffer
+	)
+	for {
+		line, err := br.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("failed to read chunk header: %v", err)
+		}
+		size, err := strconv.ParseInt(strings.SplitN(strings.TrimSpace(line), ";", 2)[0], 16, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid chunk header %q", line)
+		}
+		if size == 0 {
+			return out.Bytes(), nil
+		}
+		if _, err := io.CopyN(&out, br, size); err != nil {
+			return nil, fmt.Errorf("failed to read chunk: %v", err)
+		}
+		if _, err := br.Discard(2); err != nil {
+			return nil, fmt.Errorf("failed to read chunk: %v", err)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+package fakes3
+
+import (
+	"encoding/xml"
+	"fmt"
+	"net/http"
+)
+
+var (
+	// ErrNoSuchKey is returned by handlers when requested object doesn't exist.
+	ErrNoSuchKey = &Error{Code: http.StatusNotFound, Message: "The specified key does not exist."}
+	// ErrNoSuchUpload is returned when multipart upload id is unknown.
+	ErrNoSuchUpload = &Error{Code: http.StatusNotFound, S3Code: "NoSuchUpload", Message: "The specified upload does not exist."}
+)
+
+// Error is a S3 REST API error returned to the client.
+type Error struct {
+	// Code is a HTTP status code of the response.
+	Code int
+	// Message is a human readable error description.
+	Message string
+	// S3Code is a S3 error code e.g. "NoSuchKey", if empty it is derived from Code.
+	S3Code string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("s3 error %d: %s", e.Code, e.Message)
+}
+
+func (e *Error) s3Code() string {
+	if e.S3Code != "" {
+		return e.S3Code
+	}
+	switch e.Code {
+	case http.StatusBadRequest:
+		return "InvalidRequest"
+	case http.StatusForbidden:
+		return "AccessDenied"
+	case http.StatusNotFound:
+		return "NoSuchKey"
+	case http.StatusPreconditionFailed:
+		return "PreconditionFailed"
+	case http.StatusServiceUnavailable:
+		return "SlowDown"
+	case http.StatusNotImplemented:
+		return "NotImplemented"
+	default:
+		return "InternalError"
+	}
+}
+
+type errorResult struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource,omitempty"`
+}
+
+// writeError responds with S3 XML error matching handler error.
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	serr, ok := err.(*Error)
+	if !ok {
+		serr = &Error{Code: http.StatusInternalServerError, Message: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(serr.Code)
+	if r.Method == http.MethodHead {
+		return
+	}
+	writeXMLBody(w, errorResult{
+		Code:     serr.s3Code(),
+		Message:  serr.Message,
+		Resource: r.URL.Path,
+	})
+}
+
+func writeXML(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/xml")
+	writeXMLBody(w, v)
+}
+
+func writeXMLBody(w http.ResponseWriter, v interface{}) {
+	w.Write([]byte(xml.Header))
+	if err := xml.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
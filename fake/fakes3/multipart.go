@@ -0,0 +1,179 @@
+package fakes3
+
+import (
+	"bytes"
+	"crypto/md5"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// multipartUpload collects uploaded parts until the upload is completed.
+type multipartUpload struct {
+	object BucketObject
+	parts  map[int][]byte
+}
+
+type initiateUploadResult struct {
+	XMLName  xml.Name `xml:"InitiateMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	UploadID string   `xml:"UploadId"`
+}
+
+type completeUploadRequest struct {
+	Parts []struct {
+		PartNumber int    `xml:"PartNumber"`
+		ETag       string `xml:"ETag"`
+	} `xml:"Part"`
+}
+
+type completeUploadResult struct {
+	XMLName  xml.Name `xml:"CompleteMultipartUploadResult"`
+	Xmlns    string   `xml:"xmlns,attr"`
+	Location string   `xml:"Location"`
+	Bucket   string   `xml:"Bucket"`
+	Key      string   `xml:"Key"`
+	ETag     string   `xml:"ETag"`
+}
+
+func (f *S3) handleCreateUpload(w http.ResponseWriter, r *http.Request) {
+	id, err := newUploadID()
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	bo := objectFromVars(r)
+	f.uploadsMtx.Lock()
+	if f.uploads == nil {
+		f.uploads = make(map[string]*multipartUpload)
+	}
+	f.uploads[id] = &multipartUpload{
+		object: bo,
+		parts:  make(map[int][]byte),
+	}
+	f.uploadsMtx.Unlock()
+
+	writeXML(w, initiateUploadResult{
+		Xmlns:    s3Namespace,
+		Bucket:   bo.Bucket,
+		Key:      bo.Key,
+		UploadID: id,
+	})
+}
+
+func (f *S3) upload(r *http.Request) (*multipartUpload, error) {
+	f.uploadsMtx.Lock()
+	defer f.uploadsMtx.Unlock()
+	upload, ok := f.uploads[r.URL.Query().Get("uploadId")]
+	if !ok || upload.object != objectFromVars(r) {
+		return nil, ErrNoSuchUpload
+	}
+	return upload, nil
+}
+
+func (f *S3) handleUploadPart(w http.ResponseWriter, r *http.Request) {
+	upload, err := f.upload(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	n, err := strconv.Atoi(r.URL.Query().Get("partNumber"))
+	if err != nil || n < 1 || n > 10000 {
+		writeError(w, r, &Error{Code: http.StatusBadRequest, S3Code: "InvalidArgument", Message: "Part number must be an integer between 1 and 10000, inclusive"})
+		return
+	}
+	content, err := readBody(r)
+	if err != nil {
+		writeError(w, r, &Error{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	f.uploadsMtx.Lock()
+	upload.parts[n] = content
+	f.uploadsMtx.Unlock()
+
+	w.Header().Set("ETag", etag(content))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *S3) handleCompleteUpload(w http.ResponseWriter, r *http.Request) {
+	upload, err := f.upload(r)
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	body, err := readBody(r)
+	if err != nil {
+		writeError(w, r, &Error{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	var req completeUploadRequest
+	if err := xml.Unmarshal(body, &req); err != nil || len(req.Parts) == 0 {
+		writeError(w, r, &Error{Code: http.StatusBadRequest, S3Code: "MalformedXML", Message: "The XML you provided was not well-formed"})
+		return
+	}
+	if !sort.SliceIsSorted(req.Parts, func(i, j int) bool { return req.Parts[i].PartNumber < req.Parts[j].PartNumber }) {
+		writeError(w, r, &Error{Code: http.StatusBadRequest, S3Code: "InvalidPartOrder", Message: "The list of parts was not in ascending order"})
+		return
+	}
+
+	var (
+		content bytes.Buffer
+		sums    []byte
+	)
+	f.uploadsMtx.Lock()
+	for _, p := range req.Parts {
+		part, ok := upload.parts[p.PartNumber]
+		if !ok || strings.Trim(p.ETag, `"`) != strings.Trim(etag(part), `"`) {
+			f.uploadsMtx.Unlock()
+			writeError(w, r, &Error{Code: http.StatusBadRequest, S3Code: "InvalidPart", Message: fmt.Sprintf("part %d could not be found", p.PartNumber)})
+			return
+		}
+		sum := md5.Sum(part)
+		sums = append(sums, sum[:]...)
+		content.Write(part)
+	}
+	f.uploadsMtx.Unlock()
+
+	if err := f.putObject(upload.object, content.Bytes()); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	f.uploadsMtx.Lock()
+	delete(f.uploads, r.URL.Query().Get("uploadId"))
+	f.uploadsMtx.Unlock()
+
+	sum := md5.Sum(sums)
+	writeXML(w, completeUploadResult{
+		Xmlns:    s3Namespace,
+		Location: "https://" + r.Host + r.URL.Path,
+		Bucket:   upload.object.Bucket,
+		Key:      upload.object.Key,
+		ETag:     strconv.Quote(fmt.Sprintf("%s-%d", hex.EncodeToString(sum[:]), len(req.Parts))),
+	})
+}
+
+func (f *S3) handleAbortUpload(w http.ResponseWriter, r *http.Request) {
+	if _, err := f.upload(r); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	f.uploadsMtx.Lock()
+	delete(f.uploads, r.URL.Query().Get("uploadId"))
+	f.uploadsMtx.Unlock()
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func newUploadID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
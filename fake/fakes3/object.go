@@ -0,0 +1,140 @@
+package fakes3
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"encoding/xml"
+	"strconv"
+	"time"
+
+	"github.com/smallinsky/mtf/fake/internal/listing"
+)
+
+const (
+	defaultMaxKeys = 1000
+	s3Namespace    = "http://s3.amazonaws.com/doc/2006-03-01/"
+)
+
+// ObjectAttrs describes bucket object metadata returned to the client.
+type ObjectAttrs struct {
+	Bucket       string
+	Key          string
+	ContentType  string
+	Size         int64
+	ETag         string
+	Metadata     map[string]string
+	LastModified time.Time
+}
+
+// ListQuery is a ListObjectsV2 call query.
+type ListQuery struct {
+	Bucket            string
+	Prefix            string
+	Delimiter         string
+	ContinuationToken string
+	StartAfter        string
+	// MaxKeys limits listed keys and prefixes, zero lists none of them like S3 does
+	// for max-keys=0.
+	MaxKeys int
+}
+
+// ObjectList is a single page of ListObjectsV2 call.
+type ObjectList struct {
+	Objects               []ObjectAttrs
+	Prefixes              []string
+	NextContinuationToken string
+	IsTruncated           bool
+}
+
+// FilterObjects applies query prefix, delimiter and paging rules to objects collection.
+func FilterObjects(objects []ObjectAttrs, q ListQuery) ObjectList {
+	after := q.StartAfter
+	if q.ContinuationToken != "" {
+		after = q.ContinuationToken
+	}
+	keys := make([]string, len(objects))
+	for i, o := range objects {
+		keys[i] = o.Key
+	}
+	page := listing.List(keys, listing.Query{
+		Prefix:    q.Prefix,
+		Delimiter: q.Delimiter,
+		After:     after,
+		Max:       q.MaxKeys,
+	})
+
+	out := ObjectList{
+		Prefixes:              page.Prefixes,
+		NextContinuationToken: page.Next,
+		IsTruncated:           page.Truncated,
+	}
+	for _, i := range page.Objects {
+		out.Objects = append(out.Objects, objects[i])
+	}
+	return out
+}
+
+// etag returns S3 ETag of the object content.
+func etag(content []byte) string {
+	sum := md5.Sum(content)
+	return strconv.Quote(hex.EncodeToString(sum[:]))
+}
+
+type contentsResult struct {
+	Key          string `xml:"Key"`
+	LastModified string `xml:"LastModified,omitempty"`
+	ETag         string `xml:"ETag,omitempty"`
+	Size         int64  `xml:"Size"`
+	StorageClass string `xml:"StorageClass"`
+}
+
+type prefixResult struct {
+	Prefix string `xml:"Prefix"`
+}
+
+type listResult struct {
+	XMLName               xml.Name         `xml:"ListBucketResult"`
+	Xmlns                 string           `xml:"xmlns,attr"`
+	Name                  string           `xml:"Name"`
+	Prefix                string           `xml:"Prefix"`
+	Delimiter             string           `xml:"Delimiter,omitempty"`
+	MaxKeys               int              `xml:"MaxKeys"`
+	KeyCount              int              `xml:"KeyCount"`
+	IsTruncated           bool             `xml:"IsTruncated"`
+	ContinuationToken     string           `xml:"ContinuationToken,omitempty"`
+	NextContinuationToken string           `xml:"NextContinuationToken,omitempty"`
+	StartAfter            string           `xml:"StartAfter,omitempty"`
+	Contents              []contentsResult `xml:"Contents"`
+	CommonPrefixes        []prefixResult   `xml:"CommonPrefixes"`
+}
+
+func (l ObjectList) toResult(q ListQuery) listResult {
+	res := listResult{
+		Xmlns:                 s3Namespace,
+		Name:                  q.Bucket,
+		Prefix:                q.Prefix,
+		Delimiter:             q.Delimiter,
+		MaxKeys:               q.MaxKeys,
+		KeyCount:              len(l.Objects) + len(l.Prefixes),
+		IsTruncated:           l.IsTruncated,
+		ContinuationToken:     q.ContinuationToken,
+		NextContinuationToken: l.NextContinuationToken,
+		StartAfter:            q.StartAfter,
+	}
+	for _, o := range l.Objects {
+		c := contentsResult{
+			Key:          o.Key,
+			ETag:         o.ETag,
+			Size:         o.Size,
+			StorageClass: "STANDARD",
+		}
+		if !o.LastModified.IsZero() {
+			c.LastModified = o.LastModified.UTC().Format(time.RFC3339Nano)
+		}
+		res.Contents = append(res.Contents, c)
+	}
+	for _, p := range l.Prefixes {
+		res.CommonPrefixes = append(res.CommonPrefixes, prefixResult{Prefix: p})
+	}
+	return res
+}
@@ -0,0 +1,212 @@
+package fakes3
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/gorilla/mux"
+)
+
+var (
+	// S3Host is a global S3 endpoint, regional endpoints "s3.<region>.amazonaws.com"
+	// are served as well.
+	S3Host = "s3.amazonaws.com"
+)
+
+type S3 struct {
+	OnPutObject    func(BucketObject, io.Reader) error
+	OnGetObject    func(BucketObject, io.Writer) error
+	OnHeadObject   func(BucketObject) (*ObjectAttrs, error)
+	OnListObjects  func(ListQuery) ([]ObjectAttrs, error)
+	OnDeleteObject func(BucketObject) error
+
+	uploadsMtx sync.Mutex
+	uploads    map[string]*multipartUpload
+}
+
+type BucketObject struct {
+	Bucket string
+	Key    string
+}
+
+func objectFromVars(r *http.Request) BucketObject {
+	vars := mux.Vars(r)
+	return BucketObject{
+		Bucket: vars["bucket"],
+		Key:    vars["key"],
+	}
+}
+
+func (f *S3) handlePut(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("uploadId") != "" {
+		f.handleUploadPart(w, r)
+		return
+	}
+	content, err := readBody(r)
+	if err != nil {
+		writeError(w, r, &Error{Code: http.StatusBadRequest, Message: err.Error()})
+		return
+	}
+	if err := f.putObject(objectFromVars(r), content); err != nil {
+		writeError(w, r, err)
+		return
+	}
+	w.Header().Set("ETag", etag(content))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *S3) putObject(bo BucketObject, content []byte) error {
+	if f.OnPutObject == nil {
+		return nil
+	}
+	return f.OnPutObject(bo, bytes.NewReader(content))
+}
+
+func (f *S3) handleGet(w http.ResponseWriter, r *http.Request) {
+	if f.OnGetObject == nil {
+		writeError(w, r, &Error{Code: http.StatusNotImplemented, Message: "GetObject is not supported"})
+		return
+	}
+	if err := f.OnGetObject(objectFromVars(r), w); err != nil {
+		writeError(w, r, err)
+	}
+}
+
+func (f *S3) handleHead(w http.ResponseWriter, r *http.Request) {
+	if f.OnHeadObject == nil {
+		writeError(w, r, &Error{Code: http.StatusNotImplemented, Message: "HeadObject is not supported"})
+		return
+	}
+	attrs, err := f.OnHeadObject(objectFromVars(r))
+	if err != nil {
+		writeError(w, r, err)
+		return
+	}
+	if attrs == nil {
+		writeError(w, r, ErrNoSuchKey)
+		return
+	}
+	h := w.Header()
+	if attrs.ContentType != "" {
+		h.Set("Content-Type", attrs.ContentType)
+	}
+	if attrs.ETag != "" {
+		h.Set("ETag", attrs.ETag)
+	}
+	if !attrs.LastModified.IsZero() {
+		h.Set("Last-Modified", attrs.LastModified.UTC().Format(http.TimeFormat))
+	}
+	for k, v := range attrs.Metadata {
+		h.Set("X-Amz-Meta-"+k, v)
+	}
+	h.Set("Content-Length", strconv.FormatInt(attrs.Size, 10))
+	w.WriteHeader(http.StatusOK)
+}
+
+func (f *S3) handleDelete(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Query().Get("uploadId") != "" {
+		f.handleAbortUpload(w, r)
+		return
+	}
+	if f.OnDeleteObject != nil {
+		err := f.OnDeleteObject(objectFromVars(r))
+		// DeleteObject of nonexistent key succeeds in S3.
+		if err != nil && err != ErrNoSuchKey {
+			writeError(w, r, err)
+			return
+		}
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (f *S3) handlePost(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	switch {
+	case q.Get("uploadId") != "":
+		f.handleCompleteUpload(w, r)
+	case hasQueryParam(r, "uploads"):
+		f.handleCreateUpload(w, r)
+	default:
+		writeError(w, r, &Error{Code: http.StatusNotImplemented, Message: "operation is not supported"})
+	}
+}
+
+func (f *S3) handleList(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if q.Get("list-type") != "2" {
+		writeError(w, r, &Error{Code: http.StatusNotImplemented, Message: "only ListObjectsV2 is supported"})
+		return
+	}
+	query := ListQuery{
+		Bucket:            mux.Vars(r)["bucket"],
+		Prefix:            q.Get("prefix"),
+		Delimiter:         q.Get("delimiter"),
+		ContinuationToken: q.Get("continuation-token"),
+		StartAfter:        q.Get("start-after"),
+		MaxKeys:           defaultMaxKeys,
+	}
+	if v := q.Get("max-keys"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			writeError(w, r, &Error{Code: http.StatusBadRequest, S3Code: "InvalidArgument", Message: "invalid max-keys value"})
+			return
+		}
+		query.MaxKeys = n
+	}
+
+	var objects []ObjectAttrs
+	if f.OnListObjects != nil {
+		var err error
+		if objects, err = f.OnListObjects(query); err != nil {
+			writeError(w, r, err)
+			return
+		}
+	}
+	writeXML(w, FilterObjects(objects, query).toResult(query))
+}
+
+func hasQueryParam(r *http.Request, name string) bool {
+	_, ok := r.URL.Query()[name]
+	return ok
+}
+
+// withAuth rejects requests that are not anonymous or SigV4 signed.
+func withAuth(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := checkAuth(r); err != nil {
+			writeError(w, r, err)
+			return
+		}
+		h(w, r)
+	}
+}
+
+// hosts returns path-style and virtual-hosted style endpoints host templates.
+func hosts() (pathStyle, virtualHosted []string) {
+	return []string{S3Host, "s3.{region}.amazonaws.com"},
+		[]string{"{bucket}." + S3Host, "{bucket}.s3.{region}.amazonaws.com"}
+}
+
+func (f *S3) addObjectRoutes(r *mux.Router, host, object, bucket string) {
+	r.Host(host).Path(object).Methods(http.MethodPut).HandlerFunc(withAuth(f.handlePut))
+	r.Host(host).Path(object).Methods(http.MethodGet).HandlerFunc(withAuth(f.handleGet))
+	r.Host(host).Path(object).Methods(http.MethodHead).HandlerFunc(withAuth(f.handleHead))
+	r.Host(host).Path(object).Methods(http.MethodDelete).HandlerFunc(withAuth(f.handleDelete))
+	r.Host(host).Path(object).Methods(http.MethodPost).HandlerFunc(withAuth(f.handlePost))
+	r.Host(host).Path(bucket).Methods(http.MethodGet).HandlerFunc(withAuth(f.handleList))
+}
+
+func (f *S3) AddMuxRoute(r *mux.Router) *mux.Router {
+	pathStyle, virtualHosted := hosts()
+	// Virtual-hosted style hosts are more specific so they are matched first.
+	for _, h := range virtualHosted {
+		f.addObjectRoutes(r, h, "/{key:.+}", "/")
+	}
+	for _, h := range pathStyle {
+		f.addObjectRoutes(r, h, "/{bucket}/{key:.+}", "/{bucket}")
+	}
+	return r
+}
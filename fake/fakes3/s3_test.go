@@ -0,0 +1,261 @@
+package fakes3
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+const sigV4Auth = "AWS4-HMAC-SHA256 Credential=AKID/20200101/us-east-1/s3/aws4_request, SignedHeaders=host;x-amz-date, Signature=abc"
+
+type memStore struct {
+	mtx     sync.Mutex
+	objects map[BucketObject][]byte
+}
+
+func (m *memStore) fake() *S3 {
+	m.objects = make(map[BucketObject][]byte)
+	return &S3{
+		OnPutObject: func(bo BucketObject, r io.Reader) error {
+			b, err := ioutil.ReadAll(r)
+			m.mtx.Lock()
+			m.objects[bo] = b
+			m.mtx.Unlock()
+			return err
+		},
+		OnGetObject: func(bo BucketObject, w io.Writer) error {
+			m.mtx.Lock()
+			b, ok := m.objects[bo]
+			m.mtx.Unlock()
+			if !ok {
+				return ErrNoSuchKey
+			}
+			_, err := w.Write(b)
+			return err
+		},
+		OnHeadObject: func(bo BucketObject) (*ObjectAttrs, error) {
+			m.mtx.Lock()
+			b, ok := m.objects[bo]
+			m.mtx.Unlock()
+			if !ok {
+				return nil, ErrNoSuchKey
+			}
+			return &ObjectAttrs{Key: bo.Key, Size: int64(len(b)), ETag: etag(b)}, nil
+		},
+		OnListObjects: func(q ListQuery) ([]ObjectAttrs, error) {
+			m.mtx.Lock()
+			defer m.mtx.Unlock()
+			var out []ObjectAttrs
+			for bo, b := range m.objects {
+				if bo.Bucket == q.Bucket {
+					out = append(out, ObjectAttrs{Key: bo.Key, Size: int64(len(b))})
+				}
+			}
+			return out, nil
+		},
+		OnDeleteObject: func(bo BucketObject) error {
+			m.mtx.Lock()
+			defer m.mtx.Unlock()
+			delete(m.objects, bo)
+			return nil
+		},
+	}
+}
+
+func newTestServer(f *S3) *httptest.Server {
+	return httptest.NewServer(f.AddMuxRoute(mux.NewRouter()))
+}
+
+func do(t *testing.T, srv *httptest.Server, method, host, path string, body []byte, header http.Header) (*http.Response, []byte) {
+	req, err := http.NewRequest(method, srv.URL+path, bytes.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to create request: %v", err)
+	}
+	req.Host = host
+	req.Header.Set("Authorization", sigV4Auth)
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	b, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("failed to read response: %v", err)
+	}
+	return resp, b
+}
+
+func TestObjectOperations(t *testing.T) {
+	var m memStore
+	srv := newTestServer(m.fake())
+	defer srv.Close()
+
+	for _, tc := range []struct {
+		name string
+		host string
+		path string
+	}{
+		{"path-style", "s3.amazonaws.com", "/bucket/dir/file.txt"},
+		{"regional path-style", "s3.eu-west-1.amazonaws.com", "/bucket/dir/file.txt"},
+		{"virtual-hosted", "bucket.s3.amazonaws.com", "/dir/file.txt"},
+		{"regional virtual-hosted", "bucket.s3.eu-west-1.amazonaws.com", "/dir/file.txt"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			resp, _ := do(t, srv, http.MethodPut, tc.host, tc.path, []byte("content"), nil)
+			if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") != etag([]byte("content")) {
+				t.Fatalf("unexpected put response: %v %v", resp.Status, resp.Header)
+			}
+			if _, ok := m.objects[BucketObject{Bucket: "bucket", Key: "dir/file.txt"}]; !ok {
+				t.Fatalf("object not stored: %v", m.objects)
+			}
+
+			resp, _ = do(t, srv, http.MethodHead, tc.host, tc.path, nil, nil)
+			if resp.StatusCode != http.StatusOK || resp.ContentLength != 7 {
+				t.Fatalf("unexpected head response: %v %v", resp.Status, resp.Header)
+			}
+
+			_, b := do(t, srv, http.MethodGet, tc.host, tc.path, nil, nil)
+			if got, want := string(b), "content"; got != want {
+				t.Fatalf("content mismatch, got: %q want: %q", got, want)
+			}
+
+			resp, _ = do(t, srv, http.MethodDelete, tc.host, tc.path, nil, nil)
+			if resp.StatusCode != http.StatusNoContent {
+				t.Fatalf("unexpected delete status: %v", resp.Status)
+			}
+
+			resp, b = do(t, srv, http.MethodGet, tc.host, tc.path, nil, nil)
+			if resp.StatusCode != http.StatusNotFound || !strings.Contains(string(b), "<Code>NoSuchKey</Code>") {
+				t.Fatalf("unexpected get response: %v %s", resp.Status, b)
+			}
+		})
+	}
+}
+
+func TestListObjectsV2(t *testing.T) {
+	var m memStore
+	srv := newTestServer(m.fake())
+	defer srv.Close()
+
+	for _, key := range []string{"a.txt", "dir/b.txt", "dir/c.txt", "e.txt"} {
+		do(t, srv, http.MethodPut, "s3.amazonaws.com", "/bucket/"+key, []byte(key), nil)
+	}
+
+	var (
+		keys   []string
+		token  string
+		result listResult
+	)
+	for {
+		path := "/bucket?list-type=2&delimiter=/&max-keys=2"
+		if token != "" {
+			path += "&continuation-token=" + token
+		}
+		resp, b := do(t, srv, http.MethodGet, "s3.amazonaws.com", path, nil, nil)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected list response: %v %s", resp.Status, b)
+		}
+		result = listResult{}
+		if err := xml.Unmarshal(b, &result); err != nil {
+			t.Fatalf("failed to decode list result: %v", err)
+		}
+		for _, c := range result.Contents {
+			keys = append(keys, c.Key)
+		}
+		for _, p := range result.CommonPrefixes {
+			keys = append(keys, p.Prefix)
+		}
+		if !result.IsTruncated {
+			break
+		}
+		token = result.NextContinuationToken
+	}
+	if want := []string{"a.txt", "dir/", "e.txt"}; !reflect.DeepEqual(keys, want) {
+		t.Fatalf("keys mismatch, got: %v want: %v", keys, want)
+	}
+
+	_, b := do(t, srv, http.MethodGet, "s3.amazonaws.com", "/bucket?list-type=2&max-keys=0", nil, nil)
+	result = listResult{}
+	if err := xml.Unmarshal(b, &result); err != nil {
+		t.Fatalf("failed to decode list result: %v", err)
+	}
+	if result.MaxKeys != 0 || result.KeyCount != 0 || !result.IsTruncated {
+		t.Fatalf("expected empty truncated list for max-keys=0, got: %s", b)
+	}
+}
+
+func TestMultipartUpload(t *testing.T) {
+	var m memStore
+	srv := newTestServer(m.fake())
+	defer srv.Close()
+	const host, path = "bucket.s3.amazonaws.com", "/big.bin"
+
+	resp, b := do(t, srv, http.MethodPost, host, path+"?uploads", nil, nil)
+	var initiate initiateUploadResult
+	if err := xml.Unmarshal(b, &initiate); err != nil || initiate.UploadID == "" {
+		t.Fatalf("unexpected initiate response: %v %s", resp.Status, b)
+	}
+
+	var complete bytes.Buffer
+	complete.WriteString("<CompleteMultipartUpload>")
+	for i, part := range []string{"part1-", "part2-", "part3"} {
+		header := http.Header{}
+		if i == 1 {
+			// Second part is sent with streaming SigV4 payload.
+			header.Set("X-Amz-Content-Sha256", streamingPayload)
+			part = "3;chunk-signature=abc\r\npar\r\n3;chunk-signature=def\r\nt2-\r\n0;chunk-signature=ghi\r\n\r\n"
+		}
+		resp, b := do(t, srv, http.MethodPut, host, path+"?partNumber="+strconv.Itoa(i+1)+"&uploadId="+initiate.UploadID, []byte(part), header)
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("unexpected upload part response: %v %s", resp.Status, b)
+		}
+		complete.WriteString("<Part><PartNumber>" + strconv.Itoa(i+1) + "</PartNumber><ETag>" + resp.Header.Get("ETag") + "</ETag></Part>")
+	}
+	complete.WriteString("</CompleteMultipartUpload>")
+
+	resp, b = do(t, srv, http.MethodPost, host, path+"?uploadId="+initiate.UploadID, complete.Bytes(), nil)
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(b), "-3&#34;</ETag>") {
+		t.Fatalf("unexpected complete response: %v %s", resp.Status, b)
+	}
+	if got, want := string(m.objects[BucketObject{Bucket: "bucket", Key: "big.bin"}]), "part1-part2-part3"; got != want {
+		t.Fatalf("content mismatch, got: %q want: %q", got, want)
+	}
+
+	resp, b = do(t, srv, http.MethodDelete, host, path+"?uploadId="+initiate.UploadID, nil, nil)
+	if resp.StatusCode != http.StatusNotFound || !strings.Contains(string(b), "NoSuchUpload") {
+		t.Fatalf("unexpected abort response: %v %s", resp.Status, b)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	var m memStore
+	srv := newTestServer(m.fake())
+	defer srv.Close()
+
+	resp, b := do(t, srv, http.MethodGet, "s3.amazonaws.com", "/bucket/file.txt", nil, http.Header{
+		"Authorization": {"AWS AKID:signature"},
+	})
+	if resp.StatusCode != http.StatusBadRequest || !strings.Contains(string(b), "InvalidRequest") {
+		t.Fatalf("unexpected response: %v %s", resp.Status, b)
+	}
+
+	resp, b = do(t, srv, http.MethodGet, "s3.amazonaws.com",
+		"/bucket/file.txt?X-Amz-Algorithm=AWS4-HMAC-SHA256&X-Amz-Date=20200101T000000Z&X-Amz-Expires=60&X-Amz-Signature=abc",
+		nil, http.Header{"Authorization": {""}})
+	if resp.StatusCode != http.StatusForbidden || !strings.Contains(string(b), "Request has expired") {
+		t.Fatalf("unexpected response: %v %s", resp.Status, b)
+	}
+}
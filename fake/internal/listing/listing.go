@@ -0,0 +1,73 @@
+// Package listing implements objects listing rules shared by fake storages.
+package listing
+
+import (
+	"sort"
+	"strings"
+)
+
+// Query selects keys starting with Prefix and greater than After. Keys containing
+// Delimiter after the Prefix are rolled up into a single prefix. At most Max keys
+// and prefixes are listed, zero Max lists none of them.
+type Query struct {
+	Prefix    string
+	Delimiter string
+	After     string
+	Max       int
+}
+
+// Page is a single page of listed keys.
+type Page struct {
+	// Objects are indexes of listed keys in the keys collection, in keys order.
+	Objects  []int
+	Prefixes []string
+	// Next is the last listed key or prefix if there are more results.
+	Next string
+	// Truncated is set if there are more results, Next is empty when nothing was listed.
+	Truncated bool
+}
+
+// List applies query prefix, delimiter and paging rules to keys collection.
+func List(keys []string, q Query) Page {
+	sorted := make([]int, len(keys))
+	for i := range sorted {
+		sorted[i] = i
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return keys[sorted[i]] < keys[sorted[j]]
+	})
+
+	var (
+		out  Page
+		last string
+		n    int
+	)
+	for _, idx := range sorted {
+		name := keys[idx]
+		if !strings.HasPrefix(name, q.Prefix) {
+			continue
+		}
+		key, isPrefix := name, false
+		if q.Delimiter != "" {
+			rest := name[len(q.Prefix):]
+			if i := strings.Index(rest, q.Delimiter); i >= 0 {
+				key, isPrefix = q.Prefix+rest[:i+len(q.Delimiter)], true
+			}
+		}
+		if key <= q.After || key == last {
+			continue
+		}
+		if n == q.Max {
+			out.Next, out.Truncated = last, true
+			break
+		}
+		if isPrefix {
+			out.Prefixes = append(out.Prefixes, key)
+		} else {
+			out.Objects = append(out.Objects, idx)
+		}
+		last = key
+		n++
+	}
+	return out
+}
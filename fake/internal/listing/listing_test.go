@@ -0,0 +1,28 @@
+package listing
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestList(t *testing.T) {
+	keys := []string{"in/sub/c.txt", "in/b.txt", "out.txt", "in/a.txt", "in/sub/d.txt"}
+
+	page := List(keys, Query{Prefix: "in/", Delimiter: "/", Max: 2})
+	want := Page{Objects: []int{3, 1}, Next: "in/b.txt", Truncated: true}
+	if !reflect.DeepEqual(page, want) {
+		t.Fatalf("first page mismatch, got: %+v want: %+v", page, want)
+	}
+
+	page = List(keys, Query{Prefix: "in/", Delimiter: "/", After: page.Next, Max: 2})
+	want = Page{Prefixes: []string{"in/sub/"}}
+	if !reflect.DeepEqual(page, want) {
+		t.Fatalf("second page mismatch, got: %+v want: %+v", page, want)
+	}
+
+	page = List(keys, Query{Prefix: "in/"})
+	want = Page{Truncated: true}
+	if !reflect.DeepEqual(page, want) {
+		t.Fatalf("empty page mismatch, got: %+v want: %+v", page, want)
+	}
+}
@@ -22,3 +22,17 @@ type Loggable interface {
 	// Name returns container name.
 	Name() string
 }
+
+// Resettable allows to restore component initial state
+// between test cases.
+type Resettable interface {
+	// Reset removes state left by previous test case.
+	Reset(context.Context) error
+}
+
+// Snapshotter allows to take component state restored by Reset
+// once all components are started and before SUT is started.
+type Snapshotter interface {
+	// Snapshot takes component state snapshot.
+	Snapshot(context.Context) error
+}
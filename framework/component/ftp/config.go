@@ -1,12 +1,67 @@
 package ftp
 
 import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"github.com/smallinsky/mtf/pkg/cert"
 	"github.com/smallinsky/mtf/pkg/docker"
 )
 
+// Protocol selects file transfer server variant.
+type Protocol int
+
+const (
+	ProtocolFTP Protocol = iota
+	// ProtocolFTPS enables explicit TLS (AUTH TLS) with the MTF generated cert, plain FTP
+	// connections are still accepted.
+	ProtocolFTPS
+	// ProtocolSFTP runs SSH file transfer server instead of FTP.
+	ProtocolSFTP
+)
+
+const (
+	defaultUser           = "test"
+	defaultPassword       = "test"
+	defaultFTPPort        = 21
+	defaultSFTPPort       = 2222
+	defaultPassivePortMin = 21100
+	defaultPassivePortMax = 21110
+)
+
+type User struct {
+	User     string
+	Password string
+}
+
 type FTPConfig struct {
 	User     string
 	Password string
+	// Users are additional accounts sharing the same /ftp root dir.
+	Users []User
+	// HostPort is a host port forwarded to FTP control port or SFTP port.
+	HostPort int
+	// PassivePortMin and PassivePortMax limit passive mode data ports, the same
+	// ports are forwarded to the host.
+	PassivePortMin int
+	PassivePortMax int
+	Protocol       Protocol
+}
+
+func (c *FTPConfig) setDefaults() {
+	if c.User == "" {
+		c.User, c.Password = defaultUser, defaultPassword
+	}
+	if c.HostPort == 0 {
+		c.HostPort = defaultFTPPort
+		if c.Protocol == ProtocolSFTP {
+			c.HostPort = defaultSFTPPort
+		}
+	}
+	if c.PassivePortMin == 0 && c.PassivePortMax == 0 {
+		c.PassivePortMin, c.PassivePortMax = defaultPassivePortMin, defaultPassivePortMax
+	}
 }
 
 func BuildContainerConfig(cfg FTPConfig) (*docker.ContainerConfig, error) {
@@ -16,30 +71,57 @@ func BuildContainerConfig(cfg FTPConfig) (*docker.ContainerConfig, error) {
 		network = "mtf_net"
 	)
 
-	return &docker.ContainerConfig{
+	cfg.setDefaults()
+	if cfg.PassivePortMin > cfg.PassivePortMax {
+		return nil, fmt.Errorf("invalid passive ports range %d-%d", cfg.PassivePortMin, cfg.PassivePortMax)
+	}
+
+	var users []string
+	for _, u := range cfg.Users {
+		if strings.ContainsAny(u.User+u.Password, ": ") {
+			return nil, fmt.Errorf("ftp user %q credentials can't contain spaces or colons", u.User)
+		}
+		users = append(users, u.User+":"+u.Password)
+	}
+
+	conf := &docker.ContainerConfig{
 		Image:       image,
 		Name:        name,
 		NetworkName: network,
 		Env: []string{
-			"FTP_USER=test",
-			"FTP_PASS=test",
-		},
-		PortMap: docker.PortMap{
-			20:    20,
-			21:    21,
-			21100: 21100,
-			21101: 21101,
-			21102: 21102,
-			21103: 21103,
-			21104: 21104,
-			21105: 21105,
-			21106: 21106,
-			21107: 21107,
-			21108: 21108,
-			21109: 21109,
-			21110: 21110,
+			"FTP_USER=" + cfg.User,
+			"FTP_PASS=" + cfg.Password,
+			"FTP_USERS=" + strings.Join(users, " "),
 		},
+		PortMap:       docker.PortMap{},
 		AttachIfExist: false,
-		WaitPolicy:    &docker.WaitForPort{Port: 21},
-	}, nil
+	}
+
+	switch cfg.Protocol {
+	case ProtocolSFTP:
+		conf.Env = append(conf.Env, "FTP_PROTOCOL=sftp")
+		conf.PortMap[22] = docker.HostPort(cfg.HostPort)
+		conf.WaitPolicy = &docker.WaitForPort{Port: 22}
+		return conf, nil
+	case ProtocolFTPS:
+		conf.Env = append(conf.Env, "FTP_PROTOCOL=ftps")
+		conf.Mounts = docker.Mounts{
+			{
+				Source: filepath.Dir(cert.ServerCertFile),
+				Target: "/etc/vsftpd/cert",
+			},
+		}
+	}
+
+	conf.Env = append(conf.Env,
+		fmt.Sprintf("PASV_MIN_PORT=%d", cfg.PassivePortMin),
+		fmt.Sprintf("PASV_MAX_PORT=%d", cfg.PassivePortMax),
+	)
+	conf.PortMap[20] = 20
+	conf.PortMap[21] = docker.HostPort(cfg.HostPort)
+	for p := cfg.PassivePortMin; p <= cfg.PassivePortMax; p++ {
+		conf.PortMap[docker.ContainerPort(p)] = docker.HostPort(p)
+	}
+	conf.WaitPolicy = &docker.WaitForPort{Port: 21}
+	return conf, nil
 }
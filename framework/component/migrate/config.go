@@ -2,21 +2,78 @@ package migrate
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 	"path/filepath"
+	"time"
 
 	"github.com/smallinsky/mtf/pkg/docker"
 )
 
+// Driver selects database type the migrations are applied to.
+type Driver int
+
+const (
+	DriverMySQL Driver = iota
+	DriverPostgres
+)
+
 type MigrateConfig struct {
-	Path     string
+	Path string
+	// Driver defaults to MySQL.
+	Driver Driver
+	// User defaults to root for MySQL and to postgres for Postgres.
+	User     string
 	Password string
+	// Port and Hostname default to the MTF database component address in the MTF network.
 	Port     string
 	Hostname string
 	Database string
 	Labels   map[string]string
+	// Tool selects built-in migrator, Migrator can be set to use a custom one.
+	Tool     Tool
+	Migrator Migrator
+	// Version is a schema version migrations are applied up to, all migrations are
+	// applied if it's 0. Resulting schema version is verified with ExpectedVersion.
+	Version uint64
+	// TestRollback reverts all migrations after they are applied and applies them again.
+	TestRollback bool
+	// SchemaFile is a golden schema snapshot compared with the database schema after
+	// migrations, SchemaAddr is the database host address used to dump the schema.
+	SchemaFile string
+	SchemaAddr string
+	// UpdateSchema writes the snapshot to SchemaFile instead of comparing it.
+	UpdateSchema bool
+	// Timeout limits migration time, defaults to 5 minutes.
+	Timeout time.Duration
 
 	absolutePath string
+	latest       uint64
+}
+
+func (c *MigrateConfig) setDefaults() {
+	switch c.Driver {
+	case DriverPostgres:
+		if c.User == "" {
+			c.User = "postgres"
+		}
+		if c.Hostname == "" {
+			c.Hostname = "postgres_mtf"
+		}
+		if c.Port == "" {
+			c.Port = "5432"
+		}
+	default:
+		if c.User == "" {
+			c.User = "root"
+		}
+		if c.Hostname == "" {
+			c.Hostname = "mysql_mtf"
+		}
+		if c.Port == "" {
+			c.Port = "3306"
+		}
+	}
 }
 
 func (c *MigrateConfig) Build() error {
@@ -32,20 +89,61 @@ func (c *MigrateConfig) Build() error {
 	if err != nil {
 		return err
 	}
-	return nil
+
+	if c.Migrator == nil {
+		c.Migrator = c.Tool.migrator()
+	}
+	c.latest, err = latestVersion(c.Migrator, c.absolutePath)
+	return err
+}
+
+// ExpectedVersion returns schema version expected after migration, it is Version or
+// the latest migration version found in Path when Version is 0. It returns 0 when
+// the migrator doesn't use numeric versions.
+func (c *MigrateConfig) ExpectedVersion() uint64 {
+	if c.Version != 0 {
+		return c.Version
+	}
+	return c.latest
 }
 
 func (c *MigrateConfig) DBConnString() string {
-	return fmt.Sprintf("mysql://root:%s@tcp(%s:%s)/%s", c.Password, c.Hostname, c.Port, c.Database)
+	c.setDefaults()
+	switch c.Driver {
+	case DriverPostgres:
+		u := url.URL{
+			Scheme:   "postgres",
+			User:     url.UserPassword(c.User, c.Password),
+			Host:     c.Hostname + ":" + c.Port,
+			Path:     "/" + c.Database,
+			RawQuery: "sslmode=disable",
+		}
+		return u.String()
+	default:
+		return fmt.Sprintf("mysql://%s:%s@tcp(%s:%s)/%s", c.User, c.Password, c.Hostname, c.Port, c.Database)
+	}
+}
+
+// driverDSN returns connection string in the go database driver format.
+func (c *MigrateConfig) driverDSN() string {
+	if c.Driver == DriverPostgres {
+		return c.DBConnString()
+	}
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", c.User, c.Password, c.Hostname, c.Port, c.Database)
 }
 
 func BuildContainerConfig(config MigrateConfig) (*docker.ContainerConfig, error) {
 	if err := config.Build(); err != nil {
 		return nil, err
 	}
+	config.setDefaults()
+	script, err := config.Migrator.Script(config)
+	if err != nil {
+		return nil, err
+	}
 
 	var (
-		image   = "migrate/migrate"
+		image   = config.Migrator.Image()
 		name    = "migrate_mtf"
 		network = "mtf_net"
 	)
@@ -58,12 +156,9 @@ func BuildContainerConfig(config MigrateConfig) (*docker.ContainerConfig, error)
 		Mounts: docker.Mounts{
 			docker.Mount{
 				Source: config.absolutePath,
-				Target: "/migrations",
+				Target: migrationsDir,
 			},
 		},
-		Cmd: []string{
-			"-path", "/migrations",
-			"-database", config.DBConnString(), "up",
-		},
+		EntryPoint: []string{"/bin/sh", "-c", script},
 	}, nil
 }
@@ -0,0 +1,50 @@
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/smallinsky/mtf/pkg/docker"
+)
+
+// migrateToolDockerfile provides goose, sql-migrate and database clients, it is the
+// same as docker/migrate_tools/Dockerfile.
+const migrateToolDockerfile = `FROM golang:1.13.1-alpine3.10 AS builder
+
+RUN apk add git gcc musl-dev
+RUN go get github.com/pressly/goose/cmd/goose
+RUN go get github.com/rubenv/sql-migrate/sql-migrate
+
+FROM alpine:3.10
+
+RUN apk update \
+ && apk add mysql-client postgresql-client
+
+COPY --from=builder /go/bin/goose /go/bin/sql-migrate /usr/local/bin/
+`
+
+// buildMigrateToolImage builds migrate tools image if it doesn't exist yet.
+func buildMigrateToolImage(ctx context.Context, cli *docker.Docker) error {
+	if cli.ImageExists(ctx, migrateToolImage) {
+		return nil
+	}
+	dir, err := ioutil.TempDir("", "mtf_migrate_tools")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	if err := ioutil.WriteFile(filepath.Join(dir, "Dockerfile"), []byte(migrateToolDockerfile), 0644); err != nil {
+		return err
+	}
+	err = cli.BuildImageFromConfig(ctx, docker.BuildImageConfig{
+		Path: dir,
+		Tag:  migrateToolImage,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to build %s image: %v", migrateToolImage, err)
+	}
+	return nil
+}
@@ -2,15 +2,15 @@ package migrate
 
 import (
 	"context"
-	"fmt"
-	"io/ioutil"
+	"io"
+	"os"
 	"time"
 
 	"github.com/smallinsky/mtf/pkg/docker"
 )
 
 const (
-	fetchStatusDelayStep = time.Millisecond * 200
+	defaultTimeout = time.Minute * 5
 )
 
 type Component struct {
@@ -23,43 +23,43 @@ func New(cli *docker.Docker, config MigrateConfig) (*Component, error) {
 	if err != nil {
 		return nil, err
 	}
+	if containerConf.Image == migrateToolImage {
+		if err := buildMigrateToolImage(context.Background(), cli); err != nil {
+			return nil, err
+		}
+	}
 
 	container, err := cli.NewContainer(*containerConf)
 	if err != nil {
 		return nil, err
 	}
 
+	config.setDefaults()
 	return &Component{
 		config:    config,
 		Container: container,
 	}, nil
 }
 
+// Start runs migrations and waits until they are applied, migration tool output
+// is streamed to stdout.
 func (c *Component) Start(ctx context.Context) error {
-	if err := c.Container.Start(ctx); err != nil {
-		return err
+	timeout := c.config.Timeout
+	if timeout == 0 {
+		timeout = defaultTimeout
 	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
 
-	state, err := c.Container.GetState(ctx)
-	if err != nil {
+	out := docker.NewLogWriter(os.Stdout, "["+c.Container.Name()+"] ")
+	defer out.Close()
+	if err := c.Container.Run(ctx, out); err != nil {
 		return err
 	}
 
-	for {
-		if !state.Running {
-			break
-		}
-		time.Sleep(fetchStatusDelayStep)
-		state, err = c.Container.GetState(ctx)
-		if err != nil {
-			return err
-		}
-	}
-
-	if state.ExitCode != 0 {
-		return c.handleExecutionError(ctx)
+	if c.config.SchemaFile != "" {
+		return c.checkSchema(ctx)
 	}
-
 	return nil
 }
 
@@ -67,14 +67,10 @@ func (c *Component) Stop(ctx context.Context) error {
 	return c.Container.Stop(ctx)
 }
 
-func (c *Component) handleExecutionError(ctx context.Context) error {
-	r, err := c.Container.Logs(ctx)
-	if err != nil {
-		return err
-	}
-	buff, err := ioutil.ReadAll(r)
-	if err != nil {
-		return err
-	}
-	return fmt.Errorf("migarton command filed:\n%s", string(buff))
+func (c *Component) Logs(ctx context.Context) (io.Reader, error) {
+	return c.Container.Logs(ctx)
+}
+
+func (c *Component) Name() string {
+	return c.Container.Name()
 }
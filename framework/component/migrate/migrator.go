@@ -0,0 +1,270 @@
+package migrate
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	migrationsDir = "/migrations"
+	// migrateToolImage is built locally from migrateToolDockerfile when it doesn't exist.
+	migrateToolImage = "migrate_tools:mtf"
+)
+
+// Tool selects one of the built-in migrators.
+type Tool int
+
+const (
+	// ToolMigrate uses golang-migrate, files are named <version>_<name>.(up|down).sql.
+	ToolMigrate Tool = iota
+	// ToolGoose uses goose, files are named <version>_<name>.sql.
+	ToolGoose
+	// ToolSQLMigrate uses sql-migrate, it doesn't support target version.
+	ToolSQLMigrate
+	// ToolPlainSQL applies Flyway style V<version>__<name>.sql files with the database client,
+	// U<version>__<name>.sql files are used to revert migrations.
+	ToolPlainSQL
+)
+
+func (t Tool) migrator() Migrator {
+	switch t {
+	case ToolGoose:
+		return Goose{}
+	case ToolSQLMigrate:
+		return SQLMigrate{}
+	case ToolPlainSQL:
+		return PlainSQL{}
+	default:
+		return GolangMigrate{}
+	}
+}
+
+// Migrator runs migration tool in a container with migrations dir mounted at /migrations.
+type Migrator interface {
+	// Image is a docker image providing the tool and a shell.
+	Image() string
+	// Version returns schema version of the migration file, false if the file
+	// isn't a migration or the tool doesn't use numeric versions.
+	Version(file string) (uint64, bool)
+	// Script returns shell script applying migrations up to the config Version, or all
+	// migrations if Version is 0. The script reverts all migrations and applies them
+	// again if TestRollback is set and fails if resulting schema version is different
+	// than the config ExpectedVersion.
+	Script(c MigrateConfig) (string, error)
+}
+
+// latestVersion returns the highest migration version in the dir.
+func latestVersion(m Migrator, dir string) (uint64, error) {
+	infos, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return 0, err
+	}
+	var latest uint64
+	for _, fi := range infos {
+		if v, ok := m.Version(fi.Name()); ok && !fi.IsDir() && v > latest {
+			latest = v
+		}
+	}
+	return latest, nil
+}
+
+func fileVersion(re *regexp.Regexp, file string) (uint64, bool) {
+	m := re.FindStringSubmatch(file)
+	if m == nil {
+		return 0, false
+	}
+	v, err := strconv.ParseUint(m[1], 10, 64)
+	return v, err == nil
+}
+
+// GolangMigrate runs github.com/golang-migrate/migrate.
+type GolangMigrate struct{}
+
+var golangMigrateFile = regexp.MustCompile(`^(\d+)_.*\.(up|down)\.sql$`)
+
+func (GolangMigrate) Image() string {
+	return "migrate/migrate"
+}
+
+func (GolangMigrate) Version(file string) (uint64, bool) {
+	return fileVersion(golangMigrateFile, file)
+}
+
+func (GolangMigrate) Script(c MigrateConfig) (string, error) {
+	migrate := fmt.Sprintf("migrate -path %s -database %s", migrationsDir, shellQuote(c.DBConnString()))
+	up := migrate + " up"
+	if c.Version != 0 {
+		up = fmt.Sprintf("%s goto %d", migrate, c.Version)
+	}
+
+	var s script
+	s.run(up)
+	if c.TestRollback {
+		s.run(migrate + " down -all")
+		s.run(up)
+	}
+	if v := c.ExpectedVersion(); v != 0 {
+		// Version is printed to stderr, dirty schema is reported as "<version> (dirty)".
+		s.verify(fmt.Sprintf("%s version 2>&1", migrate), v)
+	}
+	return s.String(), nil
+}
+
+// Goose runs github.com/pressly/goose, only SQL migrations are supported.
+type Goose struct{}
+
+var gooseFile = regexp.MustCompile(`^(\d+)_.*\.sql$`)
+
+func (Goose) Image() string {
+	return migrateToolImage
+}
+
+func (Goose) Version(file string) (uint64, bool) {
+	return fileVersion(gooseFile, file)
+}
+
+func (Goose) Script(c MigrateConfig) (string, error) {
+	dialect := "mysql"
+	if c.Driver == DriverPostgres {
+		dialect = "postgres"
+	}
+	goose := fmt.Sprintf("goose -dir %s %s %s", migrationsDir, dialect, shellQuote(c.driverDSN()))
+	up := goose + " up"
+	if c.Version != 0 {
+		up = fmt.Sprintf("%s up-to %d", goose, c.Version)
+	}
+
+	var s script
+	s.run(up)
+	if c.TestRollback {
+		s.run(goose + " reset")
+		s.run(up)
+	}
+	if v := c.ExpectedVersion(); v != 0 {
+		s.verify(fmt.Sprintf(`%s version 2>&1 | sed -n 's/.*version \([0-9]*\).*/\1/p'`, goose), v)
+	}
+	return s.String(), nil
+}
+
+// SQLMigrate runs github.com/rubenv/sql-migrate, all migrations are always applied.
+type SQLMigrate struct{}
+
+func (SQLMigrate) Image() string {
+	return migrateToolImage
+}
+
+func (SQLMigrate) Version(string) (uint64, bool) {
+	return 0, false
+}
+
+func (SQLMigrate) Script(c MigrateConfig) (string, error) {
+	if c.Version != 0 {
+		return "", fmt.Errorf("sql-migrate doesn't support target version")
+	}
+	dialect := "mysql"
+	if c.Driver == DriverPostgres {
+		dialect = "postgres"
+	}
+	config := fmt.Sprintf("mtf:\n  dialect: %s\n  datasource: %s\n  dir: %s\n", dialect, c.driverDSN(), migrationsDir)
+	sqlMigrate := "sql-migrate %s -config=/tmp/dbconfig.yml -env=mtf"
+
+	var s script
+	s.run(fmt.Sprintf("printf '%%s' %s > /tmp/dbconfig.yml", shellQuote(config)))
+	s.run(fmt.Sprintf(sqlMigrate, "up"))
+	if c.TestRollback {
+		s.run(fmt.Sprintf(sqlMigrate, "down -limit=0"))
+		s.run(fmt.Sprintf(sqlMigrate, "up"))
+	}
+	s.run(fmt.Sprintf(`if %s | grep -Eq '\| +no +\|'; then echo "not all migrations were applied"; exit 1; fi`,
+		fmt.Sprintf(sqlMigrate, "status")))
+	return s.String(), nil
+}
+
+// PlainSQL applies Flyway style migrations with mysql or psql client and keeps applied
+// versions in the flyway_history table.
+type PlainSQL struct{}
+
+var plainSQLFile = regexp.MustCompile(`^V(\d+)__.*\.sql$`)
+
+func (PlainSQL) Image() string {
+	return migrateToolImage
+}
+
+func (PlainSQL) Version(file string) (uint64, bool) {
+	return fileVersion(plainSQLFile, file)
+}
+
+func (PlainSQL) Script(c MigrateConfig) (string, error) {
+	var s script
+	switch c.Driver {
+	case DriverPostgres:
+		client := fmt.Sprintf("psql -h %s -p %s -U %s -d %s -v ON_ERROR_STOP=1 -q",
+			shellQuote(c.Hostname), shellQuote(c.Port), shellQuote(c.User), shellQuote(c.Database))
+		s.run("export PGPASSWORD=" + shellQuote(c.Password))
+		s.run(fmt.Sprintf(`q() { %s -tA -c "$1"; }`, client))
+		s.run(fmt.Sprintf(`apply() { %s -f "$1"; }`, client))
+	default:
+		client := fmt.Sprintf("mysql -h %s -P %s -u %s %s",
+			shellQuote(c.Hostname), shellQuote(c.Port), shellQuote(c.User), shellQuote(c.Database))
+		s.run("export MYSQL_PWD=" + shellQuote(c.Password))
+		s.run(fmt.Sprintf(`q() { %s -N -s -e "$1"; }`, client))
+		s.run(fmt.Sprintf(`apply() { %s < "$1"; }`, client))
+	}
+	s.run(`q "CREATE TABLE IF NOT EXISTS flyway_history (version BIGINT PRIMARY KEY, script VARCHAR(255) NOT NULL)"`)
+	s.run(fmt.Sprintf(`up() {
+  for m in $(ls %[1]s | sed -n 's/^V\([0-9][0-9]*\)__.*\.sql$/\1:&/p' | sort -n); do
+    v=${m%%%%:*}; f=${m#*:}
+    [ %[2]d -eq 0 ] || [ "$v" -le %[2]d ] || break
+    [ "$(q "SELECT COUNT(*) FROM flyway_history WHERE version = $v")" = "0" ] || continue
+    echo "applying $f"; apply "%[1]s/$f"
+    q "INSERT INTO flyway_history (version, script) VALUES ($v, '$f')"
+  done
+}`, migrationsDir, c.Version))
+	s.run(fmt.Sprintf(`down() {
+  for v in $(q "SELECT version FROM flyway_history ORDER BY version DESC"); do
+    f=$(ls %[1]s | grep -E "^U0*${v}__.*\.sql$" | head -n 1)
+    [ -n "$f" ] || { echo "missing undo migration of version $v"; exit 1; }
+    echo "reverting $f"; apply "%[1]s/$f"
+    q "DELETE FROM flyway_history WHERE version = $v"
+  done
+}`, migrationsDir))
+	s.run("up")
+	if c.TestRollback {
+		s.run("down")
+		s.run("up")
+	}
+	if v := c.ExpectedVersion(); v != 0 {
+		s.verify(`q "SELECT COALESCE(MAX(version), 0) FROM flyway_history"`, v)
+	}
+	return s.String(), nil
+}
+
+// script builds shell script failing on the first failed command.
+type script struct {
+	buff bytes.Buffer
+}
+
+func (s *script) run(cmd string) {
+	if s.buff.Len() == 0 {
+		s.buff.WriteString("set -e\n")
+	}
+	s.buff.WriteString(cmd + "\n")
+}
+
+// verify fails the script if cmd output is different than expected version.
+func (s *script) verify(cmd string, version uint64) {
+	s.run(fmt.Sprintf(`v=$(%s)`, cmd))
+	s.run(fmt.Sprintf(`if [ "$v" != "%d" ]; then echo "expected schema version %d, got: $v"; exit 1; fi`, version, version))
+}
+
+func (s *script) String() string {
+	return s.buff.String()
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
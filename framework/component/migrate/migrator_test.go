@@ -0,0 +1,47 @@
+package migrate
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScriptVersion(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mtf_migrate")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, file := range []string{"1_users.up.sql", "1_users.down.sql", "2_orders.up.sql", "2_orders.down.sql"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, file), nil, 0644); err != nil {
+			t.Fatalf("failed to write migration: %v", err)
+		}
+	}
+
+	for _, tc := range []struct {
+		version  uint64
+		contains []string
+	}{
+		{0, []string{" up\n", `"$v" != "2"`}},
+		{1, []string{" goto 1\n", `"$v" != "1"`}},
+	} {
+		c := MigrateConfig{Path: dir, Version: tc.version}
+		if err := c.Build(); err != nil {
+			t.Fatalf("failed to build config: %v", err)
+		}
+		if c.Version != tc.version {
+			t.Fatalf("version was changed to %d", c.Version)
+		}
+		script, err := c.Migrator.Script(c)
+		if err != nil {
+			t.Fatalf("failed to create script: %v", err)
+		}
+		for _, s := range tc.contains {
+			if !strings.Contains(script, s) {
+				t.Fatalf("script of version %d doesn't contain %q:\n%s", tc.version, s, script)
+			}
+		}
+	}
+}
@@ -0,0 +1,237 @@
+package migrate
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// bookkeepingTables are migration tools tables excluded from schema snapshot.
+var bookkeepingTables = map[string]bool{
+	"schema_migrations": true,
+	"goose_db_version":  true,
+	"gorp_migrations":   true,
+	"flyway_history":    true,
+}
+
+// intDisplayWidth matches integer display width reported only by MySQL 5.x.
+var intDisplayWidth = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|bigint)\(\d+\)`)
+
+// checkSchema compares schema of the migrated database with the SchemaFile, the file
+// is written instead if UpdateSchema is set.
+func (c *Component) checkSchema(ctx context.Context) error {
+	if c.config.Driver != DriverMySQL {
+		return fmt.Errorf("schema snapshot is supported only for MySQL")
+	}
+	dsn := fmt.Sprintf("%s:%s@tcp(%s)/%s", c.config.User, c.config.Password, c.config.SchemaAddr, c.config.Database)
+	db, err := sql.Open("mysql", dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(ctx, time.Second*30)
+	defer cancel()
+	schema, err := DumpMySQLSchema(ctx, db, c.config.Database)
+	if err != nil {
+		return fmt.Errorf("failed to dump %q schema: %v", c.config.Database, err)
+	}
+
+	if c.config.UpdateSchema {
+		if err := os.MkdirAll(filepath.Dir(c.config.SchemaFile), 0777); err != nil {
+			return err
+		}
+		return ioutil.WriteFile(c.config.SchemaFile, []byte(schema), 0666)
+	}
+	golden, err := ioutil.ReadFile(c.config.SchemaFile)
+	if err != nil {
+		return fmt.Errorf("failed to read schema file: %v", err)
+	}
+	if diff := diffLines(string(golden), schema); diff != "" {
+		return fmt.Errorf("%q schema differs from %s (-want +got):\n%s", c.config.Database, c.config.SchemaFile, diff)
+	}
+	return nil
+}
+
+// DumpMySQLSchema returns normalised description of the database tables, columns,
+// indexes and foreign keys. Tables and indexes are sorted by name, columns are kept
+// in the table order.
+func DumpMySQLSchema(ctx context.Context, db *sql.DB, database string) (string, error) {
+	tables := make(map[string][]string)
+
+	columns, err := db.QueryContext(ctx, `
+		SELECT TABLE_NAME, COLUMN_NAME, COLUMN_TYPE, IS_NULLABLE, COLUMN_DEFAULT, EXTRA
+		FROM information_schema.COLUMNS
+		WHERE TABLE_SCHEMA = ?
+		ORDER BY TABLE_NAME, ORDINAL_POSITION`, database)
+	if err != nil {
+		return "", err
+	}
+	err = scanRows(columns, func(scan func(...interface{}) error) error {
+		var (
+			table, name, typ, nullable, extra string
+			def                               sql.NullString
+		)
+		if err := scan(&table, &name, &typ, &nullable, &def, &extra); err != nil {
+			return err
+		}
+		line := fmt.Sprintf("COLUMN %s %s", name, intDisplayWidth.ReplaceAllString(typ, "$1"))
+		if nullable == "NO" {
+			line += " NOT NULL"
+		}
+		if def.Valid {
+			line += " DEFAULT " + def.String
+		}
+		// MySQL 8 marks columns with expression default as DEFAULT_GENERATED.
+		if extra = strings.TrimSpace(strings.Replace(extra, "DEFAULT_GENERATED", "", 1)); extra != "" {
+			line += " " + strings.ToUpper(extra)
+		}
+		tables[table] = append(tables[table], line)
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	indexes, err := db.QueryContext(ctx, `
+		SELECT TABLE_NAME, INDEX_NAME, NON_UNIQUE, GROUP_CONCAT(COLUMN_NAME ORDER BY SEQ_IN_INDEX SEPARATOR ', ')
+		FROM information_schema.STATISTICS
+		WHERE TABLE_SCHEMA = ?
+		GROUP BY TABLE_NAME, INDEX_NAME, NON_UNIQUE
+		ORDER BY TABLE_NAME, INDEX_NAME`, database)
+	if err != nil {
+		return "", err
+	}
+	err = scanRows(indexes, func(scan func(...interface{}) error) error {
+		var (
+			table, name, cols string
+			nonUnique         int
+		)
+		if err := scan(&table, &name, &nonUnique, &cols); err != nil {
+			return err
+		}
+		kind := "INDEX"
+		if nonUnique == 0 {
+			kind = "UNIQUE INDEX"
+		}
+		tables[table] = append(tables[table], fmt.Sprintf("%s %s (%s)", kind, name, cols))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	fks, err := db.QueryContext(ctx, `
+		SELECT k.TABLE_NAME, k.CONSTRAINT_NAME,
+			GROUP_CONCAT(k.COLUMN_NAME ORDER BY k.ORDINAL_POSITION SEPARATOR ', '),
+			k.REFERENCED_TABLE_NAME,
+			GROUP_CONCAT(k.REFERENCED_COLUMN_NAME ORDER BY k.ORDINAL_POSITION SEPARATOR ', '),
+			r.UPDATE_RULE, r.DELETE_RULE
+		FROM information_schema.KEY_COLUMN_USAGE k
+		JOIN information_schema.REFERENTIAL_CONSTRAINTS r
+			ON r.CONSTRAINT_SCHEMA = k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME = k.CONSTRAINT_NAME
+		WHERE k.TABLE_SCHEMA = ? AND k.REFERENCED_TABLE_NAME IS NOT NULL
+		GROUP BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.REFERENCED_TABLE_NAME, r.UPDATE_RULE, r.DELETE_RULE
+		ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME`, database)
+	if err != nil {
+		return "", err
+	}
+	err = scanRows(fks, func(scan func(...interface{}) error) error {
+		var table, name, cols, refTable, refCols, onUpdate, onDelete string
+		if err := scan(&table, &name, &cols, &refTable, &refCols, &onUpdate, &onDelete); err != nil {
+			return err
+		}
+		tables[table] = append(tables[table], fmt.Sprintf("FOREIGN KEY %s (%s) REFERENCES %s (%s) ON UPDATE %s ON DELETE %s",
+			name, cols, refTable, refCols, onUpdate, onDelete))
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+
+	var names []string
+	for name := range tables {
+		if !bookkeepingTables[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	var buff bytes.Buffer
+	for _, name := range names {
+		fmt.Fprintf(&buff, "TABLE %s\n", name)
+		for _, line := range tables[name] {
+			fmt.Fprintf(&buff, "  %s\n", line)
+		}
+	}
+	return buff.String(), nil
+}
+
+func scanRows(rows *sql.Rows, fn func(scan func(...interface{}) error) error) error {
+	defer rows.Close()
+	for rows.Next() {
+		if err := fn(rows.Scan); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// diffLines returns line diff of want and got texts, empty if they are equal.
+func diffLines(want, got string) string {
+	a := strings.Split(strings.TrimRight(want, "\n"), "\n")
+	b := strings.Split(strings.TrimRight(got, "\n"), "\n")
+
+	// lcs[i][j] is the longest common subsequence length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	var (
+		buff    bytes.Buffer
+		changed bool
+		i, j    int
+	)
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			fmt.Fprintf(&buff, "  %s\n", a[i])
+			i++
+			j++
+		case j == len(b) || i < len(a) && lcs[i+1][j] >= lcs[i][j+1]:
+			fmt.Fprintf(&buff, "- %s\n", a[i])
+			changed = true
+			i++
+		default:
+			fmt.Fprintf(&buff, "+ %s\n", b[j])
+			changed = true
+			j++
+		}
+	}
+	if !changed {
+		return ""
+	}
+	return buff.String()
+}
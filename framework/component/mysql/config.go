@@ -3,9 +3,40 @@ package mysql
 import (
 	"bytes"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
 	"github.com/smallinsky/mtf/pkg/docker"
 )
 
+// Variant selects MySQL compatible server image.
+type Variant int
+
+const (
+	VariantMySQL Variant = iota
+	VariantMariaDB
+)
+
+const (
+	defaultPort    = "3306"
+	defaultVersion = "latest"
+	initScriptsDir = "/docker-entrypoint-initdb.d"
+)
+
+// User is a non-root account created on start.
+type User struct {
+	Name     string
+	Password string
+	// Host defaults to "%".
+	Host string
+	// Grants are privileges granted to the user e.g. "SELECT, INSERT ON test_db.*",
+	// defaults to all privileges on all component databases.
+	Grants []string
+}
+
 type MySQLConfig struct {
 	//obsolete
 	Database  string
@@ -13,48 +44,194 @@ type MySQLConfig struct {
 	Password  string
 	Hostname  string
 	Network   string
+	// Port is a host port forwarded to mysql port, defaults to 3306.
+	Port string
+	// Variant and Version select the server image, Version defaults to latest.
+	Variant Variant
+	Version string
+	Users   []User
+	// InitScripts are SQL or shell scripts paths, or dirs with such scripts, executed
+	// in the given order after databases and users are created.
+	InitScripts []string
+	// SQLMode and TimeZone set sql_mode and default time zone e.g. "+00:00".
+	SQLMode  string
+	TimeZone string
+	// Flags are additional server flags e.g. "--max-connections=500".
+	Flags []string
 
 	AttachIfExist bool
+	// Isolation selects how databases are restored between test cases.
+	Isolation Isolation
+}
+
+func (c *MySQLConfig) setDefaults() {
+	if c.Port == "" {
+		c.Port = defaultPort
+	}
+	if c.Version == "" {
+		c.Version = defaultVersion
+	}
+}
+
+func (c MySQLConfig) rootDSN() string {
+	return fmt.Sprintf("root:%s@tcp(localhost:%s)/", c.Password, c.Port)
+}
+
+// databases returns names of all databases created by the component.
+func (c MySQLConfig) databases() []string {
+	var out []string
+	seen := make(map[string]bool)
+	for _, db := range append([]string{c.Database}, c.Databases...) {
+		if db == "" || seen[db] {
+			continue
+		}
+		seen[db] = true
+		out = append(out, db)
+	}
+	return out
 }
 
 func BuildContainerConfig(config MySQLConfig) (*docker.ContainerConfig, error) {
+	config.setDefaults()
+	hostPort, err := strconv.Atoi(config.Port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid mysql port %q: %v", config.Port, err)
+	}
+	mounts, err := initScriptMounts(config.InitScripts)
+	if err != nil {
+		return nil, err
+	}
+
 	var (
-		image   = "library/mysql"
+		image   = "library/mysql:" + config.Version
 		name    = "mysql_mtf"
 		network = "mtf_net"
 	)
+	if config.Variant == VariantMariaDB {
+		image = "library/mariadb:" + config.Version
+	}
 
-	cmd := fmt.Sprintf("mysqladmin -h localhost status --password=%s", config.Password)
+	// TCP connection is accepted only after init scripts are executed.
+	cmd := fmt.Sprintf("%s -h 127.0.0.1 status --password=%s", config.adminCommand(), config.Password)
 
 	return &docker.ContainerConfig{
 		Image:       image,
 		Name:        name,
 		NetworkName: network,
 		PortMap: docker.PortMap{
-			3306: 3306,
+			3306: docker.HostPort(hostPort),
 		},
 		Env: []string{
 			fmt.Sprintf("MYSQL_DATABASE=%s", config.Database),
 			fmt.Sprintf("MYSQL_ROOT_PASSWORD=%s", config.Password),
 		},
+		Mounts: mounts,
+		// Flags passed as Cmd are available as "$@" in the entrypoint script.
 		EntryPoint: []string{
-			`/bin/bash`, `-c`, createDBCommand(config.Databases),
-		},
-		Cmd: []string{
-			"--default-authentication-plugin=mysql_native_password",
+			`/bin/bash`, `-c`, createDBCommand(config), "mysql",
 		},
+		Cmd:           config.flags(),
 		AttachIfExist: config.AttachIfExist,
 		WaitPolicy:    &docker.WaitForCommand{Command: cmd},
 	}, nil
 }
 
-func createDBCommand(databases []string) string {
-	var buff bytes.Buffer
-	_, _ = fmt.Fprint(&buff, `echo '`)
-	for _, db := range databases {
-		_, _ = fmt.Fprintf(&buff, `CREATE DATABASE IF NOT EXISTS %s; `, db)
+// adminCommand returns mysqladmin binary name, MariaDB images since 10.5 provide mariadb-admin.
+func (c MySQLConfig) adminCommand() string {
+	if c.Variant != VariantMariaDB {
+		return "mysqladmin"
+	}
+	var major, minor int
+	if _, err := fmt.Sscanf(c.Version, "%d.%d", &major, &minor); err == nil && (major < 10 || major == 10 && minor < 5) {
+		return "mysqladmin"
+	}
+	return "mariadb-admin"
+}
+
+func (c MySQLConfig) flags() []string {
+	flags := []string{
+		"--character-set-server=utf8mb4",
+		"--collation-server=utf8mb4_unicode_ci",
+	}
+	if c.SQLMode != "" {
+		flags = append(flags, "--sql-mode="+c.SQLMode)
+	}
+	if c.TimeZone != "" {
+		flags = append(flags, "--default-time-zone="+c.TimeZone)
+	}
+	return append(flags, c.Flags...)
+}
+
+// initScriptMounts mounts init scripts into the init dir with a prefix keeping their order.
+func initScriptMounts(paths []string) (docker.Mounts, error) {
+	var files []string
+	for _, p := range paths {
+		abs, err := filepath.Abs(p)
+		if err != nil {
+			return nil, err
+		}
+		stat, err := os.Stat(abs)
+		if err != nil {
+			return nil, err
+		}
+		if !stat.IsDir() {
+			files = append(files, abs)
+			continue
+		}
+		infos, err := ioutil.ReadDir(abs)
+		if err != nil {
+			return nil, err
+		}
+		for _, fi := range infos {
+			if !fi.IsDir() {
+				files = append(files, filepath.Join(abs, fi.Name()))
+			}
+		}
+	}
+
+	var mounts docker.Mounts
+	for i, f := range files {
+		mounts = append(mounts, docker.Mount{
+			Source: f,
+			Target: fmt.Sprintf("%s/%03d_%s", initScriptsDir, i+1, filepath.Base(f)),
+		})
+	}
+	return mounts, nil
+}
+
+func createDBCommand(config MySQLConfig) string {
+	var script bytes.Buffer
+	for _, db := range config.Databases {
+		_, _ = fmt.Fprintf(&script, "CREATE DATABASE IF NOT EXISTS `%s`; ", db)
+	}
+	for _, u := range config.Users {
+		host := u.Host
+		if host == "" {
+			host = "%"
+		}
+		account := fmt.Sprintf("%s@%s", sqlString(u.Name), sqlString(host))
+		_, _ = fmt.Fprintf(&script, "CREATE USER IF NOT EXISTS %s IDENTIFIED BY %s; ", account, sqlString(u.Password))
+		grants := u.Grants
+		if len(grants) == 0 {
+			for _, db := range config.databases() {
+				grants = append(grants, fmt.Sprintf("ALL PRIVILEGES ON `%s`.*", db))
+			}
+		}
+		for _, g := range grants {
+			_, _ = fmt.Fprintf(&script, "GRANT %s TO %s; ", g, account)
+		}
 	}
-	_, _ = fmt.Fprintf(&buff, `' > /docker-entrypoint-initdb.d/init.sql; `)
-	_, _ = fmt.Fprintf(&buff, `/usr/local/bin/docker-entrypoint.sh --character-set-server=utf8mb4 --collation-server=utf8mb4_unicode_ci`)
+
+	var buff bytes.Buffer
+	_, _ = fmt.Fprintf(&buff, `echo %s > %s/000_mtf_init.sql; `, shellQuote(script.String()), initScriptsDir)
+	_, _ = fmt.Fprint(&buff, `exec /usr/local/bin/docker-entrypoint.sh "$@"`)
 	return buff.String()
 }
+
+func sqlString(s string) string {
+	return "'" + strings.Replace(strings.Replace(s, `\`, `\\`, -1), "'", "''", -1) + "'"
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.Replace(s, "'", `'\''`, -1) + "'"
+}
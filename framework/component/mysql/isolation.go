@@ -0,0 +1,264 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+)
+
+// Isolation selects how database state is restored between test cases.
+type Isolation int
+
+const (
+	IsolationNone Isolation = iota
+	// IsolationTruncate truncates all tables except migration bookkeeping tables.
+	IsolationTruncate
+	// IsolationSnapshot restores tables data from a snapshot taken by Snapshot.
+	IsolationSnapshot
+	// IsolationDatabasePerTest drops the database and creates it again from the template
+	// taken by Snapshot, so tables created or altered by the SUT are also restored.
+	// SUT connections using the database are killed before it's dropped, so the SUT has
+	// to reconnect, database/sql pools do it on the next query. Views, triggers and
+	// routines are not copied.
+	IsolationDatabasePerTest
+)
+
+// migrationTables are bookkeeping tables of supported migration tools.
+var migrationTables = map[string]bool{
+	"schema_migrations": true,
+	"goose_db_version":  true,
+	"gorp_migrations":   true,
+	"flyway_history":    true,
+}
+
+// snapshotSuffix is appended to database name to create snapshot database name.
+const snapshotSuffix = "_mtf_snapshot"
+
+// Snapshot takes databases snapshot restored by Reset with IsolationSnapshot and
+// IsolationDatabasePerTest, it is called once migrations are applied and before SUT is started.
+func (c *Component) Snapshot(ctx context.Context) error {
+	if c.config.Isolation != IsolationSnapshot && c.config.Isolation != IsolationDatabasePerTest {
+		return nil
+	}
+	err := c.withConn(ctx, func(conn *sql.Conn) error {
+		for _, name := range c.config.databases() {
+			if err := copyDatabase(ctx, conn, name, name+snapshotSuffix); err != nil {
+				return fmt.Errorf("failed to take %q database snapshot: %v", name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	c.snapshotTaken = true
+	return nil
+}
+
+// Reset restores databases state according to the configured isolation.
+func (c *Component) Reset(ctx context.Context) error {
+	if c.config.Isolation == IsolationNone {
+		return nil
+	}
+	return c.withConn(ctx, func(conn *sql.Conn) error {
+		for _, name := range c.config.databases() {
+			if err := c.reset(ctx, conn, name); err != nil {
+				return fmt.Errorf("failed to reset %q database: %v", name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// withConn calls fn with root connection. Single connection is used, since foreign
+// key checks are disabled per session.
+func (c *Component) withConn(ctx context.Context, fn func(*sql.Conn) error) error {
+	db, err := sql.Open("mysql", c.config.rootDSN())
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to connect to mysql: %v", err)
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "SET FOREIGN_KEY_CHECKS = 0"); err != nil {
+		return err
+	}
+	return fn(conn)
+}
+
+func (c *Component) reset(ctx context.Context, conn *sql.Conn, name string) error {
+	switch c.config.Isolation {
+	case IsolationTruncate:
+		return truncate(ctx, conn, name)
+	case IsolationSnapshot:
+		if !c.snapshotTaken {
+			return fmt.Errorf("snapshot wasn't taken")
+		}
+		return restoreData(ctx, conn, name+snapshotSuffix, name)
+	case IsolationDatabasePerTest:
+		if !c.snapshotTaken {
+			return fmt.Errorf("snapshot wasn't taken")
+		}
+		// Open transactions of the SUT would block dropping the database.
+		if err := killConnections(ctx, conn, name); err != nil {
+			return err
+		}
+		return copyDatabase(ctx, conn, name+snapshotSuffix, name)
+	default:
+		return fmt.Errorf("unsupported isolation %v", c.config.Isolation)
+	}
+}
+
+// killConnections kills other connections using the database.
+func killConnections(ctx context.Context, conn *sql.Conn, name string) error {
+	rows, err := conn.QueryContext(ctx,
+		"SELECT id FROM information_schema.processlist WHERE db = ? AND id != CONNECTION_ID()", name)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+	var ids []int64
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return err
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, id := range ids {
+		// Connection could be closed in the meantime.
+		conn.ExecContext(ctx, fmt.Sprintf("KILL %d", id))
+	}
+	return nil
+}
+
+func truncate(ctx context.Context, conn *sql.Conn, name string) error {
+	tables, err := listTables(ctx, conn, name)
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		if migrationTables[t] {
+			continue
+		}
+		if _, err := conn.ExecContext(ctx, "TRUNCATE TABLE "+quote(name, t)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyDatabase recreates dst database with tables and data of the src database.
+// Tables are created from SHOW CREATE TABLE, unlike CREATE TABLE LIKE it keeps
+// foreign keys, which reference tables in the dst database since it's selected.
+func copyDatabase(ctx context.Context, conn *sql.Conn, src, dst string) error {
+	tables, err := listTables(ctx, conn, src)
+	if err != nil {
+		return err
+	}
+	stmts := []string{
+		"DROP DATABASE IF EXISTS " + quote(dst),
+		"CREATE DATABASE " + quote(dst),
+		"USE " + quote(dst),
+	}
+	for _, t := range tables {
+		var name, create string
+		if err := conn.QueryRowContext(ctx, "SHOW CREATE TABLE "+quote(src, t)).Scan(&name, &create); err != nil {
+			return fmt.Errorf("failed to get %q table definition: %v", t, err)
+		}
+		insert, err := copyRows(ctx, conn, src, dst, t)
+		if err != nil {
+			return err
+		}
+		stmts = append(stmts, create, insert)
+	}
+	return execAll(ctx, conn, stmts)
+}
+
+// restoreData replaces dst tables data with data of the src database tables.
+func restoreData(ctx context.Context, conn *sql.Conn, src, dst string) error {
+	tables, err := listTables(ctx, conn, src)
+	if err != nil {
+		return err
+	}
+	var stmts []string
+	for _, t := range tables {
+		insert, err := copyRows(ctx, conn, src, dst, t)
+		if err != nil {
+			return err
+		}
+		stmts = append(stmts, "TRUNCATE TABLE "+quote(dst, t), insert)
+	}
+	return execAll(ctx, conn, stmts)
+}
+
+// copyRows returns statement copying table rows from src to dst database. Generated
+// columns are skipped, since their values can't be inserted.
+func copyRows(ctx context.Context, conn *sql.Conn, src, dst, table string) (string, error) {
+	rows, err := conn.QueryContext(ctx, `
+		SELECT column_name FROM information_schema.columns
+		WHERE table_schema = ? AND table_name = ? AND extra NOT IN ('VIRTUAL GENERATED', 'STORED GENERATED')
+		ORDER BY ordinal_position`, src, table)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+	var columns []string
+	for rows.Next() {
+		var c string
+		if err := rows.Scan(&c); err != nil {
+			return "", err
+		}
+		columns = append(columns, quote(c))
+	}
+	if err := rows.Err(); err != nil {
+		return "", err
+	}
+	list := strings.Join(columns, ", ")
+	return fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", quote(dst, table), list, list, quote(src, table)), nil
+}
+
+func listTables(ctx context.Context, conn *sql.Conn, name string) ([]string, error) {
+	rows, err := conn.QueryContext(ctx,
+		"SELECT table_name FROM information_schema.tables WHERE table_schema = ? AND table_type = 'BASE TABLE'", name)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var tables []string
+	for rows.Next() {
+		var t string
+		if err := rows.Scan(&t); err != nil {
+			return nil, err
+		}
+		tables = append(tables, t)
+	}
+	return tables, rows.Err()
+}
+
+func execAll(ctx context.Context, conn *sql.Conn, stmts []string) error {
+	for _, s := range stmts {
+		if _, err := conn.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("%q failed: %v", s, err)
+		}
+	}
+	return nil
+}
+
+// quote returns dot separated quoted identifiers.
+func quote(names ...string) string {
+	quoted := make([]string, len(names))
+	for i, n := range names {
+		quoted[i] = "`" + strings.Replace(n, "`", "``", -1) + "`"
+	}
+	return strings.Join(quoted, ".")
+}
@@ -9,9 +9,12 @@ import (
 type Component struct {
 	config    MySQLConfig
 	Container docker.Container
+	// snapshotTaken is set once the databases snapshot is taken by Snapshot.
+	snapshotTaken bool
 }
 
 func New(cli *docker.Docker, config MySQLConfig) (*Component, error) {
+	config.setDefaults()
 	containerConf, err := BuildContainerConfig(config)
 	if err != nil {
 		return nil, err
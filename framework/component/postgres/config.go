@@ -0,0 +1,109 @@
+package postgres
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/smallinsky/mtf/pkg/docker"
+)
+
+const (
+	defaultUser     = "postgres"
+	defaultDatabase = "postgres"
+	defaultVersion  = "12"
+	defaultPort     = 5432
+)
+
+type PostgresConfig struct {
+	// User is a superuser name, defaults to postgres.
+	User string
+	// Password is the superuser password, if empty connections are accepted without password.
+	Password string
+	// Databases are created on start, the first one is the default user database.
+	Databases []string
+	// Extensions are created in each database, e.g. "uuid-ossp" or "pgcrypto".
+	Extensions []string
+	// HostPort is a host port forwarded to postgres port, defaults to 5432.
+	HostPort int
+	// Version is postgres image tag, defaults to 12.
+	Version string
+}
+
+func (c *PostgresConfig) setDefaults() {
+	if c.User == "" {
+		c.User = defaultUser
+	}
+	if len(c.Databases) == 0 {
+		c.Databases = []string{defaultDatabase}
+	}
+	if c.HostPort == 0 {
+		c.HostPort = defaultPort
+	}
+	if c.Version == "" {
+		c.Version = defaultVersion
+	}
+}
+
+func BuildContainerConfig(config PostgresConfig) (*docker.ContainerConfig, error) {
+	config.setDefaults()
+
+	var (
+		image   = "library/postgres:" + config.Version
+		name    = "postgres_mtf"
+		network = "mtf_net"
+	)
+
+	// pg_isready connects over TCP, which is enabled only after init scripts are executed.
+	cmd := fmt.Sprintf("pg_isready -h localhost -U %s -d %s", config.User, config.Databases[0])
+
+	env := []string{
+		"POSTGRES_USER=" + config.User,
+		"POSTGRES_PASSWORD=" + config.Password,
+		"POSTGRES_DB=" + config.Databases[0],
+	}
+	if config.Password == "" {
+		// Postgres image refuses to initialize the database without password otherwise.
+		env = append(env, "POSTGRES_HOST_AUTH_METHOD=trust")
+	}
+
+	return &docker.ContainerConfig{
+		Image:       image,
+		Name:        name,
+		NetworkName: network,
+		PortMap: docker.PortMap{
+			defaultPort: docker.HostPort(config.HostPort),
+		},
+		Env: env,
+		EntryPoint: []string{
+			`/bin/bash`, `-c`, initCommand(config),
+		},
+		WaitPolicy: &docker.WaitForCommand{Command: cmd},
+	}, nil
+}
+
+// initCommand writes init script creating additional databases and extensions
+// and starts the postgres entrypoint.
+func initCommand(config PostgresConfig) string {
+	var script bytes.Buffer
+	for _, db := range config.Databases[1:] {
+		fmt.Fprintf(&script, "CREATE DATABASE %s;\n", quoteIdent(db))
+	}
+	if len(config.Extensions) != 0 {
+		for _, db := range config.Databases {
+			fmt.Fprintf(&script, "\\connect %s\n", quoteIdent(db))
+			for _, ext := range config.Extensions {
+				fmt.Fprintf(&script, "CREATE EXTENSION IF NOT EXISTS %s;\n", quoteIdent(ext))
+			}
+		}
+	}
+
+	var buff bytes.Buffer
+	fmt.Fprintf(&buff, `echo '%s' > /docker-entrypoint-initdb.d/init.sql; `, strings.Replace(script.String(), `'`, `'\''`, -1))
+	fmt.Fprint(&buff, `exec docker-entrypoint.sh postgres`)
+	return buff.String()
+}
+
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
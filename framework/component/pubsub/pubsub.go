@@ -2,16 +2,30 @@ package pubsub
 
 import (
 	"context"
+	"fmt"
 	"os"
 	"time"
 
 	"cloud.google.com/go/pubsub"
+	"google.golang.org/api/iterator"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
 	"github.com/smallinsky/mtf/pkg/docker"
 )
 
+const (
+	ackDeadline = time.Second * 10
+	// drainTimeout is a time window used to purge subscription backlog when
+	// emulator doesn't support seek operation.
+	drainTimeout = time.Millisecond * 300
+)
+
 type Component struct {
 	Config    Config
 	Container docker.Container
+
+	client *pubsub.Client
 }
 
 func New(cli *docker.Docker, config Config) (*Component, error) {
@@ -44,36 +58,17 @@ func (c *Component) Start(ctx context.Context) error {
 	if err != nil {
 		return err
 	}
+	c.client = conn
 
 	for _, ts := range c.Config.TopicSubscriptions {
-		topic := conn.Topic(ts.Topic)
-		exists, err := topic.Exists(ctx)
-		if err != nil {
-			return err
-		}
-		if exists {
-			continue
-		}
-		topic, err = conn.CreateTopic(ctx, ts.Topic)
+		topic, err := c.ensureTopic(ctx, ts.Topic)
 		if err != nil {
 			return err
 		}
-
 		for _, sn := range ts.Subscriptions {
-			sub := conn.Subscription(sn)
-			exists, err := sub.Exists(ctx)
-			if err != nil {
+			if err := c.ensureSubscription(ctx, topic, sn); err != nil {
 				return err
 			}
-			if !exists {
-				_, err = conn.CreateSubscription(ctx, sn, pubsub.SubscriptionConfig{
-					Topic:       topic,
-					AckDeadline: time.Second * 10,
-				})
-				if err != nil {
-					return err
-				}
-			}
 		}
 	}
 
@@ -83,6 +78,85 @@ func (c *Component) Start(ctx context.Context) error {
 	return nil
 }
 
+// ensureTopic returns topic with given name, the topic is created if doesn't exist.
+func (c *Component) ensureTopic(ctx context.Context, name string) (*pubsub.Topic, error) {
+	topic := c.client.Topic(name)
+	exists, err := topic.Exists(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		return topic, nil
+	}
+	return c.client.CreateTopic(ctx, name)
+}
+
+// ensureSubscription creates missing subscription or verifies that existing one
+// is attached to the expected topic.
+func (c *Component) ensureSubscription(ctx context.Context, topic *pubsub.Topic, name string) error {
+	sub := c.client.Subscription(name)
+	exists, err := sub.Exists(ctx)
+	if err != nil {
+		return err
+	}
+	if !exists {
+		_, err = c.client.CreateSubscription(ctx, name, pubsub.SubscriptionConfig{
+			Topic:       topic,
+			AckDeadline: ackDeadline,
+		})
+		return err
+	}
+
+	cfg, err := sub.Config(ctx)
+	if err != nil {
+		return err
+	}
+	if cfg.Topic == nil || cfg.Topic.String() != topic.String() {
+		return fmt.Errorf("subscription %q is attached to %v topic, expected %q", name, cfg.Topic, topic.ID())
+	}
+	return nil
+}
+
+// Reset removes backlog from all subscriptions attached to configured topics, so messages
+// published but not consumed during one test case won't be delivered in the next one.
+func (c *Component) Reset(ctx context.Context) error {
+	if c.client == nil {
+		return nil
+	}
+	for _, ts := range c.Config.TopicSubscriptions {
+		it := c.client.Topic(ts.Topic).Subscriptions(ctx)
+		for {
+			sub, err := it.Next()
+			if err == iterator.Done {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if err := purgeSubscription(ctx, sub); err != nil {
+				return fmt.Errorf("failed to reset %q subscription: %v", sub.ID(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func purgeSubscription(ctx context.Context, sub *pubsub.Subscription) error {
+	err := sub.SeekToTime(ctx, time.Now())
+	if status.Code(err) != codes.Unimplemented {
+		return err
+	}
+	// Fallback for emulator versions without seek support.
+	ctx, cancel := context.WithTimeout(ctx, drainTimeout)
+	defer cancel()
+	return sub.Receive(ctx, func(ctx context.Context, msg *pubsub.Message) {
+		msg.Ack()
+	})
+}
+
 func (c *Component) Stop(ctx context.Context) error {
+	if c.client != nil {
+		c.client.Close()
+	}
 	return c.Container.Stop(ctx)
 }
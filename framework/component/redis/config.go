@@ -2,31 +2,59 @@ package redis
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/smallinsky/mtf/pkg/docker"
 )
 
+const defaultPort = "6379"
+
 type RedisConfig struct {
 	Password string
-	Port     string
+	// Port is a host port forwarded to redis port, defaults to 6379.
+	Port string
+	// FixtureFile is a path to JSON fixture loaded before each test, see Fixture.
+	FixtureFile string
+}
+
+func (c *RedisConfig) setDefaults() {
+	if c.Port == "" {
+		c.Port = defaultPort
+	}
+}
+
+// Addr returns host address of the redis server.
+func (c RedisConfig) Addr() string {
+	return "localhost:" + c.Port
 }
 
 func BuildContainerConfig(config RedisConfig) (*docker.ContainerConfig, error) {
+	config.setDefaults()
+	hostPort, err := strconv.Atoi(config.Port)
+	if err != nil {
+		return nil, fmt.Errorf("invalid redis port %q: %v", config.Port, err)
+	}
+
 	var (
 		image   = "bitnami/redis:4.0"
 		name    = "redis_mtf"
 		network = "mtf_net"
 	)
 
+	env := []string{
+		fmt.Sprintf("REDIS_PASSWORD=%s", config.Password),
+	}
+	if config.Password == "" {
+		env = append(env, "ALLOW_EMPTY_PASSWORD=yes")
+	}
+
 	return &docker.ContainerConfig{
 		Name:  name,
 		Image: image,
 		PortMap: docker.PortMap{
-			6379: 6379,
+			6379: docker.HostPort(hostPort),
 		},
 		NetworkName: network,
-		Env: []string{
-			fmt.Sprintf("REDIS_PASSWORD=%s", config.Password),
-		},
+		Env:         env,
 	}, nil
 }
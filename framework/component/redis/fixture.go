@@ -0,0 +1,94 @@
+package redis
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"time"
+
+	"github.com/go-redis/redis"
+)
+
+// Fixture describes redis keys loaded before each test, e.g:
+//
+//	{
+//	  "strings": {"user:1": "bob"},
+//	  "hashes":  {"session:1": {"user": "bob"}},
+//	  "lists":   {"queue": ["a", "b"]},
+//	  "sets":    {"tags": ["x", "y"]},
+//	  "ttl":     {"session:1": "30s"}
+//	}
+type Fixture struct {
+	Strings map[string]string            `json:"strings"`
+	Hashes  map[string]map[string]string `json:"hashes"`
+	Lists   map[string][]string          `json:"lists"`
+	Sets    map[string][]string          `json:"sets"`
+	// TTL values are parsed with time.ParseDuration.
+	TTL map[string]string `json:"ttl"`
+}
+
+// ReadFixture reads and validates fixture file.
+func ReadFixture(path string) (*Fixture, error) {
+	buff, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var f Fixture
+	if err := json.Unmarshal(buff, &f); err != nil {
+		return nil, fmt.Errorf("failed to decode %q fixture: %v", path, err)
+	}
+	for key, ttl := range f.TTL {
+		if _, err := time.ParseDuration(ttl); err != nil {
+			return nil, fmt.Errorf("invalid %q key ttl: %v", key, err)
+		}
+	}
+	return &f, nil
+}
+
+// Load writes fixture keys in a single transaction. Empty hashes, lists and sets are
+// skipped, since redis doesn't store empty collections.
+func (f *Fixture) Load(client *redis.Client) error {
+	pipe := client.TxPipeline()
+	for key, value := range f.Strings {
+		pipe.Set(key, value, 0)
+	}
+	for key, fields := range f.Hashes {
+		if len(fields) == 0 {
+			continue
+		}
+		values := make(map[string]interface{}, len(fields))
+		for k, v := range fields {
+			values[k] = v
+		}
+		pipe.HMSet(key, values)
+	}
+	for key, values := range f.Lists {
+		if len(values) == 0 {
+			continue
+		}
+		pipe.RPush(key, toArgs(values)...)
+	}
+	for key, values := range f.Sets {
+		if len(values) == 0 {
+			continue
+		}
+		pipe.SAdd(key, toArgs(values)...)
+	}
+	for key, ttl := range f.TTL {
+		d, err := time.ParseDuration(ttl)
+		if err != nil {
+			return fmt.Errorf("invalid %q key ttl: %v", key, err)
+		}
+		pipe.Expire(key, d)
+	}
+	_, err := pipe.Exec()
+	return err
+}
+
+func toArgs(values []string) []interface{} {
+	args := make([]interface{}, len(values))
+	for i, v := range values {
+		args[i] = v
+	}
+	return args
+}
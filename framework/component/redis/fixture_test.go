@@ -0,0 +1,90 @@
+package redis
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis"
+)
+
+func writeFixture(t *testing.T, dir, content string) string {
+	path := filepath.Join(dir, "fixture.json")
+	if err := ioutil.WriteFile(path, []byte(content), 0666); err != nil {
+		t.Fatalf("failed to write fixture: %v", err)
+	}
+	return path
+}
+
+func TestReadFixtureInvalidTTL(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mtf_redis")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := writeFixture(t, dir, `{"strings": {"a": "b"}, "ttl": {"a": "soon"}}`)
+	if _, err := ReadFixture(path); err == nil {
+		t.Fatalf("expected invalid ttl error")
+	}
+
+	f := &Fixture{TTL: map[string]string{"a": "soon"}}
+	if err := f.Load(redis.NewClient(&redis.Options{Addr: "localhost:0"})); err == nil || !strings.Contains(err.Error(), "ttl") {
+		t.Fatalf("expected invalid ttl error, got: %v", err)
+	}
+}
+
+func TestFixtureLoad(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start redis: %v", err)
+	}
+	defer s.Close()
+	client := redis.NewClient(&redis.Options{Addr: s.Addr()})
+	defer client.Close()
+	dir, err := ioutil.TempDir("", "mtf_redis")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	f, err := ReadFixture(writeFixture(t, dir, `{
+		"strings": {"user:1": "bob"},
+		"hashes":  {"session:1": {"user": "bob"}, "empty_hash": {}},
+		"lists":   {"queue": ["a", "b"], "empty_list": []},
+		"sets":    {"tags": ["x"], "empty_set": []},
+		"ttl":     {"session:1": "30s"}
+	}`))
+	if err != nil {
+		t.Fatalf("failed to read fixture: %v", err)
+	}
+	if err := f.Load(client); err != nil {
+		t.Fatalf("failed to load fixture: %v", err)
+	}
+
+	if got, err := client.Get("user:1").Result(); err != nil || got != "bob" {
+		t.Fatalf("string mismatch, got: %q err: %v", got, err)
+	}
+	if got, err := client.HGetAll("session:1").Result(); err != nil || !reflect.DeepEqual(got, map[string]string{"user": "bob"}) {
+		t.Fatalf("hash mismatch, got: %v err: %v", got, err)
+	}
+	if got, err := client.LRange("queue", 0, -1).Result(); err != nil || !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Fatalf("list mismatch, got: %v err: %v", got, err)
+	}
+	if got, err := client.SMembers("tags").Result(); err != nil || !reflect.DeepEqual(got, []string{"x"}) {
+		t.Fatalf("set mismatch, got: %v err: %v", got, err)
+	}
+	if got := s.TTL("session:1"); got != time.Second*30 {
+		t.Fatalf("ttl mismatch, got: %v", got)
+	}
+	for _, key := range []string{"empty_hash", "empty_list", "empty_set"} {
+		if s.Exists(key) {
+			t.Fatalf("empty %q key was created", key)
+		}
+	}
+}
@@ -2,21 +2,36 @@ package redis
 
 import (
 	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-redis/redis"
 
 	"github.com/smallinsky/mtf/pkg/docker"
 )
 
+const readyTimeout = time.Second * 30
+
 type Component struct {
 	config    RedisConfig
 	Container docker.Container
+	fixture   *Fixture
 }
 
 func New(cli *docker.Docker, config RedisConfig) (*Component, error) {
+	config.setDefaults()
 	containerConf, err := BuildContainerConfig(config)
 	if err != nil {
 		return nil, err
 	}
 
+	var fixture *Fixture
+	if config.FixtureFile != "" {
+		if fixture, err = ReadFixture(config.FixtureFile); err != nil {
+			return nil, err
+		}
+	}
+
 	container, err := cli.NewContainer(*containerConf)
 	if err != nil {
 		return nil, err
@@ -25,13 +40,63 @@ func New(cli *docker.Docker, config RedisConfig) (*Component, error) {
 	return &Component{
 		config:    config,
 		Container: container,
+		fixture:   fixture,
 	}, nil
 }
 
+// Start starts redis container and waits until it answers authenticated PING.
 func (c *Component) Start(ctx context.Context) error {
-	return c.Container.Start(ctx)
+	if err := c.Container.Start(ctx); err != nil {
+		return err
+	}
+	return c.waitForReady(ctx)
+}
+
+func (c *Component) waitForReady(ctx context.Context) error {
+	client := c.client()
+	defer client.Close()
+
+	deadline := time.Now().Add(readyTimeout)
+	for {
+		err := client.Ping().Err()
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("redis is not ready after %v: %v", readyTimeout, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(time.Millisecond * 100):
+		}
+	}
 }
 
 func (c *Component) Stop(ctx context.Context) error {
 	return c.Container.Stop(ctx)
 }
+
+// Reset removes all keys and loads the fixture, it is no-op when fixture file wasn't set.
+func (c *Component) Reset(ctx context.Context) error {
+	if c.fixture == nil {
+		return nil
+	}
+	client := c.client()
+	defer client.Close()
+
+	if err := client.FlushAll().Err(); err != nil {
+		return fmt.Errorf("failed to flush redis: %v", err)
+	}
+	if err := c.fixture.Load(client); err != nil {
+		return fmt.Errorf("failed to load redis fixture: %v", err)
+	}
+	return nil
+}
+
+func (c *Component) client() *redis.Client {
+	return redis.NewClient(&redis.Options{
+		Addr:     c.config.Addr(),
+		Password: c.config.Password,
+	})
+}
@@ -14,14 +14,24 @@ import (
 // SutConfig is a configuration required to buld and run sut
 // into docker container.
 type SutConfig struct {
+	// Name distinguishes SUT containers when more than one SUT is used.
+	Name string
 	// Path to dir binary source files that will be
 	// build and executed into docker containers as a SUT.
 	Path string
+	// Image is a prebuilt SUT image used instead of Path.
+	Image string
+	// Dockerfile is a path to Dockerfile used instead of Path.
+	Dockerfile string
+	// BuildContext is the Dockerfile build context dir, defaults to the Dockerfile dir.
+	BuildContext string
 	// Env is list of environment variables will be passed to SUT.
 	Env []string
 	// ExposedPorts is a list of port that will be exposed and forwarded
 	// to docker host.
 	ExposedPorts []int
+	// Mounts are host directories mounted into SUT container.
+	Mounts []Mount
 	// RuntimeTypeCommand allows to distinguish between service and simple command binary.
 	RuntimeTypeCommand bool
 
@@ -29,6 +39,21 @@ type SutConfig struct {
 	binaryName  string
 }
 
+// Mount describes host directory mounted into SUT container, the host dir
+// is created if it doesn't exist.
+type Mount struct {
+	HostDir      string
+	ContainerDir string
+}
+
+// containerName returns sut_mtf or sut_<name>_mtf for named SUT.
+func (c SutConfig) containerName() string {
+	if c.Name == "" {
+		return "sut_mtf"
+	}
+	return fmt.Sprintf("sut_%s_mtf", c.Name)
+}
+
 func (c *SutConfig) Build() error {
 	stat, err := os.Stat(c.Path)
 	if err != nil {
@@ -63,7 +88,7 @@ func BuildContainerConfig(config SutConfig) (*docker.ContainerConfig, error) {
 
 	var (
 		image   = "smallinsky/run_sut"
-		name    = "sut_mtf"
+		name    = config.containerName()
 		network = "mtf_net"
 	)
 
@@ -78,22 +103,48 @@ func BuildContainerConfig(config SutConfig) (*docker.ContainerConfig, error) {
 		Source: config.absoltePath,
 		Target: "/component",
 	}
+	mounts := docker.Mounts{
+		certMount,
+		binaryMount,
+	}
+	userMounts, err := hostMounts(config.Mounts)
+	if err != nil {
+		return nil, err
+	}
+	mounts = append(mounts, userMounts...)
+
 	var waitPolicy docker.WaitPolicy
 	if !config.RuntimeTypeCommand {
 		waitPolicy = &docker.WaitForProcess{Process: config.binaryName}
 	}
 
 	return &docker.ContainerConfig{
-		Name:  name,
-		Image: image,
-		Env:   env,
-		Mounts: docker.Mounts{
-			certMount,
-			binaryMount,
-		},
+		Name:        name,
+		Image:       image,
+		Env:         env,
+		Mounts:      mounts,
 		PortMap:     ports,
 		NetworkName: network,
 		Privileged:  true,
 		WaitPolicy:  waitPolicy,
 	}, nil
 }
+
+// hostMounts resolves mounts host dirs and creates them if they don't exist.
+func hostMounts(mounts []Mount) (docker.Mounts, error) {
+	var out docker.Mounts
+	for _, m := range mounts {
+		dir, err := filepath.Abs(m.HostDir)
+		if err != nil {
+			return nil, err
+		}
+		if err := os.MkdirAll(dir, 0777); err != nil {
+			return nil, fmt.Errorf("failed to create %q mount dir: %v", dir, err)
+		}
+		out = append(out, docker.Mount{
+			Source: dir,
+			Target: m.ContainerDir,
+		})
+	}
+	return out, nil
+}
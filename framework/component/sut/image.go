@@ -0,0 +1,190 @@
+package sut
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/smallinsky/mtf/framework/core"
+	"github.com/smallinsky/mtf/pkg/cert"
+	"github.com/smallinsky/mtf/pkg/docker"
+)
+
+const (
+	// netReadyFile is created by the network container once traffic redirection is set up.
+	netReadyFile = "/tmp/mtf_ready"
+	// certTarget is a path of MTF cert in SUT image, Go and other clients reading all
+	// certs from /etc/ssl/certs trust it along with the image system certs.
+	certTarget        = "/etc/ssl/certs/mtf_server.crt"
+	credentialsTarget = "/tmp/mtf/gcloud/application_default_credentials.json"
+)
+
+// forwardHTTPScript redirects HTTP and HTTPS traffic to MTF HTTP ports like run_sut does.
+var forwardHTTPScript = strings.Join([]string{
+	`DOCKER_HOST=$(nslookup $DOCKER_HOST_ADDR 2> /dev/null | grep Address | cut -d":" -f2 | tr -d " ")`,
+	`iptables -t nat -A OUTPUT -p tcp --dport 80 -j DNAT --to-destination ${DOCKER_HOST}:8080`,
+	`iptables -t nat -A OUTPUT -p tcp --dport 443 -j DNAT --to-destination ${DOCKER_HOST}:8443`,
+	`touch ` + netReadyFile,
+	`while :; do sleep 3600; done`,
+}, "\n")
+
+// ImageComponent runs SUT from a prebuilt image or an image built from Dockerfile.
+// SUT container joins network stack of run_sut container that sets up traffic
+// redirection with iptables, so the image doesn't need to provide any tools.
+type ImageComponent struct {
+	cli    *docker.Docker
+	config SutConfig
+	image  string
+
+	net docker.Container
+	app docker.Container
+}
+
+func NewImage(cli *docker.Docker, config SutConfig) (*ImageComponent, error) {
+	c := &ImageComponent{
+		cli:    cli,
+		config: config,
+		image:  config.Image,
+	}
+	ctx := context.Background()
+	if config.Dockerfile == "" {
+		return c, cli.PullImageIfNotExist(ctx, c.image)
+	}
+
+	c.image = config.containerName() + ":mtf"
+	if !core.Settings.BuildBinary && cli.ImageExists(ctx, c.image) {
+		return c, nil
+	}
+	dockerfile, err := filepath.Abs(config.Dockerfile)
+	if err != nil {
+		return nil, err
+	}
+	buildContext := filepath.Dir(dockerfile)
+	if config.BuildContext != "" {
+		if buildContext, err = filepath.Abs(config.BuildContext); err != nil {
+			return nil, err
+		}
+	}
+	rel, err := filepath.Rel(buildContext, dockerfile)
+	if err != nil || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return nil, fmt.Errorf("dockerfile %s is outside of %s build context", config.Dockerfile, buildContext)
+	}
+	err = cli.BuildImageFromConfig(ctx, docker.BuildImageConfig{
+		Path:       buildContext,
+		Tag:        c.image,
+		Dockerfile: filepath.ToSlash(rel),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to build sut image from %s: %v", config.Dockerfile, err)
+	}
+	return c, nil
+}
+
+// Start creates network and SUT containers, so command runtime SUT is started
+// from a clean container for each test case.
+func (c *ImageComponent) Start(ctx context.Context) error {
+	netConfig, appConfig, err := c.containerConfigs()
+	if err != nil {
+		return err
+	}
+	if c.net, err = c.cli.NewContainer(*netConfig); err != nil {
+		return err
+	}
+	if err := c.net.Start(ctx); err != nil {
+		return fmt.Errorf("failed to set up sut network: %v", err)
+	}
+	if c.app, err = c.cli.NewContainer(*appConfig); err != nil {
+		return err
+	}
+	if err := c.app.Start(ctx); err != nil {
+		return err
+	}
+	if c.config.RuntimeTypeCommand {
+		return nil
+	}
+	return waitForPorts(ctx, c.config.ExposedPorts, c.exited)
+}
+
+func (c *ImageComponent) exited() (bool, string) {
+	state, err := c.app.GetState(context.Background())
+	if err != nil || state.Running {
+		return false, ""
+	}
+	r, err := c.app.Logs(context.Background())
+	if err != nil {
+		return true, err.Error()
+	}
+	buff, _ := ioutil.ReadAll(r)
+	return true, string(buff)
+}
+
+func (c *ImageComponent) containerConfigs() (*docker.ContainerConfig, *docker.ContainerConfig, error) {
+	ports := make(map[docker.ContainerPort]docker.HostPort)
+	for _, v := range c.config.ExposedPorts {
+		ports[docker.ContainerPort(v)] = docker.HostPort(v)
+	}
+	name := c.config.containerName()
+
+	// Network container is named after the SUT, so other containers reach the SUT by its name.
+	netConfig := &docker.ContainerConfig{
+		Name:        name,
+		Image:       "smallinsky/run_sut",
+		EntryPoint:  []string{"/bin/sh", "-c", forwardHTTPScript},
+		PortMap:     ports,
+		NetworkName: "mtf_net",
+		Privileged:  true,
+		WaitPolicy:  &docker.WaitForCommand{Command: "test -f " + netReadyFile},
+	}
+
+	credentials, err := writeGcloudCredentials()
+	if err != nil {
+		return nil, nil, err
+	}
+	mounts := docker.Mounts{
+		{Source: credentials, Target: credentialsTarget},
+	}
+	if _, err := os.Stat(cert.ServerCertFile); err == nil {
+		mounts = append(mounts, docker.Mount{Source: cert.ServerCertFile, Target: certTarget})
+	}
+	userMounts, err := hostMounts(c.config.Mounts)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	appConfig := &docker.ContainerConfig{
+		Name:        name + "_app",
+		Image:       c.image,
+		Env:         append(c.config.Env, "GOOGLE_APPLICATION_CREDENTIALS="+credentialsTarget),
+		Mounts:      append(mounts, userMounts...),
+		NetworkMode: "container:" + name,
+	}
+	return netConfig, appConfig, nil
+}
+
+func (c *ImageComponent) Stop(ctx context.Context) error {
+	for _, container := range []docker.Container{c.app, c.net} {
+		if container == nil {
+			continue
+		}
+		if err := container.Stop(ctx); err != nil {
+			return err
+		}
+	}
+	c.app, c.net = nil, nil
+	return nil
+}
+
+func (c *ImageComponent) Logs(ctx context.Context) (io.Reader, error) {
+	if c.app == nil {
+		return strings.NewReader(""), nil
+	}
+	return c.app.Logs(ctx)
+}
+
+func (c *ImageComponent) Name() string {
+	return c.config.containerName()
+}
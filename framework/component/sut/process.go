@@ -0,0 +1,293 @@
+package sut
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/smallinsky/mtf/framework/core"
+	"github.com/smallinsky/mtf/pkg/build"
+	"github.com/smallinsky/mtf/pkg/cert"
+)
+
+const (
+	// portsTimeout is how long Start waits for the SUT to listen on its ports.
+	portsTimeout = time.Second * 30
+	// stopTimeout is how long Stop waits for the SUT process to exit after interrupt.
+	stopTimeout = time.Second * 5
+)
+
+// gcloudCredentials are fake application default credentials, so google clients
+// don't look for real ones.
+const gcloudCredentials = `{
+  "client_id": "test_client_id",
+  "client_secret": "test_client_secret",
+  "refresh_token": "test_refresh_token",
+  "type": "authorized_user",
+  "auth_uri": "myauth.google.com",
+  "token_uri": "mytoken.google.com"
+}`
+
+// systemCertFiles are CA bundle locations of Linux distributions checked by Go crypto/x509.
+var systemCertFiles = []string{
+	"/etc/ssl/certs/ca-certificates.crt",                // Debian/Ubuntu/Gentoo etc.
+	"/etc/pki/tls/certs/ca-bundle.crt",                  // Fedora/RHEL 6
+	"/etc/ssl/ca-bundle.pem",                            // OpenSUSE
+	"/etc/pki/tls/cacert.pem",                           // OpenELEC
+	"/etc/pki/ca-trust/extracted/pem/tls-ca-bundle.pem", // CentOS/RHEL 7
+	"/etc/ssl/cert.pem",                                 // Alpine Linux
+}
+
+// ProcessComponent runs SUT binary directly on the host. HTTP traffic is intercepted
+// with HTTP_PROXY and HTTPS_PROXY instead of iptables and MTF cert is trusted with
+// SSL_CERT_FILE pointing to a bundle of the system CA certs and MTF cert, so real TLS
+// hosts are still trusted. Go ignores SSL_CERT_FILE on macOS, there MTF cert needs to be
+// added to the system keychain. Mounts are ignored, since the SUT can access host dirs directly.
+type ProcessComponent struct {
+	config SutConfig
+	binary string
+
+	mtx   sync.Mutex
+	cmd   *exec.Cmd
+	proxy *proxy
+	done  chan struct{}
+	logs  logBuffer
+}
+
+func NewProcess(config SutConfig) (*ProcessComponent, error) {
+	if err := config.Build(); err != nil {
+		return nil, err
+	}
+	if core.Settings.BuildBinary {
+		if err := build.BuildForHost(config.absoltePath); err != nil {
+			return nil, fmt.Errorf("failed to build sut binary from %s, err %v", config.absoltePath, err)
+		}
+	}
+	return &ProcessComponent{
+		config: config,
+		binary: filepath.Join(config.absoltePath, config.binaryName),
+	}, nil
+}
+
+func (c *ProcessComponent) Start(ctx context.Context) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+
+	p, err := startProxy()
+	if err != nil {
+		return fmt.Errorf("failed to start http proxy: %v", err)
+	}
+	env, err := c.env(p)
+	if err != nil {
+		p.Close()
+		return err
+	}
+
+	c.logs.Reset()
+	cmd := exec.Command(c.binary)
+	cmd.Dir = c.config.absoltePath
+	cmd.Env = env
+	cmd.Stdout = &c.logs
+	cmd.Stderr = &c.logs
+	if err := cmd.Start(); err != nil {
+		p.Close()
+		return fmt.Errorf("failed to run %s: %v", c.binary, err)
+	}
+	c.cmd = cmd
+	c.proxy = p
+	c.done = make(chan struct{})
+	go func(done chan struct{}) {
+		cmd.Wait()
+		close(done)
+	}(c.done)
+
+	if c.config.RuntimeTypeCommand {
+		return nil
+	}
+	if err := waitForPorts(ctx, c.config.ExposedPorts, c.exited); err != nil {
+		// Process isn't left running and the proxy listening when Start fails.
+		c.stop()
+		return err
+	}
+	return nil
+}
+
+func (c *ProcessComponent) exited() (bool, string) {
+	select {
+	case <-c.done:
+		return true, c.logs.String()
+	default:
+		return false, ""
+	}
+}
+
+func (c *ProcessComponent) env(p *proxy) ([]string, error) {
+	credentials, err := writeGcloudCredentials()
+	if err != nil {
+		return nil, err
+	}
+
+	env := append(os.Environ(),
+		"HTTP_PROXY="+p.URL(),
+		"HTTPS_PROXY="+p.URL(),
+		"http_proxy="+p.URL(),
+		"https_proxy="+p.URL(),
+		"NO_PROXY=localhost,127.0.0.1",
+		"no_proxy=localhost,127.0.0.1",
+		"GOOGLE_APPLICATION_CREDENTIALS="+credentials,
+	)
+	if _, err := os.Stat(cert.ServerCertFile); err == nil {
+		bundle, err := writeCABundle(cert.ServerCertFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to write ca bundle: %v", err)
+		}
+		env = append(env, "SSL_CERT_FILE="+bundle)
+	}
+	return append(env, c.config.Env...), nil
+}
+
+// writeCABundle writes the system CA certs followed by the cert file on the host and
+// returns its path. System certs are read from SSL_CERT_FILE when it is set.
+func writeCABundle(certFile string) (string, error) {
+	mtfCert, err := ioutil.ReadFile(certFile)
+	if err != nil {
+		return "", err
+	}
+	files := systemCertFiles
+	if f := os.Getenv("SSL_CERT_FILE"); f != "" {
+		files = []string{f}
+	}
+	var bundle []byte
+	for _, f := range files {
+		if b, err := ioutil.ReadFile(f); err == nil {
+			bundle = append(b, '\n')
+			break
+		}
+	}
+	bundle = append(bundle, mtfCert...)
+
+	dir := filepath.Join(os.TempDir(), "mtf")
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "ca_bundle.pem")
+	if err := ioutil.WriteFile(path, bundle, 0666); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// writeGcloudCredentials writes fake credentials file on the host and returns its path.
+func writeGcloudCredentials() (string, error) {
+	dir := filepath.Join(os.TempDir(), "mtf", "gcloud")
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return "", err
+	}
+	path := filepath.Join(dir, "application_default_credentials.json")
+	if err := ioutil.WriteFile(path, []byte(gcloudCredentials), 0666); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// waitForPorts waits until the SUT accepts connections on all ports forwarded to
+// the host, exited reports if the SUT terminated and its output.
+func waitForPorts(ctx context.Context, ports []int, exited func() (bool, string)) error {
+	ctx, cancel := context.WithTimeout(ctx, portsTimeout)
+	defer cancel()
+	for _, port := range ports {
+		addr := net.JoinHostPort("localhost", strconv.Itoa(port))
+		for {
+			conn, err := net.DialTimeout("tcp", addr, time.Second)
+			if err == nil {
+				conn.Close()
+				break
+			}
+			if ok, logs := exited(); ok {
+				return fmt.Errorf("sut exited:\n%s", logs)
+			}
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("sut doesn't listen on %s: %v", addr, ctx.Err())
+			case <-time.After(time.Millisecond * 100):
+			}
+		}
+	}
+	return nil
+}
+
+// Stop interrupts the SUT process and kills it if it doesn't exit in stopTimeout.
+func (c *ProcessComponent) Stop(ctx context.Context) error {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return c.stop()
+}
+
+func (c *ProcessComponent) stop() error {
+	if c.cmd == nil {
+		return nil
+	}
+	defer func() {
+		c.proxy.Close()
+		c.cmd = nil
+	}()
+
+	select {
+	case <-c.done:
+		return nil
+	default:
+	}
+	if err := c.cmd.Process.Signal(os.Interrupt); err != nil {
+		return c.cmd.Process.Kill()
+	}
+	select {
+	case <-c.done:
+	case <-time.After(stopTimeout):
+		if err := c.cmd.Process.Kill(); err != nil {
+			return err
+		}
+		<-c.done
+	}
+	return nil
+}
+
+func (c *ProcessComponent) Logs(ctx context.Context) (io.Reader, error) {
+	return bytes.NewBufferString(c.logs.String()), nil
+}
+
+func (c *ProcessComponent) Name() string {
+	return c.config.containerName()
+}
+
+// logBuffer collects SUT process stdout and stderr.
+type logBuffer struct {
+	mtx  sync.Mutex
+	buff bytes.Buffer
+}
+
+func (b *logBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buff.Write(p)
+}
+
+func (b *logBuffer) String() string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return b.buff.String()
+}
+
+func (b *logBuffer) Reset() {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.buff.Reset()
+}
@@ -0,0 +1,67 @@
+package sut
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestProcessStartFailureCleanup(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	port := l.Addr().(*net.TCPAddr).Port
+	l.Close()
+
+	// Shell exits immediately without input, so it never listens on the port.
+	c := &ProcessComponent{
+		config: SutConfig{ExposedPorts: []int{port}},
+		binary: "/bin/sh",
+	}
+	if err := c.Start(context.Background()); err == nil {
+		t.Fatalf("expected start error")
+	}
+	if c.cmd != nil {
+		t.Fatalf("process wasn't stopped")
+	}
+	if _, err := http.Get(c.proxy.URL()); err == nil {
+		t.Fatalf("proxy is still listening")
+	}
+}
+
+func TestWriteCABundle(t *testing.T) {
+	dir, err := ioutil.TempDir("", "mtf_sut")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	systemFile := filepath.Join(dir, "system.pem")
+	certFile := filepath.Join(dir, "mtf.pem")
+	if err := ioutil.WriteFile(systemFile, []byte("system"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	if err := ioutil.WriteFile(certFile, []byte("mtf"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+
+	prevFiles, prevEnv := systemCertFiles, os.Getenv("SSL_CERT_FILE")
+	defer func() {
+		systemCertFiles = prevFiles
+		os.Setenv("SSL_CERT_FILE", prevEnv)
+	}()
+	os.Unsetenv("SSL_CERT_FILE")
+	systemCertFiles = []string{filepath.Join(dir, "missing.pem"), systemFile}
+
+	path, err := writeCABundle(certFile)
+	if err != nil {
+		t.Fatalf("failed to write bundle: %v", err)
+	}
+	if b, err := ioutil.ReadFile(path); err != nil || string(b) != "system\nmtf" {
+		t.Fatalf("bundle mismatch, got: %q err: %v", b, err)
+	}
+}
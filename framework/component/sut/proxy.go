@@ -0,0 +1,99 @@
+package sut
+
+import (
+	"io"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"time"
+)
+
+var (
+	// httpAddr and httpsAddr are MTF HTTP port servers addresses.
+	httpAddr  = "localhost:8080"
+	httpsAddr = "localhost:8443"
+)
+
+// proxy redirects SUT process HTTP traffic to MTF HTTP port servers, like iptables
+// rules do in the SUT container. Only requests to default HTTP and HTTPS ports are
+// redirected, other requests are forwarded to their destinations.
+type proxy struct {
+	listener net.Listener
+	server   *http.Server
+}
+
+func startProxy() (*proxy, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	p := &proxy{
+		listener: l,
+	}
+	forward := &httputil.ReverseProxy{
+		Director: func(r *http.Request) {
+			if r.URL.Port() == "" || r.URL.Port() == "80" {
+				// Host header still contains the original host.
+				r.URL.Host = httpAddr
+			}
+		},
+	}
+	p.server = &http.Server{
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Method == http.MethodConnect {
+				p.tunnel(w, r)
+				return
+			}
+			forward.ServeHTTP(w, r)
+		}),
+	}
+	go p.server.Serve(l)
+	return p, nil
+}
+
+// URL returns proxy URL set as HTTP_PROXY and HTTPS_PROXY.
+func (p *proxy) URL() string {
+	return "http://" + p.listener.Addr().String()
+}
+
+func (p *proxy) Close() error {
+	return p.server.Close()
+}
+
+// tunnel handles HTTPS CONNECT request, TLS connection is established by the SUT
+// with MTF HTTPS server trusted thanks to MTF CA cert.
+func (p *proxy) tunnel(w http.ResponseWriter, r *http.Request) {
+	addr := r.Host
+	if _, port, err := net.SplitHostPort(r.Host); err == nil && port == "443" {
+		addr = httpsAddr
+	}
+	dst, err := net.DialTimeout("tcp", addr, time.Second*5)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		dst.Close()
+		http.Error(w, "hijacking not supported", http.StatusInternalServerError)
+		return
+	}
+	src, _, err := hijacker.Hijack()
+	if err != nil {
+		dst.Close()
+		return
+	}
+	if _, err := io.WriteString(src, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		src.Close()
+		dst.Close()
+		return
+	}
+	go pipe(dst, src)
+	go pipe(src, dst)
+}
+
+func pipe(dst, src net.Conn) {
+	defer dst.Close()
+	defer src.Close()
+	io.Copy(dst, src)
+}
@@ -0,0 +1,77 @@
+package sut
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+// startTestProxy starts the proxy redirecting traffic to the test servers and
+// returns client sending requests through it.
+func startTestProxy(t *testing.T, httpSrv, httpsSrv *httptest.Server) (*http.Client, func()) {
+	prevHTTP, prevHTTPS := httpAddr, httpsAddr
+	httpAddr = strings.TrimPrefix(httpSrv.URL, "http://")
+	httpsAddr = strings.TrimPrefix(httpsSrv.URL, "https://")
+
+	p, err := startProxy()
+	if err != nil {
+		t.Fatalf("failed to start proxy: %v", err)
+	}
+	proxyURL, err := url.Parse(p.URL())
+	if err != nil {
+		t.Fatalf("failed to parse proxy url: %v", err)
+	}
+	client := &http.Client{
+		Transport: &http.Transport{
+			Proxy:           http.ProxyURL(proxyURL),
+			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+		},
+	}
+	return client, func() {
+		p.Close()
+		httpAddr, httpsAddr = prevHTTP, prevHTTPS
+	}
+}
+
+func hostHandler(name string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(name + " " + r.Host + r.URL.Path))
+	}
+}
+
+func TestProxy(t *testing.T) {
+	httpSrv := httptest.NewServer(hostHandler("http"))
+	defer httpSrv.Close()
+	httpsSrv := httptest.NewTLSServer(hostHandler("https"))
+	defer httpsSrv.Close()
+	client, cleanup := startTestProxy(t, httpSrv, httpsSrv)
+	defer cleanup()
+
+	for _, tc := range []struct {
+		url  string
+		want string
+	}{
+		{"http://example.com/path", "http example.com/path"},
+		{"http://example.com:80/path", "http example.com:80/path"},
+		{"https://example.com/path", "https example.com/path"},
+	} {
+		t.Run(tc.url, func(t *testing.T) {
+			resp, err := client.Get(tc.url)
+			if err != nil {
+				t.Fatalf("failed to send request: %v", err)
+			}
+			defer resp.Body.Close()
+			body, err := ioutil.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("failed to read response: %v", err)
+			}
+			if got := string(body); got != tc.want {
+				t.Fatalf("response mismatch, got: %q want: %q", got, tc.want)
+			}
+		})
+	}
+}
@@ -11,6 +11,7 @@ import (
 )
 
 var contextMap = map[string]*TestContext{}
+var current *TestContext
 var mtx sync.Mutex
 
 func Get(t *testing.T) *TestContext {
@@ -19,14 +20,23 @@ func Get(t *testing.T) *TestContext {
 	return contextMap[getTestPrefix(t)]
 }
 
+// Current returns context of currently executed test case or nil if there is none.
+// It allows to attribute events from non test goroutines e.g. port handlers.
+func Current() *TestContext {
+	mtx.Lock()
+	defer mtx.Unlock()
+	return current
+}
+
 func getTestPrefix(t *testing.T) string {
 	return strings.Split(t.Name(), "/")[0]
 }
 
 type TestContext struct {
-	file *os.File
-	mtx  sync.Mutex
-	log  *log.Logger
+	file     *os.File
+	mtx      sync.Mutex
+	log      *log.Logger
+	failures []string
 }
 
 const (
@@ -61,14 +71,34 @@ func CreateTestContext(t *testing.T) {
 	mtx.Lock()
 	defer mtx.Unlock()
 	contextMap[getTestPrefix(t)] = c
+	current = c
 }
 
 func RemoveTextContext(t *testing.T) {
 	mtx.Lock()
 	defer mtx.Unlock()
+	if c := contextMap[getTestPrefix(t)]; c == current {
+		current = nil
+	}
 	delete(contextMap, getTestPrefix(t))
 }
 
+// Fail records test failure reported outside of test goroutine.
+func (c *TestContext) Fail(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	c.failures = append(c.failures, msg)
+	c.log.Printf("[FAIL] %s", msg)
+}
+
+// Failures returns failures recorded by Fail.
+func (c *TestContext) Failures() []string {
+	c.mtx.Lock()
+	defer c.mtx.Unlock()
+	return append([]string(nil), c.failures...)
+}
+
 func (c *TestContext) LogReceive(name string, i interface{}) {
 	payload, _ := dump(i)
 	c.mtx.Lock()
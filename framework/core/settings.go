@@ -8,6 +8,7 @@ type ArgSettings struct {
 	BuildBinary             bool
 	StopComponentsAfterExit bool
 	Wait                    bool
+	UpdateSchema            bool
 }
 
 var Settings = ArgSettings{}
@@ -21,4 +22,7 @@ func init() {
 
 	flag.BoolVar(&Settings.Wait, "wait", false,
 		"Don't kill container after test execution")
+
+	flag.BoolVar(&Settings.UpdateSchema, "update_schema", false,
+		"Write migrated database schema snapshots to the migration SchemaFile instead of comparing them")
 }
@@ -16,6 +16,7 @@ import (
 	"github.com/smallinsky/mtf/framework/component/ftp"
 	"github.com/smallinsky/mtf/framework/component/migrate"
 	"github.com/smallinsky/mtf/framework/component/mysql"
+	"github.com/smallinsky/mtf/framework/component/postgres"
 	"github.com/smallinsky/mtf/framework/component/pubsub"
 	"github.com/smallinsky/mtf/framework/component/redis"
 	"github.com/smallinsky/mtf/framework/component/sut"
@@ -33,7 +34,7 @@ type TestEnvironment struct {
 	settings Settings
 
 	components []component.Component
-	SUT        component.Component
+	suts       []*sutInstance
 	network    *docker.Network
 
 	M *testing.M
@@ -68,7 +69,7 @@ func (env *TestEnvironment) Run() {
 	}
 
 	if core.Settings.Wait {
-		sig := make(chan os.Signal)
+		sig := make(chan os.Signal, 1)
 		signal.Notify(sig, os.Interrupt)
 		fmt.Println("waiting for signal...")
 		<-sig
@@ -97,7 +98,15 @@ func (env *TestEnvironment) Start(ctx context.Context) error {
 		log.Fatalf("[ERROR] Failed to prepare env: %v", err)
 	}
 
+	// Snapshots are taken once databases are migrated, before service SUTs are started.
+	snapshotTaken := false
 	for _, container := range env.components {
+		if !snapshotTaken && env.isSUT(container) {
+			if err := env.Snapshot(ctx); err != nil {
+				log.Fatalf("[ERROR] Failed to take snapshot: %v", err)
+			}
+			snapshotTaken = true
+		}
 		start := time.Now()
 		fmt.Printf("  - Starting %s ", getComponentName(container))
 		err := container.Start(ctx)
@@ -106,6 +115,11 @@ func (env *TestEnvironment) Start(ctx context.Context) error {
 		}
 		fmt.Printf("-  %v\n", time.Now().Sub(start))
 	}
+	if !snapshotTaken {
+		if err := env.Snapshot(ctx); err != nil {
+			log.Fatalf("[ERROR] Failed to take snapshot: %v", err)
+		}
+	}
 	fmt.Printf("=== TEST RUN DONE - %v\n\n", time.Now().Sub(start))
 
 	return nil
@@ -132,22 +146,119 @@ func (env *TestEnvironment) Stop(ctx context.Context) error {
 	return nil
 }
 
+// StartSutInCommandMode starts command runtime SUTs before the test case, SUTs with
+// ManualStart are started by the test with StartSUT.
 func (env *TestEnvironment) StartSutInCommandMode() error {
-	err := env.SUT.Start(context.Background())
-	if err != nil {
-		return err
+	for _, s := range env.suts {
+		if !s.settings.RuntimeType.command() || s.settings.ManualStart {
+			continue
+		}
+		if err := env.StartSUT(s.settings.Name); err != nil {
+			return err
+		}
 	}
 	return nil
 }
 
+// StopSutInCommandMode writes logs of running command runtime SUTs and stops them.
 func (env *TestEnvironment) StopSutInCommandMode(tcName string) error {
 	ctx := context.Background()
-	if err := env.WriteComponentLogs(ctx, env.SUT, fmt.Sprintf("%s-", tcName)); err != nil {
-		log.Printf("[ERROR] Failed to write sut logs: %v", err)
+	for _, s := range env.suts {
+		if !s.settings.RuntimeType.command() || !s.running {
+			continue
+		}
+		if err := env.WriteComponentLogs(ctx, s.comp, fmt.Sprintf("%s-", tcName)); err != nil {
+			log.Printf("[ERROR] Failed to write sut logs: %v", err)
+		}
+		if err := env.StopSUT(s.settings.Name); err != nil {
+			return err
+		}
 	}
-	if err := env.SUT.Stop(ctx); err != nil {
+	return nil
+}
+
+// StartSUT starts the SUT with the given name, empty name selects unnamed SUT.
+func (env *TestEnvironment) StartSUT(name string) error {
+	s, err := env.sut(name)
+	if err != nil {
+		return err
+	}
+	if s.running {
+		return fmt.Errorf("sut %q is already running", name)
+	}
+	if err := s.comp.Start(context.Background()); err != nil {
+		return err
+	}
+	s.running = true
+	return nil
+}
+
+// StopSUT stops the SUT with the given name, empty name selects unnamed SUT.
+func (env *TestEnvironment) StopSUT(name string) error {
+	s, err := env.sut(name)
+	if err != nil {
 		return err
 	}
+	if !s.running {
+		return nil
+	}
+	s.running = false
+	return s.comp.Stop(context.Background())
+}
+
+func (env *TestEnvironment) sut(name string) (*sutInstance, error) {
+	for _, s := range env.suts {
+		if s.settings.Name == name {
+			return s, nil
+		}
+	}
+	return nil, fmt.Errorf("sut %q not found", name)
+}
+
+// StartSUT starts the named SUT of the running test environment.
+func StartSUT(name string) error {
+	return testenv.StartSUT(name)
+}
+
+// StopSUT stops the named SUT of the running test environment.
+func StopSUT(name string) error {
+	return testenv.StopSUT(name)
+}
+
+// Reset restores initial state of all resettable components.
+// Snapshot takes state snapshots of components restoring it with Reset.
+func (env *TestEnvironment) Snapshot(ctx context.Context) error {
+	for _, c := range env.components {
+		v, ok := c.(component.Snapshotter)
+		if !ok {
+			continue
+		}
+		if err := v.Snapshot(ctx); err != nil {
+			return fmt.Errorf("failed to take %s snapshot: %v", getComponentName(c), err)
+		}
+	}
+	return nil
+}
+
+func (env *TestEnvironment) isSUT(c component.Component) bool {
+	for _, s := range env.suts {
+		if s.comp == c {
+			return true
+		}
+	}
+	return false
+}
+
+func (env *TestEnvironment) Reset(ctx context.Context) error {
+	for _, c := range env.components {
+		v, ok := c.(component.Resettable)
+		if !ok {
+			continue
+		}
+		if err := v.Reset(ctx); err != nil {
+			return fmt.Errorf("failed to reset %s: %v", getComponentName(c), err)
+		}
+	}
 	return nil
 }
 
@@ -191,8 +302,9 @@ func (env *TestEnvironment) Prepare(cli *docker.Docker) error {
 
 	if conf.Redis != nil {
 		comp, err := redis.New(cli, redis.RedisConfig{
-			Password: conf.Redis.Password,
-			Port:     conf.Redis.Port,
+			Password:    conf.Redis.Password,
+			Port:        conf.Redis.Port,
+			FixtureFile: conf.Redis.FixtureFile,
 		})
 		if err != nil {
 			return err
@@ -218,11 +330,51 @@ func (env *TestEnvironment) Prepare(cli *docker.Docker) error {
 	}
 
 	if cfg := conf.MySQL; cfg != nil {
-		comp, err := mysql.New(cli, mysql.MySQLConfig{
+		variant, err := mysqlVariant(cfg.Variant)
+		if err != nil {
+			return err
+		}
+		isolation, err := mysqlIsolation(cfg.Isolation)
+		if err != nil {
+			return err
+		}
+		mysqlConfig := mysql.MySQLConfig{
 			Database:      cfg.DatabaseName,
 			Databases:     cfg.Databases,
 			Password:      cfg.Password,
+			Port:          cfg.Port,
+			Variant:       variant,
+			Version:       cfg.Version,
+			InitScripts:   cfg.InitScripts,
+			SQLMode:       cfg.SQLMode,
+			TimeZone:      cfg.TimeZone,
+			Flags:         cfg.Flags,
 			AttachIfExist: true,
+			Isolation:     isolation,
+		}
+		for _, u := range cfg.Users {
+			mysqlConfig.Users = append(mysqlConfig.Users, mysql.User{
+				Name:     u.Name,
+				Password: u.Password,
+				Host:     u.Host,
+				Grants:   u.Grants,
+			})
+		}
+		comp, err := mysql.New(cli, mysqlConfig)
+		if err != nil {
+			return err
+		}
+		components = append(components, comp)
+	}
+
+	if cfg := conf.Postgres; cfg != nil {
+		comp, err := postgres.New(cli, postgres.PostgresConfig{
+			User:       cfg.User,
+			Password:   cfg.Password,
+			Databases:  cfg.Databases,
+			Extensions: cfg.Extensions,
+			HostPort:   cfg.Port,
+			Version:    cfg.Version,
 		})
 		if err != nil {
 			return err
@@ -231,13 +383,26 @@ func (env *TestEnvironment) Prepare(cli *docker.Docker) error {
 	}
 
 	if len(conf.Migration) != 0 {
+		// Schema is dumped through the MySQL component port forwarded to the host.
+		schemaAddr := "localhost:3306"
+		if conf.MySQL != nil && conf.MySQL.Port != "" {
+			schemaAddr = "localhost:" + conf.MySQL.Port
+		}
 		for _, mig := range conf.Migration {
 			comp, err := migrate.New(cli, migrate.MigrateConfig{
-				Path:     mig.Dir,
-				Password: mig.Password,
-				Port:     mig.Port,
-				Hostname: "mysql_mtf",
-				Database: mig.DBName,
+				Path:         mig.Dir,
+				Driver:       migrate.Driver(mig.Driver),
+				User:         mig.User,
+				Password:     mig.Password,
+				Port:         mig.Port,
+				Database:     mig.DBName,
+				Tool:         migrate.Tool(mig.Tool),
+				Version:      mig.Version,
+				TestRollback: mig.TestRollback,
+				SchemaFile:   mig.SchemaFile,
+				SchemaAddr:   schemaAddr,
+				UpdateSchema: core.Settings.UpdateSchema,
+				Timeout:      mig.Timeout,
 			})
 			if err != nil {
 				return err
@@ -266,27 +431,47 @@ func (env *TestEnvironment) Prepare(cli *docker.Docker) error {
 	}
 
 	if cfg := conf.FTP; cfg != nil {
-		comp, err := ftp.New(cli, ftp.FTPConfig{})
+		protocol, err := ftpProtocol(cfg.Protocol)
+		if err != nil {
+			return err
+		}
+		ftpConfig := ftp.FTPConfig{
+			User:           cfg.User,
+			Password:       cfg.Pass,
+			HostPort:       cfg.Port,
+			PassivePortMin: cfg.PassivePortMin,
+			PassivePortMax: cfg.PassivePortMax,
+			Protocol:       protocol,
+		}
+		for _, u := range cfg.Users {
+			ftpConfig.Users = append(ftpConfig.Users, ftp.User{
+				User:     u.User,
+				Password: u.Pass,
+			})
+		}
+		comp, err := ftp.New(cli, ftpConfig)
 		if err != nil {
 			return err
 		}
 		components = append(components, comp)
 	}
 
-	if conf.SUT != nil {
-		conf.SUT.Envs = append(conf.SUT.Envs, "PUBSUB_EMULATOR_HOST="+GetDockerHostAddr(8085))
-		comp, err := sut.New(cli, sut.SutConfig{
-			Path:               conf.SUT.Dir,
-			Env:                conf.SUT.Envs,
-			ExposedPorts:       conf.SUT.Ports,
-			RuntimeTypeCommand: conf.SUT.RuntimeType == RuntimeTypeCommand,
-		})
+	if err := checkSUTNames(conf.SUT); err != nil {
+		return err
+	}
+	for _, cfg := range conf.SUT {
+		comp, err := newSUT(cli, cfg)
 		if err != nil {
 			return err
 		}
-
-		env.SUT = comp
-		if conf.SUT.RuntimeType != RuntimeTypeCommand {
+		// Service SUTs are started with other components and run until the environment is stopped.
+		command := cfg.RuntimeType.command()
+		env.suts = append(env.suts, &sutInstance{
+			settings: cfg,
+			comp:     comp,
+			running:  !command,
+		})
+		if !command {
 			components = append(components, comp)
 		}
 	}
@@ -295,11 +480,112 @@ func (env *TestEnvironment) Prepare(cli *docker.Docker) error {
 	return nil
 }
 
+func checkSUTNames(suts []*SutSettings) error {
+	names := make(map[string]bool)
+	for _, cfg := range suts {
+		if names[cfg.Name] {
+			return fmt.Errorf("duplicated %q sut name", cfg.Name)
+		}
+		names[cfg.Name] = true
+	}
+	return nil
+}
+
+// sutInstance is a system under test component started according to its runtime type.
+type sutInstance struct {
+	settings *SutSettings
+	comp     component.Component
+	running  bool
+}
+
+func newSUT(cli *docker.Docker, cfg *SutSettings) (component.Component, error) {
+	// Envs are copied, so the settings slice backing array isn't modified.
+	envs := append([]string{}, cfg.Envs...)
+	if cfg.RuntimeType.process() {
+		envs = append(envs, "PUBSUB_EMULATOR_HOST=localhost:8085")
+	} else {
+		envs = append(envs, "PUBSUB_EMULATOR_HOST="+GetDockerHostAddr(8085))
+	}
+	sutConfig := sut.SutConfig{
+		Name:               cfg.Name,
+		Path:               cfg.Dir,
+		Image:              cfg.Image,
+		Dockerfile:         cfg.Dockerfile,
+		BuildContext:       cfg.BuildContext,
+		Env:                envs,
+		ExposedPorts:       cfg.Ports,
+		RuntimeTypeCommand: cfg.RuntimeType.command(),
+	}
+	for _, m := range cfg.Mounts {
+		sutConfig.Mounts = append(sutConfig.Mounts, sut.Mount{
+			HostDir:      m.HostDir,
+			ContainerDir: m.ContainerDir,
+		})
+	}
+	switch {
+	case cfg.Image != "" || cfg.Dockerfile != "":
+		if cfg.RuntimeType.process() {
+			return nil, fmt.Errorf("sut %q image can't be run as a process", cfg.Name)
+		}
+		return sut.NewImage(cli, sutConfig)
+	case cfg.RuntimeType.process():
+		return sut.NewProcess(sutConfig)
+	default:
+		return sut.New(cli, sutConfig)
+	}
+}
+
+func mysqlVariant(v MySQLVariant) (mysql.Variant, error) {
+	switch v {
+	case MySQLVariantMySQL:
+		return mysql.VariantMySQL, nil
+	case MySQLVariantMariaDB:
+		return mysql.VariantMariaDB, nil
+	}
+	return 0, fmt.Errorf("unsupported mysql variant %d", v)
+}
+
+func mysqlIsolation(i MySQLIsolation) (mysql.Isolation, error) {
+	switch i {
+	case MySQLIsolationNone:
+		return mysql.IsolationNone, nil
+	case MySQLIsolationTruncate:
+		return mysql.IsolationTruncate, nil
+	case MySQLIsolationSnapshot:
+		return mysql.IsolationSnapshot, nil
+	case MySQLIsolationDatabasePerTest:
+		return mysql.IsolationDatabasePerTest, nil
+	}
+	return 0, fmt.Errorf("unsupported mysql isolation %d", i)
+}
+
+func ftpProtocol(p FTPProtocol) (ftp.Protocol, error) {
+	switch p {
+	case FTPProtocolFTP:
+		return ftp.ProtocolFTP, nil
+	case FTPProtocolFTPS:
+		return ftp.ProtocolFTPS, nil
+	case FTPProtocolSFTP:
+		return ftp.ProtocolSFTP, nil
+	}
+	return 0, fmt.Errorf("unsupported ftp protocol %d", p)
+}
+
 func (env *TestEnvironment) genCerts() error {
-	if env.settings.TLS == nil {
+	ftps := env.settings.FTP != nil && env.settings.FTP.Protocol == FTPProtocolFTPS
+	if env.settings.TLS == nil && !ftps {
 		return nil
 	}
-	_, err := cert.GenCert(env.settings.TLS.Hosts)
+	var hosts []string
+	if env.settings.TLS != nil {
+		hosts = append(hosts, env.settings.TLS.Hosts...)
+	}
+	if ftps {
+		// FTPS server uses MTF cert even if TLS wasn't requested explicitly, SUT
+		// connects to it by the container name.
+		hosts = append(hosts, "ftp_mtf")
+	}
+	_, err := cert.GenCert(hosts)
 	return err
 }
 
@@ -0,0 +1,139 @@
+package framework
+
+import (
+	"context"
+	"testing"
+
+	"github.com/smallinsky/mtf/framework/component/ftp"
+	"github.com/smallinsky/mtf/framework/component/mysql"
+)
+
+type fakeSUT struct {
+	running bool
+	starts  int
+}
+
+func (s *fakeSUT) Start(context.Context) error {
+	s.running = true
+	s.starts++
+	return nil
+}
+
+func (s *fakeSUT) Stop(context.Context) error {
+	s.running = false
+	return nil
+}
+
+func newTestSUT(settings SutSettings) (*sutInstance, *fakeSUT) {
+	comp := &fakeSUT{}
+	return &sutInstance{
+		settings: &settings,
+		comp:     comp,
+		running:  !settings.RuntimeType.command(),
+	}, comp
+}
+
+func TestSUTByName(t *testing.T) {
+	unnamed, _ := newTestSUT(SutSettings{RuntimeType: RuntimeTypeCommand})
+	worker, workerComp := newTestSUT(SutSettings{Name: "worker", RuntimeType: RuntimeTypeCommand})
+	env := &TestEnvironment{suts: []*sutInstance{unnamed, worker}}
+
+	if s, err := env.sut(""); err != nil || s != unnamed {
+		t.Fatalf("unnamed sut lookup failed, got: %v err: %v", s, err)
+	}
+	if _, err := env.sut("missing"); err == nil {
+		t.Fatalf("expected missing sut error")
+	}
+
+	if err := env.StartSUT("worker"); err != nil {
+		t.Fatalf("failed to start sut: %v", err)
+	}
+	if err := env.StartSUT("worker"); err == nil {
+		t.Fatalf("expected already running sut error")
+	}
+	if err := env.StopSUT("worker"); err != nil {
+		t.Fatalf("failed to stop sut: %v", err)
+	}
+	if workerComp.running || workerComp.starts != 1 {
+		t.Fatalf("unexpected worker sut state: %+v", workerComp)
+	}
+}
+
+func TestCheckSUTNames(t *testing.T) {
+	if err := checkSUTNames([]*SutSettings{{}, {Name: "worker"}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := checkSUTNames([]*SutSettings{{Name: "worker"}, {Name: "api"}, {Name: "worker"}}); err == nil {
+		t.Fatalf("expected duplicated sut name error")
+	}
+}
+
+func TestStartSutInCommandMode(t *testing.T) {
+	service, serviceComp := newTestSUT(SutSettings{Name: "api"})
+	command, commandComp := newTestSUT(SutSettings{Name: "job", RuntimeType: RuntimeTypeCommand})
+	manual, manualComp := newTestSUT(SutSettings{Name: "manual", RuntimeType: RuntimeTypeCommand, ManualStart: true})
+	env := &TestEnvironment{suts: []*sutInstance{service, command, manual}}
+
+	if err := env.StartSutInCommandMode(); err != nil {
+		t.Fatalf("failed to start command suts: %v", err)
+	}
+	if serviceComp.starts != 0 || commandComp.starts != 1 || manualComp.starts != 0 {
+		t.Fatalf("unexpected starts, service: %d command: %d manual: %d", serviceComp.starts, commandComp.starts, manualComp.starts)
+	}
+
+	if err := env.StartSUT("manual"); err != nil {
+		t.Fatalf("failed to start manual sut: %v", err)
+	}
+	if err := env.StopSutInCommandMode("TestCase"); err != nil {
+		t.Fatalf("failed to stop command suts: %v", err)
+	}
+	if commandComp.running || manualComp.running {
+		t.Fatalf("command suts are still running")
+	}
+	if !service.running {
+		t.Fatalf("service sut was stopped")
+	}
+}
+
+func TestFTPProtocol(t *testing.T) {
+	for in, want := range map[FTPProtocol]ftp.Protocol{
+		FTPProtocolFTP:  ftp.ProtocolFTP,
+		FTPProtocolFTPS: ftp.ProtocolFTPS,
+		FTPProtocolSFTP: ftp.ProtocolSFTP,
+	} {
+		if got, err := ftpProtocol(in); err != nil || got != want {
+			t.Fatalf("protocol %d mapping mismatch, got: %v err: %v want: %v", in, got, err, want)
+		}
+	}
+	if _, err := ftpProtocol(FTPProtocol(42)); err == nil {
+		t.Fatalf("expected unsupported protocol error")
+	}
+}
+
+func TestMySQLMapping(t *testing.T) {
+	for in, want := range map[MySQLVariant]mysql.Variant{
+		MySQLVariantMySQL:   mysql.VariantMySQL,
+		MySQLVariantMariaDB: mysql.VariantMariaDB,
+	} {
+		if got, err := mysqlVariant(in); err != nil || got != want {
+			t.Fatalf("variant %d mapping mismatch, got: %v err: %v want: %v", in, got, err, want)
+		}
+	}
+	if _, err := mysqlVariant(MySQLVariant(42)); err == nil {
+		t.Fatalf("expected unsupported variant error")
+	}
+
+	for in, want := range map[MySQLIsolation]mysql.Isolation{
+		MySQLIsolationNone:            mysql.IsolationNone,
+		MySQLIsolationTruncate:        mysql.IsolationTruncate,
+		MySQLIsolationSnapshot:        mysql.IsolationSnapshot,
+		MySQLIsolationDatabasePerTest: mysql.IsolationDatabasePerTest,
+	} {
+		if got, err := mysqlIsolation(in); err != nil || got != want {
+			t.Fatalf("isolation %d mapping mismatch, got: %v err: %v want: %v", in, got, err, want)
+		}
+	}
+	if _, err := mysqlIsolation(MySQLIsolation(42)); err == nil {
+		t.Fatalf("expected unsupported isolation error")
+	}
+}
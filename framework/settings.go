@@ -1,8 +1,13 @@
 package framework
 
+import (
+	"time"
+)
+
 type Settings struct {
 	MySQL     *MysqlSettings
-	SUT       *SutSettings
+	Postgres  *PostgresSettings
+	SUT       []*SutSettings
 	PubSub    *PubSubSettings
 	Redis     *RedisSettings
 	FTP       *FTPSettings
@@ -11,12 +16,51 @@ type Settings struct {
 }
 
 type MigrationSettings struct {
+	// Driver selects database component the migrations are applied to, defaults to MySQL.
+	Driver MigrationDriver
+	// User defaults to root for MySQL and to postgres for Postgres.
+	User     string
 	Password string
 	Port     string
 	DBName   string
 	Dir      string
+	// Tool selects migration tool, defaults to golang-migrate.
+	Tool MigrationTool
+	// Version is a schema version migrations are applied up to, defaults to the latest
+	// migration in Dir. The version is verified after migrations are applied.
+	Version uint64
+	// TestRollback reverts all migrations and applies them again to test down migrations.
+	TestRollback bool
+	// SchemaFile is a golden MySQL schema snapshot, the environment fails to start if
+	// schema after migrations differs. Run tests with -update_schema flag to write it.
+	SchemaFile string
+	// Timeout limits migration time, defaults to 5 minutes.
+	Timeout time.Duration
 }
 
+// MigrationTool selects tool applying migrations.
+type MigrationTool int
+
+const (
+	// MigrationToolMigrate uses golang-migrate <version>_<name>.(up|down).sql files.
+	MigrationToolMigrate MigrationTool = iota
+	// MigrationToolGoose uses goose <version>_<name>.sql files.
+	MigrationToolGoose
+	// MigrationToolSQLMigrate uses sql-migrate, Version can't be set.
+	MigrationToolSQLMigrate
+	// MigrationToolPlainSQL applies Flyway style V<version>__<name>.sql files,
+	// rollback uses U<version>__<name>.sql files.
+	MigrationToolPlainSQL
+)
+
+// MigrationDriver distinguish between databases migrations are applied to.
+type MigrationDriver int
+
+const (
+	MigrationDriverMySQL MigrationDriver = iota
+	MigrationDriverPostgres
+)
+
 type MysqlSettings struct {
 	// DatabaseName is name of a database that will be created
 	DatabaseName string
@@ -25,8 +69,71 @@ type MysqlSettings struct {
 	MigrationDir string
 	// Password for mysql user.
 	Password string
-	// Port address used for mysql service.
+	// Port is a host port forwarded to mysql, defaults to 3306.
 	Port string
+	// Variant and Version select server image, Version is an image tag and defaults to latest.
+	Variant MySQLVariant
+	Version string
+	// Users are non-root accounts, by default granted all privileges on the Databases.
+	Users []MySQLUser
+	// InitScripts are SQL or shell scripts, or dirs with scripts, executed in order on start.
+	InitScripts []string
+	// SQLMode sets server sql_mode and TimeZone the default time zone e.g. "+00:00".
+	SQLMode  string
+	TimeZone string
+	// Flags are additional server flags e.g. "--max-connections=500".
+	Flags []string
+	// Isolation selects how databases are restored before each suite test method.
+	Isolation MySQLIsolation
+}
+
+// MySQLVariant selects MySQL compatible server.
+type MySQLVariant int
+
+const (
+	MySQLVariantMySQL MySQLVariant = iota
+	MySQLVariantMariaDB
+)
+
+type MySQLUser struct {
+	Name     string
+	Password string
+	// Host defaults to "%".
+	Host string
+	// Grants e.g. "SELECT, INSERT ON test_db.*".
+	Grants []string
+}
+
+// MySQLIsolation distinguish between different ways of restoring databases state between tests.
+type MySQLIsolation int
+
+const (
+	// MySQLIsolationNone leaves databases state unchanged between tests.
+	MySQLIsolationNone MySQLIsolation = iota
+	// MySQLIsolationTruncate truncates all tables except migration bookkeeping tables.
+	MySQLIsolationTruncate
+	// MySQLIsolationSnapshot restores tables data from a snapshot taken after migrations,
+	// before service SUTs are started.
+	MySQLIsolationSnapshot
+	// MySQLIsolationDatabasePerTest recreates each database from a template taken after
+	// migrations, restoring also tables schema changed by the SUT. SUT connections using
+	// the database are killed before it's dropped.
+	MySQLIsolationDatabasePerTest
+)
+
+type PostgresSettings struct {
+	// User is a superuser name, defaults to postgres.
+	User string
+	// Password is the superuser password, if empty connections are accepted without password.
+	Password string
+	// Databases are created on start, the first one is the default user database.
+	Databases []string
+	// Extensions are created in each database, e.g. "uuid-ossp" or "pgcrypto".
+	Extensions []string
+	// Port is a host port forwarded to postgres, defaults to 5432.
+	Port int
+	// Version is postgres image tag, defaults to 12.
+	Version string
 }
 
 // RuntimeType distinguish between different ways of sut execution.
@@ -39,22 +146,64 @@ const (
 	// RuntimeTypeCommand indicates that system under test is a simile binary that will
 	// terminate after execution.
 	RuntimeTypeCommand
+
+	// RuntimeTypeProcess runs system under test service binary directly on the host instead of
+	// docker container. HTTP traffic is redirected with HTTP_PROXY and HTTPS_PROXY.
+	RuntimeTypeProcess
+
+	// RuntimeTypeProcessCommand runs system under test command binary on the host for each test case.
+	RuntimeTypeProcessCommand
 )
 
+func (t RuntimeType) command() bool {
+	return t == RuntimeTypeCommand || t == RuntimeTypeProcessCommand
+}
+
+func (t RuntimeType) process() bool {
+	return t == RuntimeTypeProcess || t == RuntimeTypeProcessCommand
+}
+
 // SutSettings system under test settings used to build and executed sut in docker container.
 type SutSettings struct {
+	// Name distinguishes SUTs when more than one is used, SUT container is named sut_<name>_mtf
+	// and can be reached by other container SUTs under this host name. Process runtime SUTs run
+	// on the host outside of the docker network, so they neither resolve SUT host names nor can
+	// be reached by them, they can be reached only on their Ports forwarded to localhost.
+	Name string
 	// Envs allows to pass custom env to system under test container.
 	Envs []string
 	// Dir is a path to directory that collect system under test source for which binary should be build.
 	Dir string
+	// Image is a prebuilt system under test image used instead of Dir, e.g. the release image.
+	Image string
+	// Dockerfile is a path to Dockerfile the system under test image is built from instead of Dir.
+	// Image is rebuilt unless -rebuild_binary=false.
+	Dockerfile string
+	// BuildContext is a dir sent to docker as the Dockerfile build context, defaults to the Dockerfile
+	// dir. It has to contain the Dockerfile, files matching its .dockerignore patterns are not sent.
+	BuildContext string
 	// Ports is a collection of ports that sut binary require, those ports will be forwarded to local host with the
 	// same port mapping.
 	Ports []int
 
+	// Mounts are host directories mounted into the system under test container, they can be
+	// observed by the port.NewFSPort.
+	Mounts []SutMount
+
 	// RuntimeType Type of system under test runtime. In case of service runtime sut component will
 	// be executed once, but when runtime type is set to command (terminates after execution) sut component
 	// needs to be re-executed for each test case.
 	RuntimeType RuntimeType
+
+	// ManualStart disables starting command runtime SUT before each test case, the test starts
+	// it with framework.StartSUT. Running SUT is stopped after the test case.
+	ManualStart bool
+}
+
+// SutMount mounts HostDir into system under test container at ContainerDir.
+type SutMount struct {
+	HostDir      string
+	ContainerDir string
 }
 
 type PubSubSettings struct {
@@ -68,12 +217,44 @@ type TopicSubscriptions struct {
 }
 
 type RedisSettings struct {
+	// Port is a host port forwarded to redis, defaults to 6379.
 	Port     string
 	Password string
+	// FixtureFile is a JSON file with keys loaded before each test, all other keys are removed.
+	// See redis.Fixture for the file format.
+	FixtureFile string
 }
 
+// FTPProtocol selects file transfer server variant.
+type FTPProtocol int
+
+const (
+	FTPProtocolFTP FTPProtocol = iota
+	// FTPProtocolFTPS enables explicit TLS with the MTF generated cert, plain FTP connections
+	// are still accepted.
+	FTPProtocolFTPS
+	// FTPProtocolSFTP runs SSH file transfer server instead of FTP.
+	FTPProtocolSFTP
+)
+
 type FTPSettings struct {
 	Addr string
+	// User and Pass are main account credentials, defaults to test/test.
+	User string
+	Pass string
+	// Users are additional accounts sharing the same /ftp root dir.
+	Users []FTPUser
+	// Port is a host port forwarded to FTP control port, defaults to 21 for FTP and FTPS
+	// and to 2222 for SFTP.
+	Port int
+	// PassivePortMin and PassivePortMax limit FTP passive mode data ports forwarded to
+	// the host, defaults to 21100-21110.
+	PassivePortMin int
+	PassivePortMax int
+	Protocol       FTPProtocol
+}
+
+type FTPUser struct {
 	User string
 	Pass string
 }
@@ -89,13 +270,19 @@ func (env *TestEnvironment) WithMySQL(settings MysqlSettings) *TestEnvironment {
 	return env
 }
 
+func (env *TestEnvironment) WithPostgres(settings PostgresSettings) *TestEnvironment {
+	env.settings.Postgres = &settings
+	return env
+}
+
 func (env *TestEnvironment) WithMigration(settings []*MigrationSettings) *TestEnvironment {
 	env.settings.Migration = settings
 	return env
 }
 
+// WithSUT adds system under test, it can be called multiple times with differently named SUTs.
 func (env *TestEnvironment) WithSUT(settings SutSettings) *TestEnvironment {
-	env.settings.SUT = &settings
+	env.settings.SUT = append(env.settings.SUT, &settings)
 	return env
 }
 
@@ -1,6 +1,7 @@
 package framework
 
 import (
+	gocontext "context"
 	"reflect"
 	"strings"
 	"testing"
@@ -8,6 +9,7 @@ import (
 
 	"github.com/smallinsky/mtf/framework/context"
 	"github.com/smallinsky/mtf/pkg/netw"
+	"github.com/smallinsky/mtf/port"
 )
 
 type Initable interface {
@@ -33,19 +35,19 @@ func Run(t *testing.T, i interface{}) {
 	}
 
 	for _, test := range getInternalTests(i) {
-		if testenv.settings.SUT.RuntimeType == RuntimeTypeCommand {
-			err := testenv.StartSutInCommandMode()
-			if err != nil {
-				t.Fatalf("[MTF ERROR] Failed to start sut component in cmd mode: %v", err)
-			}
+		if err := testenv.Reset(gocontext.Background()); err != nil {
+			t.Fatalf("[MTF ERROR] Failed to reset components state: %v", err)
+		}
+		if err := port.Reset(gocontext.Background()); err != nil {
+			t.Fatalf("[MTF ERROR] Failed to reset ports state: %v", err)
+		}
+		if err := testenv.StartSutInCommandMode(); err != nil {
+			t.Fatalf("[MTF ERROR] Failed to start sut component in cmd mode: %v", err)
 		}
 		t.Run(test.Name, test.F)
 
-		if testenv.settings.SUT.RuntimeType == RuntimeTypeCommand {
-			err := testenv.StopSutInCommandMode(test.Name)
-			if err != nil {
-				t.Fatalf("[MTF ERROR] Failed to stop sut component in cmd mode: %v", err)
-			}
+		if err := testenv.StopSutInCommandMode(test.Name); err != nil {
+			t.Fatalf("[MTF ERROR] Failed to stop sut component in cmd mode: %v", err)
 		}
 	}
 }
@@ -69,11 +71,27 @@ func getInternalTests(i interface{}) []testing.InternalTest {
 			Name: tm.Name,
 			F: func(t *testing.T) {
 				context.CreateTestContext(t)
+				defer context.RemoveTextContext(t)
+				defer reportFailures(t, context.Get(t))
 				m.Call([]reflect.Value{reflect.ValueOf(t)})
-				context.RemoveTextContext(t)
 			},
 		})
 	}
 
 	return tests
 }
+
+// errorReporter is implemented by testing.T.
+type errorReporter interface {
+	Errorf(format string, args ...interface{})
+}
+
+// reportFailures fails the test with errors recorded by ports outside of test goroutine.
+func reportFailures(t errorReporter, c *context.TestContext) {
+	if c == nil {
+		return
+	}
+	for _, f := range c.Failures() {
+		t.Errorf("[MTF ERROR] %s", f)
+	}
+}
@@ -0,0 +1,53 @@
+package framework
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/smallinsky/mtf/framework/context"
+)
+
+type recordingReporter struct {
+	errors []string
+}
+
+func (r *recordingReporter) Errorf(format string, args ...interface{}) {
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestReportFailures(t *testing.T) {
+	// Test context logs are written to the runlogs dir in the working dir.
+	dir, err := ioutil.TempDir("", "mtf_suite")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working dir: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change working dir: %v", err)
+	}
+	defer os.Chdir(wd)
+	context.CreateDirectory()
+	context.CreateTestContext(t)
+	defer context.RemoveTextContext(t)
+
+	// Port handlers record failures outside of the test goroutine.
+	done := make(chan struct{})
+	go func() {
+		context.Current().Fail("gcs port: %s", "request timed out")
+		close(done)
+	}()
+	<-done
+
+	var r recordingReporter
+	reportFailures(&r, context.Get(t))
+	if want := []string{"[MTF ERROR] gcs port: request timed out"}; !reflect.DeepEqual(r.errors, want) {
+		t.Fatalf("reported errors mismatch, got: %q want: %q", r.errors, want)
+	}
+}
@@ -5,7 +5,10 @@ go 1.13
 require (
 	cloud.google.com/go v0.45.1
 	cloud.google.com/go/pubsub v1.0.1
+	github.com/DATA-DOG/go-sqlmock v1.5.0
 	github.com/Microsoft/go-winio v0.4.14 // indirect
+	github.com/alicebob/gopher-json v0.0.0-20180125190556-5a6b3ba71ee6 // indirect
+	github.com/alicebob/miniredis v2.5.0+incompatible
 	github.com/cenkalti/backoff v2.2.1+incompatible
 	github.com/docker/distribution v2.7.1+incompatible // indirect
 	github.com/docker/docker v1.13.1
@@ -16,6 +19,7 @@ require (
 	github.com/go-sql-driver/mysql v1.4.1
 	github.com/go-test/deep v1.0.4
 	github.com/golang/protobuf v1.3.2
+	github.com/gomodule/redigo v1.7.0 // indirect
 	github.com/google/go-cmp v0.3.1
 	github.com/gorilla/mux v1.7.3
 	github.com/jlaffaye/ftp v0.0.0-20190828173736-6aaa91c7796e
@@ -25,10 +29,12 @@ require (
 	github.com/opencontainers/go-digest v1.0.0-rc1 // indirect
 	github.com/pkg/errors v0.8.1
 	github.com/sirupsen/logrus v1.4.2
+	github.com/yuin/gopher-lua v0.0.0-20180827083657-b942cacc89fe // indirect
 	golang.org/x/net v0.0.0-20191003171128-d98b1b443823
 	golang.org/x/oauth2 v0.0.0-20190604053449-0f29369cfe45
 	golang.org/x/sync v0.0.0-20190911185100-cd5d95a43a6e // indirect
 	golang.org/x/tools v0.0.0-20191116214431-80313e1ba718 // indirect
 	google.golang.org/api v0.9.0
 	google.golang.org/grpc v1.24.0
+	gopkg.in/yaml.v2 v2.4.0
 )
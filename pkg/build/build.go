@@ -10,7 +10,18 @@ import (
 	"github.com/smallinsky/mtf/pkg/exec"
 )
 
+// Build builds linux/amd64 binary of the package in the path dir, the binary
+// is named after the dir.
 func Build(path string) error {
+	return build(path, "GOOS=linux", "GOARCH=amd64")
+}
+
+// BuildForHost builds the binary for the host platform.
+func BuildForHost(path string) error {
+	return build(path)
+}
+
+func build(path string, env ...string) error {
 	var err error
 	if path, err = filepath.Abs(path); err != nil {
 		return errors.Wrapf(err, "failed to get abs path")
@@ -35,7 +46,7 @@ func Build(path string) error {
 		"go", "build", "-o", fmt.Sprintf("%s/%s", path, bin), path,
 	}
 
-	if err := exec.Run(cmd, exec.WithEnv("CGO_ENABLED=0", "GODEBUG=x509ignoreCN=1", "GOOS=linux", "GOARCH=amd64", "GO111MODULE=on")); err != nil {
+	if err := exec.Run(cmd, exec.WithEnv(append([]string{"CGO_ENABLED=0", "GODEBUG=x509ignoreCN=1", "GO111MODULE=on"}, env...)...)); err != nil {
 		return errors.Wrapf(err, "failed to run cmd")
 	}
 
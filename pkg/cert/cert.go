@@ -31,7 +31,29 @@ var sniHosts = []string{
 	"www.googleapis.com",
 	"storage.googleapis.com",
 	"oauth2.googleapis.com",
-	"172.17.0.1", // default docker linux host addr.
+	"s3.amazonaws.com",
+	"*.s3.amazonaws.com", // virtual-hosted style bucket endpoints.
+	"172.17.0.1",         // default docker linux host addr.
+}
+
+// S3Regions are AWS regions with S3 regional endpoints trusted by the cert, SDKs
+// configured with a region call s3.<region>.amazonaws.com instead of the global one.
+var S3Regions = []string{
+	"us-east-1", "us-east-2", "us-west-1", "us-west-2",
+	"ca-central-1", "sa-east-1",
+	"eu-central-1", "eu-west-1", "eu-west-2", "eu-west-3", "eu-north-1", "eu-south-1",
+	"ap-east-1", "ap-south-1", "ap-northeast-1", "ap-northeast-2", "ap-northeast-3",
+	"ap-southeast-1", "ap-southeast-2",
+	"me-south-1", "af-south-1",
+}
+
+// s3RegionHosts returns path-style and virtual-hosted style endpoints of S3Regions.
+func s3RegionHosts() []string {
+	var hosts []string
+	for _, r := range S3Regions {
+		hosts = append(hosts, "s3."+r+".amazonaws.com", "*.s3."+r+".amazonaws.com")
+	}
+	return hosts
 }
 
 type CertKey struct {
@@ -67,6 +89,7 @@ func GenCert(hosts []string) (*CertKey, error) {
 	}
 
 	hosts = append(hosts, sniHosts...)
+	hosts = append(hosts, s3RegionHosts()...)
 	for _, h := range hosts {
 		if ip := net.ParseIP(h); ip != nil {
 			template.IPAddresses = append(template.IPAddresses, ip)
@@ -0,0 +1,33 @@
+package cert
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+)
+
+func TestGenCertS3Hosts(t *testing.T) {
+	ck, err := GenCert(nil)
+	if err != nil {
+		t.Fatalf("failed to generate cert: %v", err)
+	}
+	block, _ := pem.Decode(ck.Cert)
+	if block == nil {
+		t.Fatalf("failed to decode cert")
+	}
+	c, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		t.Fatalf("failed to parse cert: %v", err)
+	}
+
+	for _, host := range []string{
+		"s3.amazonaws.com",
+		"bucket.s3.amazonaws.com",
+		"s3.eu-west-1.amazonaws.com",
+		"bucket.s3.eu-west-1.amazonaws.com",
+	} {
+		if err := c.VerifyHostname(host); err != nil {
+			t.Fatalf("cert is not valid for %s: %v", host, err)
+		}
+	}
+}
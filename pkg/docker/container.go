@@ -17,6 +17,8 @@ import (
 
 type Container interface {
 	Start(context.Context) error
+	// Run starts one-shot job container and waits until it exits.
+	Run(context.Context, io.Writer) error
 	Stop(context.Context) error
 	Logs(context.Context) (io.Reader, error)
 	GetState(context.Context) (*types.ContainerState, error)
@@ -78,11 +78,14 @@ type ContainerConfig struct {
 	Labels          map[string]string
 	Mounts          Mounts
 	NetworkName     string
-	Healtcheck      *HealthCheckConfig
-	AttachIfExist   bool
-	AutoRemove      bool
-	Privileged      bool
-	WaitPolicy      WaitPolicy
+	// NetworkMode e.g. "container:<name>" joins network stack of another container,
+	// NetworkName, Hostname and PortMap are not used then.
+	NetworkMode   string
+	Healtcheck    *HealthCheckConfig
+	AttachIfExist bool
+	AutoRemove    bool
+	Privileged    bool
+	WaitPolicy    WaitPolicy
 }
 
 type HealthCheckConfig struct {
@@ -133,7 +136,7 @@ func (c *Docker) NewContainer(config ContainerConfig) (*ContainerType, error) {
 
 	config.Env = append(config.Env, "DOCKER_HOST_ADDR="+hostAddr)
 
-	if config.Hostname == "" {
+	if config.Hostname == "" && config.NetworkMode == "" {
 		config.Hostname = config.Name
 	}
 
@@ -169,6 +172,12 @@ func (c *Docker) NewContainer(config ContainerConfig) (*ContainerType, error) {
 			},
 		},
 	}
+	if config.NetworkMode != "" {
+		createConf.ExposedPorts = nil
+		hostConf.NetworkMode = container.NetworkMode(config.NetworkMode)
+		hostConf.PortBindings = nil
+		netConf = &network.NetworkingConfig{}
+	}
 
 	result, err := c.cli.ContainerCreate(context.Background(), createConf, hostConf, netConf, config.Name)
 	if err != nil {
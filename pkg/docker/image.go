@@ -1,10 +1,15 @@
 package docker
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"os"
+	"path/filepath"
 
 	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/builder/dockerignore"
+	"github.com/docker/docker/pkg/jsonmessage"
 	"github.com/smallinsky/mtf/pkg/tar"
 )
 
@@ -18,17 +23,65 @@ func (c *Docker) PullImage(ref string) error {
 }
 
 type BuildImageConfig struct {
+	// Path is a build context dir.
 	Path string
 	Tag  string
+	// Dockerfile is a path relative to the context dir, defaults to Dockerfile.
+	Dockerfile string
 }
 
 func (c *Docker) BuildImage(path, tag string) error {
-	r, err := tar.DirReader(path)
+	return c.BuildImageFromConfig(context.Background(), BuildImageConfig{
+		Path: path,
+		Tag:  tag,
+	})
+}
+
+// BuildImageFromConfig builds the image and waits until the build is finished. Files
+// matching context dir .dockerignore patterns are not sent to the docker daemon.
+func (c *Docker) BuildImageFromConfig(ctx context.Context, config BuildImageConfig) error {
+	excludes, err := readDockerignore(config.Path)
+	if err != nil {
+		return err
+	}
+	dockerfile := config.Dockerfile
+	if dockerfile == "" {
+		dockerfile = "Dockerfile"
+	}
+	// Dockerfile and .dockerignore are always sent like docker build does.
+	excludes = append(excludes, "!"+filepath.ToSlash(dockerfile), "!.dockerignore")
+
+	r, err := tar.DirReader(config.Path, excludes...)
 	if err != nil {
 		return fmt.Errorf("failed to tar dir: %v", err)
 	}
-	_, err = c.cli.ImageBuild(context.Background(), r, types.ImageBuildOptions{
-		Tags: []string{tag},
+	resp, err := c.cli.ImageBuild(ctx, r, types.ImageBuildOptions{
+		Tags:        []string{config.Tag},
+		Dockerfile:  config.Dockerfile,
+		Remove:      true,
+		ForceRemove: true,
 	})
-	return err
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	// Build errors are reported in the response stream.
+	var out bytes.Buffer
+	if err := jsonmessage.DisplayJSONMessagesStream(resp.Body, &out, 0, false, nil); err != nil {
+		return fmt.Errorf("failed to build %s image: %v\n%s", config.Tag, err, out.String())
+	}
+	return nil
+}
+
+func readDockerignore(dir string) ([]string, error) {
+	f, err := os.Open(filepath.Join(dir, ".dockerignore"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return dockerignore.ReadAll(f)
 }
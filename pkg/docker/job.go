@@ -0,0 +1,169 @@
+package docker
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+const (
+	// logsDrainTimeout is how long Run waits for remaining logs after container exit.
+	logsDrainTimeout = time.Second * 5
+	// maxExitErrorOutput limits container output kept in ExitError.
+	maxExitErrorOutput = 64 * 1024
+)
+
+// ExitError is returned by Run when container exits with non-zero code.
+type ExitError struct {
+	Name     string
+	ExitCode int
+	// Output is the tail of container stdout and stderr.
+	Output string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s container exited with code %d:\n%s", e.Name, e.ExitCode, e.Output)
+}
+
+// Run starts one-shot job container and blocks until it exits, container output is
+// streamed to the out writer while it runs. When ctx is done the container is killed.
+func (c *ContainerType) Run(ctx context.Context, out io.Writer) error {
+	if err := c.cli.ContainerStart(ctx, c.ID, types.ContainerStartOptions{}); err != nil {
+		return err
+	}
+
+	tail := &tailBuffer{max: maxExitErrorOutput}
+	if out == nil {
+		out = tail
+	} else {
+		out = io.MultiWriter(out, tail)
+	}
+	logsCtx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	logsDone := make(chan struct{})
+	go func() {
+		defer close(logsDone)
+		c.followLogs(logsCtx, out)
+	}()
+
+	code, err := c.cli.ContainerWait(ctx, c.ID)
+	if err != nil {
+		if ctx.Err() != nil {
+			c.kill()
+			return fmt.Errorf("%s container didn't finish: %v", c.Name(), ctx.Err())
+		}
+		return err
+	}
+
+	select {
+	case <-logsDone:
+	case <-time.After(logsDrainTimeout):
+	}
+	if code != 0 {
+		return &ExitError{
+			Name:     c.Name(),
+			ExitCode: int(code),
+			Output:   tail.String(),
+		}
+	}
+	return nil
+}
+
+func (c *ContainerType) followLogs(ctx context.Context, out io.Writer) {
+	options := types.ContainerLogsOptions{
+		ShowStdout: true,
+		ShowStderr: true,
+		Follow:     true,
+	}
+	rc, err := c.cli.ContainerLogs(ctx, c.ID, options)
+	if err != nil {
+		fmt.Fprintf(out, "failed to follow %s container logs: %v\n", c.Name(), err)
+		return
+	}
+	defer rc.Close()
+	stdcopy.StdCopy(out, out, rc)
+}
+
+func (c *ContainerType) kill() {
+	// Job ctx is already done, so the container is killed with a new one.
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*10)
+	defer cancel()
+	c.cli.ContainerKill(ctx, c.ID, "KILL")
+}
+
+// tailBuffer keeps the last max bytes written to it.
+type tailBuffer struct {
+	mtx  sync.Mutex
+	buff []byte
+	max  int
+}
+
+func (b *tailBuffer) Write(p []byte) (int, error) {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	b.buff = append(b.buff, p...)
+	if over := len(b.buff) - b.max; over > 0 {
+		b.buff = append(b.buff[:0], b.buff[over:]...)
+	}
+	return len(p), nil
+}
+
+func (b *tailBuffer) String() string {
+	b.mtx.Lock()
+	defer b.mtx.Unlock()
+	return string(b.buff)
+}
+
+// LogWriter writes each complete line prefixed with the prefix to the out writer.
+type LogWriter struct {
+	out    io.Writer
+	prefix string
+
+	mtx  sync.Mutex
+	line bytes.Buffer
+}
+
+func NewLogWriter(out io.Writer, prefix string) *LogWriter {
+	return &LogWriter{
+		out:    out,
+		prefix: prefix,
+	}
+}
+
+func (w *LogWriter) Write(p []byte) (int, error) {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	for _, b := range p {
+		w.line.WriteByte(b)
+		if b != '\n' {
+			continue
+		}
+		if err := w.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Close writes the last line if it isn't terminated with a new line.
+func (w *LogWriter) Close() error {
+	w.mtx.Lock()
+	defer w.mtx.Unlock()
+	if w.line.Len() == 0 {
+		return nil
+	}
+	w.line.WriteByte('\n')
+	return w.flush()
+}
+
+func (w *LogWriter) flush() error {
+	_, err := fmt.Fprintf(w.out, "%s%s", w.prefix, w.line.String())
+	w.line.Reset()
+	return err
+}
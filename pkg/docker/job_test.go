@@ -0,0 +1,33 @@
+package docker
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestLogWriter(t *testing.T) {
+	var buff bytes.Buffer
+	w := NewLogWriter(&buff, "[job] ")
+	for _, s := range []string{"first", " line\nsecond line\n", "last"} {
+		if _, err := w.Write([]byte(s)); err != nil {
+			t.Fatalf("failed to write: %v", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close: %v", err)
+	}
+
+	want := "[job] first line\n[job] second line\n[job] last\n"
+	if got := buff.String(); got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
+
+func TestTailBuffer(t *testing.T) {
+	b := &tailBuffer{max: 4}
+	b.Write([]byte("abc"))
+	b.Write([]byte("def"))
+	if got, want := b.String(), "cdef"; got != want {
+		t.Fatalf("got %q, want %q", got, want)
+	}
+}
@@ -20,21 +20,31 @@ const (
 	retryMaxCount = 10
 )
 
+// Monitor publishes dir changes to the addr with unary Event calls.
 func Monitor(addr, dir string) {
 	client, err := newWatcherClient(addr)
 	if err != nil {
 		log.Fatalf("failed to create watcher client: %v", err)
 	}
-
-	pub := &ActionHandler{
+	watch(dir, &ActionHandler{
 		Client: client,
-	}
+	})
+}
 
-	w := &Watcher{
-		Dir:          dir,
-		EventHandler: pub,
-		stop:         make(chan struct{}),
+// MonitorStream publishes dir changes to the addr over Watch stream.
+func MonitorStream(addr, dir string) {
+	client, err := newWatcherClient(addr)
+	if err != nil {
+		log.Fatalf("failed to create watcher client: %v", err)
 	}
+	pub := NewStreamPublisher(client)
+	go pub.Run()
+	defer pub.Close()
+	watch(dir, pub)
+}
+
+func watch(dir string, handler EventHandler) {
+	w := New(dir, handler)
 
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt)
@@ -63,44 +73,63 @@ type ActionHandler struct {
 
 // OnFileCreated sends Create action details to remote events server.
 func (g *ActionHandler) OnFileCreated(path string) error {
-	f, err := os.Open(path)
-	if err != nil {
-		return fmt.Errorf("failed to open file '%v': %s", path, err)
-	}
-	// TODO lazy wait to make sure that all bufer will be flush into file to avoid
-	// partial read of the file.
-	time.Sleep(time.Millisecond * 100)
+	return g.sendEvent(newEvent(path, pb.Action_ADDED, true))
+}
+
+// OnFileModified sends Edit action details to remote events server.
+func (g *ActionHandler) OnFileModified(path string) error {
+	return g.sendEvent(newEvent(path, pb.Action_EDITED, true))
+}
+
+// OnFileDeleted sends remove action details to remote events server.
+func (g *ActionHandler) OnFileDeleted(path string) error {
+	return g.sendEvent(newEvent(path, pb.Action_REMOVED, false))
+}
+
+// OnFileRenamed sends rename action details to remote events server.
+func (g *ActionHandler) OnFileRenamed(path string) error {
+	return g.sendEvent(newEvent(path, pb.Action_RENAMED, false))
+}
+
+// OnFileModeChanged sends mode change action details to remote events server.
+func (g *ActionHandler) OnFileModeChanged(path string) error {
+	return g.sendEvent(newEvent(path, pb.Action_MODE_CHANGED, false))
+}
 
-	buff, err := ioutil.ReadAll(f)
+// newEvent creates event request for the path, nil is returned when file content
+// was requested but the file doesn't exist anymore.
+func newEvent(path string, action pb.Action, withContent bool) (*pb.EventRequest, error) {
+	req := &pb.EventRequest{
+		Path:   path,
+		Action: action,
+	}
+	if !withContent {
+		return req, nil
+	}
+	buff, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// File was removed before it was reported, removal event will follow.
+		return nil, nil
+	}
 	if err != nil {
-		return fmt.Errorf("failed to read file '%v': %s", path, err)
+		return nil, fmt.Errorf("failed to read file '%v': %s", path, err)
 	}
-	defer f.Close()
-
-	err = withRetry(func() error {
-		_, err = g.Client.Event(context.Background(), &pb.EventRequest{
-			Path:    path,
-			Action:  pb.Action_ADDED,
-			Content: buff,
-		})
+	req.Content = buff
+	return req, nil
+}
+
+func (g *ActionHandler) sendEvent(req *pb.EventRequest, err error) error {
+	if err != nil || req == nil {
 		return err
-	})
-	if err != nil {
-		return fmt.Errorf("failed to send event: %s", err)
 	}
-	return nil
+	return g.send(req)
 }
 
-// OnFileDeleted sends remove action details to remote events server.
-func (g *ActionHandler) OnFileDeleted(path string) error {
+func (g *ActionHandler) send(req *pb.EventRequest) error {
 	err := withRetry(func() error {
-		_, err := g.Client.Event(context.Background(), &pb.EventRequest{
-			Path:   path,
-			Action: pb.Action_REMOVED,
-		})
+		_, err := g.Client.Event(context.Background(), req)
 		return err
 	})
-
 	if err != nil {
 		return fmt.Errorf("failed to send event: %s", err)
 	}
@@ -0,0 +1,223 @@
+package fswatch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+	"time"
+
+	pb "github.com/smallinsky/mtf/proto/fswatch"
+)
+
+const (
+	// maxBatchSize limits number of events sent in a single Watch stream message.
+	maxBatchSize = 64
+	// reconnectInterval is a delay between Watch stream reconnect attempts.
+	reconnectInterval = time.Millisecond * 200
+	// closeTimeout is how long Close waits for pending events to be acknowledged.
+	closeTimeout = time.Second * 10
+)
+
+// StreamPublisher publishes directory changes over Watch stream. Events are numbered,
+// sent in batches and resent after stream reconnect until acknowledged by the subscriber.
+type StreamPublisher struct {
+	client pb.WatcherClient
+	id     string
+
+	mtx sync.Mutex
+	seq uint64
+	// unacked events ordered by seq.
+	unacked []*pb.EventRequest
+
+	notify chan struct{}
+	stop   chan struct{}
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+func NewStreamPublisher(client pb.WatcherClient) *StreamPublisher {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &StreamPublisher{
+		client: client,
+		id:     publisherID(),
+		notify: make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+func publisherID() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// OnFileCreated queues Create action details.
+func (p *StreamPublisher) OnFileCreated(path string) error {
+	return p.publish(newEvent(path, pb.Action_ADDED, true))
+}
+
+// OnFileModified queues Edit action details.
+func (p *StreamPublisher) OnFileModified(path string) error {
+	return p.publish(newEvent(path, pb.Action_EDITED, true))
+}
+
+// OnFileDeleted queues remove action details.
+func (p *StreamPublisher) OnFileDeleted(path string) error {
+	return p.publish(newEvent(path, pb.Action_REMOVED, false))
+}
+
+// OnFileRenamed queues rename action details.
+func (p *StreamPublisher) OnFileRenamed(path string) error {
+	return p.publish(newEvent(path, pb.Action_RENAMED, false))
+}
+
+// OnFileModeChanged queues mode change action details.
+func (p *StreamPublisher) OnFileModeChanged(path string) error {
+	return p.publish(newEvent(path, pb.Action_MODE_CHANGED, false))
+}
+
+func (p *StreamPublisher) publish(req *pb.EventRequest, err error) error {
+	if err != nil || req == nil {
+		return err
+	}
+	p.mtx.Lock()
+	p.seq++
+	req.Seq = p.seq
+	p.unacked = append(p.unacked, req)
+	p.mtx.Unlock()
+	p.wakeup()
+	return nil
+}
+
+func (p *StreamPublisher) wakeup() {
+	select {
+	case p.notify <- struct{}{}:
+	default:
+	}
+}
+
+// Run sends queued events until Close is called, broken stream is reopened and
+// all unacknowledged events are sent again.
+func (p *StreamPublisher) Run() {
+	defer close(p.done)
+	for {
+		err := p.stream()
+		if err == nil || p.ctx.Err() != nil {
+			return
+		}
+		log.Printf("[WARN] watch stream failed: %v", err)
+		select {
+		case <-time.After(reconnectInterval):
+		case <-p.ctx.Done():
+			return
+		}
+	}
+}
+
+// Close waits until all queued events are acknowledged or closeTimeout
+// is exceeded and stops the publisher.
+func (p *StreamPublisher) Close() {
+	close(p.stop)
+	p.wakeup()
+	select {
+	case <-p.done:
+	case <-time.After(closeTimeout):
+		log.Printf("[WARN] %d events were not acknowledged", p.pending())
+	}
+	p.cancel()
+	<-p.done
+}
+
+// stream sends events over a single Watch stream, nil is returned once the
+// publisher is stopped and all events are acknowledged.
+func (p *StreamPublisher) stream() error {
+	ctx, cancel := context.WithCancel(p.ctx)
+	defer cancel()
+	stream, err := p.client.Watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	acked := make(chan error, 1)
+	go func() {
+		acked <- p.recvAcks(stream)
+	}()
+
+	// sent is the highest seq sent over this stream.
+	var sent uint64
+	for {
+		batch := p.batch(sent)
+		if len(batch) == 0 {
+			if p.stopped() && p.pending() == 0 {
+				return stream.CloseSend()
+			}
+			select {
+			case <-p.notify:
+			case err := <-acked:
+				return err
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			continue
+		}
+		if err := stream.Send(&pb.EventBatch{PublisherId: p.id, Events: batch}); err != nil {
+			return err
+		}
+		sent = batch[len(batch)-1].Seq
+	}
+}
+
+func (p *StreamPublisher) recvAcks(stream pb.Watcher_WatchClient) error {
+	for {
+		ack, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		p.mtx.Lock()
+		n := 0
+		for n < len(p.unacked) && p.unacked[n].Seq <= ack.Seq {
+			n++
+		}
+		p.unacked = p.unacked[n:]
+		p.mtx.Unlock()
+		p.wakeup()
+	}
+}
+
+// batch returns unacknowledged events with seq greater than sent.
+func (p *StreamPublisher) batch(sent uint64) []*pb.EventRequest {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	var batch []*pb.EventRequest
+	for _, e := range p.unacked {
+		if e.Seq <= sent {
+			continue
+		}
+		batch = append(batch, e)
+		if len(batch) == maxBatchSize {
+			break
+		}
+	}
+	return batch
+}
+
+func (p *StreamPublisher) pending() int {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	return len(p.unacked)
+}
+
+func (p *StreamPublisher) stopped() bool {
+	select {
+	case <-p.stop:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,132 @@
+package fswatch
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	pb "github.com/smallinsky/mtf/proto/fswatch"
+)
+
+func TestSubscriberDeliverInOrder(t *testing.T) {
+	var got []uint64
+	ss := newSubService(func(e *pb.EventRequest) {
+		got = append(got, e.GetSeq())
+	})
+	batch := func(id string, seqs ...uint64) *pb.EventBatch {
+		b := &pb.EventBatch{PublisherId: id}
+		for _, s := range seqs {
+			b.Events = append(b.Events, &pb.EventRequest{Seq: s})
+		}
+		return b
+	}
+
+	for _, tc := range []struct {
+		batch *pb.EventBatch
+		ack   uint64
+	}{
+		{batch("a", 1, 2), 2},
+		{batch("a", 4, 5), 2},
+		{batch("b", 1), 1},
+		// Events resent after reconnect.
+		{batch("a", 3, 4, 5), 5},
+		{batch("a", 5), 5},
+	} {
+		if ack := ss.deliver(tc.batch); ack != tc.ack {
+			t.Fatalf("ack mismatch, got: %v want: %v", ack, tc.ack)
+		}
+	}
+	if want := []uint64{1, 2, 1, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("delivered events mismatch, got: %v want: %v", got, want)
+	}
+}
+
+func TestStreamPublisher(t *testing.T) {
+	const addr = "localhost:11133"
+	client, err := newWatcherClient(addr)
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	pub := NewStreamPublisher(client)
+	go pub.Run()
+
+	// Events published before subscriber is available are sent once it is started.
+	for i := 0; i < 100; i++ {
+		if err := pub.OnFileDeleted(fmt.Sprintf("file%d", i)); err != nil {
+			t.Fatalf("failed to publish event: %v", err)
+		}
+	}
+
+	event := make(chan *pb.EventRequest, 100)
+	sub := NewSubscriber(addr, func(req *pb.EventRequest) {
+		event <- req
+	})
+	if err := sub.Start(); err != nil {
+		t.Fatalf("failed to start subscriber: %v", err)
+	}
+	defer sub.Stop()
+
+	for i := 0; i < 100; i++ {
+		select {
+		case got := <-event:
+			if got.GetSeq() != uint64(i+1) || got.GetPath() != fmt.Sprintf("file%d", i) {
+				t.Fatalf("unexpected event: %+v", got)
+			}
+		case <-time.After(time.Second * 3):
+			t.Fatalf("event %d was not received", i)
+		}
+	}
+	pub.Close()
+	if n := pub.pending(); n != 0 {
+		t.Fatalf("%d events were not acknowledged", n)
+	}
+}
+
+func TestMonitorStream(t *testing.T) {
+	tmpDir, err := ioutil.TempDir("", "test")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	event := make(chan *pb.EventRequest)
+	sub := NewSubscriber("localhost:11134", func(req *pb.EventRequest) {
+		event <- req
+	})
+	if err := sub.Start(); err != nil {
+		t.Fatalf("failed to start subscriber: %v", err)
+	}
+	defer sub.Stop()
+	go MonitorStream("localhost:11134", tmpDir)
+	time.Sleep(time.Millisecond * 100)
+
+	tmpFile := fmt.Sprintf("%s/%s", tmpDir, "tmpFile.txt")
+	if err := ioutil.WriteFile(tmpFile, []byte("tmp content"), 0644); err != nil {
+		t.Fatalf("failed to write to file: %v", err)
+	}
+	if err := os.Remove(tmpFile); err != nil {
+		t.Fatalf("failed to remove tmp file %v", err)
+	}
+	tmpFile2 := fmt.Sprintf("%s/%s", tmpDir, "tmpFile2.txt")
+	if err := ioutil.WriteFile(tmpFile2, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write to file: %v", err)
+	}
+
+	exp := []*pb.EventRequest{
+		{Path: tmpFile, Action: pb.Action_REMOVED, Seq: 1},
+		{Path: tmpFile2, Action: pb.Action_ADDED, Content: []byte("content"), Seq: 2},
+	}
+	for _, e := range exp {
+		select {
+		case got := <-event:
+			if !reflect.DeepEqual(got, e) {
+				t.Fatalf("got: %+v\nexp: %+v", got, e)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("file event was not received")
+		}
+	}
+}
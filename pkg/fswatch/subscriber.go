@@ -2,8 +2,11 @@ package fswatch
 
 import (
 	"context"
+	"fmt"
+	"io"
 	"log"
 	"net"
+	"sync"
 
 	"google.golang.org/grpc"
 
@@ -11,24 +14,124 @@ import (
 	pb "github.com/smallinsky/mtf/proto/fswatch"
 )
 
-func Subscriber(port string, handler func(event *pb.EventRequest)) {
-	l, err := net.Listen("tcp", port)
+// Handler is called for each event received by the Subscriber.
+type Handler func(event *pb.EventRequest)
+
+// Subscriber receives events published by Monitor and MonitorStream and passes
+// them to all registered handlers.
+type Subscriber struct {
+	addr string
+	svc  *subService
+
+	mtx      sync.Mutex
+	handlers map[int]Handler
+	nextID   int
+
+	server   *grpc.Server
+	listener net.Listener
+	serveErr chan error
+}
+
+// NewSubscriber creates subscriber that will listen on the addr once started.
+func NewSubscriber(addr string, handlers ...Handler) *Subscriber {
+	s := &Subscriber{
+		addr:     addr,
+		handlers: make(map[int]Handler),
+	}
+	s.svc = newSubService(s.dispatch)
+	for _, h := range handlers {
+		s.AddHandler(h)
+	}
+	return s
+}
+
+// AddHandler registers handler called for every received event, returned func
+// unregisters the handler.
+func (s *Subscriber) AddHandler(h Handler) func() {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	id := s.nextID
+	s.nextID++
+	s.handlers[id] = h
+	return func() {
+		s.mtx.Lock()
+		defer s.mtx.Unlock()
+		delete(s.handlers, id)
+	}
+}
+
+func (s *Subscriber) dispatch(event *pb.EventRequest) {
+	s.mtx.Lock()
+	handlers := make([]Handler, 0, len(s.handlers))
+	for i := 0; i < s.nextID; i++ {
+		if h, ok := s.handlers[i]; ok {
+			handlers = append(handlers, h)
+		}
+	}
+	s.mtx.Unlock()
+	for _, h := range handlers {
+		h(event)
+	}
+}
+
+// Start listens on the subscriber addr and serves events in the background.
+func (s *Subscriber) Start() error {
+	l, err := net.Listen("tcp", s.addr)
 	if err != nil {
-		log.Fatalf("[ERR] (fswatcher) failed to listen: %v", err)
+		return fmt.Errorf("failed to listen on %q: %v", s.addr, err)
 	}
-	s := grpc.NewServer()
-	svc := subService{
-		handler: handler,
+	s.listener = l
+	s.server = grpc.NewServer()
+	pb.RegisterWatcherServer(s.server, s.svc)
+
+	s.serveErr = make(chan error, 1)
+	go func() {
+		s.serveErr <- s.server.Serve(l)
+	}()
+	return nil
+}
+
+// Addr returns address the subscriber listens on, useful when started on port 0.
+func (s *Subscriber) Addr() string {
+	if s.listener == nil {
+		return s.addr
 	}
-	pb.RegisterWatcherServer(s, &svc)
+	return s.listener.Addr().String()
+}
 
-	if err := s.Serve(l); err != nil {
-		log.Fatalf("[ERR] (fswatcher) server stopped with err: %v", err)
+// Stop stops the subscriber and returns error if serving failed.
+func (s *Subscriber) Stop() error {
+	if s.server == nil {
+		return nil
+	}
+	s.server.Stop()
+	err := <-s.serveErr
+	s.server = nil
+	if err != nil && err != grpc.ErrServerStopped {
+		return fmt.Errorf("server stopped with err: %v", err)
 	}
+	return nil
 }
 
 type subService struct {
-	handler func(event *pb.EventRequest)
+	handler Handler
+
+	mtx sync.Mutex
+	// publishers keeps Watch stream events order per publisher id.
+	publishers map[string]*sequence
+}
+
+// sequence reorders events of a single publisher.
+type sequence struct {
+	next    uint64
+	pending map[uint64]*pb.EventRequest
+}
+
+func newSubService(handler Handler) *subService {
+	return &subService{
+		handler:    handler,
+		publishers: make(map[string]*sequence),
+	}
 }
 
 func (ss *subService) Event(ctx context.Context, req *pb.EventRequest) (*empty.Empty, error) {
@@ -37,3 +140,67 @@ func (ss *subService) Event(ctx context.Context, req *pb.EventRequest) (*empty.E
 	}
 	return &empty.Empty{}, nil
 }
+
+func (ss *subService) Watch(stream pb.Watcher_WatchServer) error {
+	for {
+		batch, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := stream.Send(&pb.Ack{Seq: ss.deliver(batch)}); err != nil {
+			return err
+		}
+	}
+}
+
+// deliver passes batch events to the handler in seq order, dropping duplicates and
+// holding events received after a gap. The highest seq up to which all events
+// were delivered is returned.
+func (ss *subService) deliver(batch *pb.EventBatch) uint64 {
+	events, ack := ss.readyEvents(batch)
+	if ss.handler != nil {
+		for _, e := range events {
+			ss.handler(e)
+		}
+	}
+	return ack
+}
+
+// readyEvents returns batch events ready to be delivered in seq order, the handler
+// isn't called under the lock, so slow handler doesn't block other publishers.
+func (ss *subService) readyEvents(batch *pb.EventBatch) ([]*pb.EventRequest, uint64) {
+	ss.mtx.Lock()
+	defer ss.mtx.Unlock()
+
+	seq, ok := ss.publishers[batch.GetPublisherId()]
+	if !ok {
+		seq = &sequence{
+			next:    1,
+			pending: make(map[uint64]*pb.EventRequest),
+		}
+		ss.publishers[batch.GetPublisherId()] = seq
+	}
+
+	for _, e := range batch.GetEvents() {
+		if e.GetSeq() >= seq.next {
+			seq.pending[e.GetSeq()] = e
+		}
+	}
+	var ready []*pb.EventRequest
+	for {
+		e, ok := seq.pending[seq.next]
+		if !ok {
+			break
+		}
+		delete(seq.pending, seq.next)
+		seq.next++
+		ready = append(ready, e)
+	}
+	if len(seq.pending) != 0 {
+		log.Printf("[WARN] (fswatcher) missing event %d of %q publisher", seq.next, batch.GetPublisherId())
+	}
+	return ready, seq.next - 1
+}
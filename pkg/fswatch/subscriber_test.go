@@ -18,11 +18,13 @@ func TestDirWatcher(t *testing.T) {
 	}
 
 	event := make(chan *pb.EventRequest)
-	go func() {
-		Subscriber("localhost:11132", func(req *pb.EventRequest) {
-			event <- req
-		})
-	}()
+	sub := NewSubscriber("localhost:11132", func(req *pb.EventRequest) {
+		event <- req
+	})
+	if err := sub.Start(); err != nil {
+		t.Fatalf("failed to start subscriber: %v", err)
+	}
+	defer sub.Stop()
 	go func() {
 		Monitor("localhost:11132", tmpDir)
 	}()
@@ -65,3 +67,59 @@ func TestDirWatcher(t *testing.T) {
 		t.Fatalf("file event was not received")
 	}
 }
+
+func TestSubscriber(t *testing.T) {
+	first, second := make(chan *pb.EventRequest, 1), make(chan *pb.EventRequest, 1)
+	sub := NewSubscriber("localhost:0", func(req *pb.EventRequest) {
+		first <- req
+	})
+	remove := sub.AddHandler(func(req *pb.EventRequest) {
+		second <- req
+	})
+	if err := sub.Start(); err != nil {
+		t.Fatalf("failed to start subscriber: %v", err)
+	}
+
+	other := NewSubscriber(sub.Addr())
+	if err := other.Start(); err == nil {
+		other.Stop()
+		t.Fatalf("expected error when addr is already in use")
+	}
+
+	client, err := newWatcherClient(sub.Addr())
+	if err != nil {
+		t.Fatalf("failed to create client: %v", err)
+	}
+	pub := &ActionHandler{Client: client}
+	if err := pub.OnFileDeleted("file"); err != nil {
+		t.Fatalf("failed to publish event: %v", err)
+	}
+	for _, c := range []chan *pb.EventRequest{first, second} {
+		if got := <-c; got.GetPath() != "file" {
+			t.Fatalf("unexpected event: %+v", got)
+		}
+	}
+
+	remove()
+	if err := pub.OnFileDeleted("file2"); err != nil {
+		t.Fatalf("failed to publish event: %v", err)
+	}
+	if got := <-first; got.GetPath() != "file2" {
+		t.Fatalf("unexpected event: %+v", got)
+	}
+	select {
+	case got := <-second:
+		t.Fatalf("removed handler got event: %+v", got)
+	default:
+	}
+
+	if err := sub.Stop(); err != nil {
+		t.Fatalf("failed to stop subscriber: %v", err)
+	}
+	if err := sub.Start(); err != nil {
+		t.Fatalf("failed to restart subscriber: %v", err)
+	}
+	if err := sub.Stop(); err != nil {
+		t.Fatalf("failed to stop subscriber: %v", err)
+	}
+}
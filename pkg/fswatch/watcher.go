@@ -3,10 +3,21 @@ package fswatch
 import (
 	"fmt"
 	"log"
+	"os"
+	"path/filepath"
+	"time"
 
 	"github.com/fsnotify/fsnotify"
 )
 
+const (
+	// settleInterval is how often pending files are checked for write completion.
+	settleInterval = time.Millisecond * 50
+	// settleTime is how long file size and modification time need to be stable
+	// to consider file write completed.
+	settleTime = time.Millisecond * 200
+)
+
 func New(dir string, eventHanlder EventHandler) *Watcher {
 	return &Watcher{
 		Dir:          dir,
@@ -16,19 +27,58 @@ func New(dir string, eventHanlder EventHandler) *Watcher {
 }
 
 type EventHandler interface {
+	// OnFileCreated is called once new file write is completed.
 	OnFileCreated(path string) error
+	// OnFileModified is called once existing file write is completed.
+	OnFileModified(path string) error
 	OnFileDeleted(path string) error
+	// OnFileRenamed is called with the old file path, new path is reported by OnFileCreated.
+	OnFileRenamed(path string) error
+	OnFileModeChanged(path string) error
 }
 
-// Watcher watches the Dir and sends notification about file system changes events to EventHandler.
+// Watcher recursively watches the Dir and sends notification about file system changes events to EventHandler.
+// EventHandler errors are logged and don't stop the watcher.
 type Watcher struct {
 	Dir          string
 	EventHandler EventHandler
 	stop         chan struct{}
+
+	watcher *fsnotify.Watcher
+	pending map[string]*pendingFile
+}
+
+// pendingFile is a file that is being written and wasn't reported yet.
+type pendingFile struct {
+	created   bool
+	size      int64
+	modTime   time.Time
+	changedAt time.Time
 }
 
 // Run starts dir notification watcher and forwards events to EventHandler.
 func (w *Watcher) Run() error {
+	if err := w.init(); err != nil {
+		return err
+	}
+	return w.loop()
+}
+
+// Start adds watches to the Dir and forwards events to EventHandler in the background,
+// changes made after Start returns are guaranteed to be reported.
+func (w *Watcher) Start() error {
+	if err := w.init(); err != nil {
+		return err
+	}
+	go func() {
+		if err := w.loop(); err != nil {
+			log.Printf("[ERR] watcher run: %v", err)
+		}
+	}()
+	return nil
+}
+
+func (w *Watcher) init() error {
 	if w.EventHandler == nil {
 		return fmt.Errorf("EventHandler can't be nil")
 	}
@@ -37,23 +87,31 @@ func (w *Watcher) Run() error {
 	if err != nil {
 		return fmt.Errorf("failed to create watcher: %v", err)
 	}
-	defer watcher.Close()
-	watcher.Add(w.Dir)
+	w.watcher = watcher
+	w.pending = make(map[string]*pendingFile)
+
+	if err := w.addDir(w.Dir, false); err != nil {
+		watcher.Close()
+		return err
+	}
+	return nil
+}
+
+func (w *Watcher) loop() error {
+	defer w.watcher.Close()
+
+	ticker := time.NewTicker(settleInterval)
+	defer ticker.Stop()
 
 	for {
 		select {
-		case event := <-watcher.Events:
-			if event.Op&fsnotify.Create == fsnotify.Create {
-				if err := w.EventHandler.OnFileCreated(event.Name); err != nil {
-					return fmt.Errorf("failed to call OnFileCreated: %v", err)
-				}
-			}
-			if event.Op&fsnotify.Remove == fsnotify.Remove {
-				if err := w.EventHandler.OnFileDeleted(event.Name); err != nil {
-					return fmt.Errorf("failed to call OnFileDeleted: %v", err)
-				}
+		case event := <-w.watcher.Events:
+			if err := w.handle(event); err != nil {
+				return err
 			}
-		case err := <-watcher.Errors:
+		case <-ticker.C:
+			w.flush()
+		case err := <-w.watcher.Errors:
 			return fmt.Errorf("watcher got err: %v", err)
 		case <-w.stop:
 			log.Printf("[INFO] stopping watcher")
@@ -62,6 +120,107 @@ func (w *Watcher) Run() error {
 	}
 }
 
+// addDir watches dir and all its subdirectories. When created is set files
+// found in the dir are reported as created, since they could be written before
+// the watch was added.
+func (w *Watcher) addDir(dir string, created bool) error {
+	return filepath.Walk(dir, func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			// The dir could be removed in the meantime.
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if fi.IsDir() {
+			if err := w.watcher.Add(path); err != nil {
+				return fmt.Errorf("failed to watch %q dir: %v", path, err)
+			}
+			return nil
+		}
+		if created {
+			w.markPending(path, true)
+		}
+		return nil
+	})
+}
+
+func (w *Watcher) handle(event fsnotify.Event) error {
+	switch {
+	case event.Op&fsnotify.Create == fsnotify.Create:
+		fi, err := os.Stat(event.Name)
+		if err != nil {
+			// File was removed before it could be handled.
+			return nil
+		}
+		if fi.IsDir() {
+			return w.addDir(event.Name, true)
+		}
+		w.markPending(event.Name, true)
+	case event.Op&fsnotify.Write == fsnotify.Write:
+		w.markPending(event.Name, false)
+	case event.Op&fsnotify.Remove == fsnotify.Remove:
+		delete(w.pending, event.Name)
+		w.notify("OnFileDeleted", w.EventHandler.OnFileDeleted, event.Name)
+	case event.Op&fsnotify.Rename == fsnotify.Rename:
+		delete(w.pending, event.Name)
+		w.notify("OnFileRenamed", w.EventHandler.OnFileRenamed, event.Name)
+	case event.Op&fsnotify.Chmod == fsnotify.Chmod:
+		// Mode change of a file that is still written is reported with its creation.
+		if _, ok := w.pending[event.Name]; ok {
+			return nil
+		}
+		w.notify("OnFileModeChanged", w.EventHandler.OnFileModeChanged, event.Name)
+	}
+	return nil
+}
+
+func (w *Watcher) markPending(path string, created bool) {
+	if p, ok := w.pending[path]; ok {
+		p.changedAt = time.Now()
+		return
+	}
+	w.pending[path] = &pendingFile{
+		created:   created,
+		size:      -1,
+		changedAt: time.Now(),
+	}
+}
+
+// notify calls the handler, its error is logged so the following events are still reported.
+func (w *Watcher) notify(name string, handler func(path string) error, path string) {
+	if err := handler(path); err != nil {
+		log.Printf("[ERR] failed to call %s for %q: %v", name, path, err)
+	}
+}
+
+// flush reports pending files which size and modification time didn't change
+// for the settleTime.
+func (w *Watcher) flush() {
+	now := time.Now()
+	for path, p := range w.pending {
+		fi, err := os.Stat(path)
+		if err != nil {
+			delete(w.pending, path)
+			continue
+		}
+		if fi.Size() != p.size || !fi.ModTime().Equal(p.modTime) {
+			p.size, p.modTime, p.changedAt = fi.Size(), fi.ModTime(), now
+			continue
+		}
+		if now.Sub(p.changedAt) < settleTime {
+			continue
+		}
+
+		delete(w.pending, path)
+		if p.created {
+			w.notify("OnFileCreated", w.EventHandler.OnFileCreated, path)
+			continue
+		}
+		w.notify("OnFileModified", w.EventHandler.OnFileModified, path)
+	}
+}
+
 // Stop the watcher.
 func (w *Watcher) Stop() {
 	close(w.stop)
@@ -0,0 +1,169 @@
+package fswatch
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type recordedEvent struct {
+	action string
+	path   string
+	size   int64
+}
+
+type recorder chan recordedEvent
+
+func (r recorder) record(action, path string) error {
+	var size int64 = -1
+	if fi, err := os.Stat(path); err == nil {
+		size = fi.Size()
+	}
+	r <- recordedEvent{action: action, path: path, size: size}
+	return nil
+}
+
+func (r recorder) OnFileCreated(path string) error     { return r.record("created", path) }
+func (r recorder) OnFileModified(path string) error    { return r.record("modified", path) }
+func (r recorder) OnFileDeleted(path string) error     { return r.record("deleted", path) }
+func (r recorder) OnFileRenamed(path string) error     { return r.record("renamed", path) }
+func (r recorder) OnFileModeChanged(path string) error { return r.record("mode", path) }
+
+func (r recorder) expect(t *testing.T, action, path string) recordedEvent {
+	t.Helper()
+	select {
+	case got := <-r:
+		if got.action != action || got.path != path {
+			t.Fatalf("got %s %s, expected %s %s", got.action, got.path, action, path)
+		}
+		return got
+	case <-time.After(time.Second * 2):
+		t.Fatalf("%s %s event was not received", action, path)
+	}
+	return recordedEvent{}
+}
+
+func (r recorder) expectNone(t *testing.T) {
+	t.Helper()
+	select {
+	case got := <-r:
+		t.Fatalf("unexpected event %s %s", got.action, got.path)
+	case <-time.After(settleTime * 2):
+	}
+}
+
+// failingHandler records events and fails to handle them.
+type failingHandler struct {
+	recorder
+}
+
+func (h failingHandler) OnFileCreated(path string) error {
+	h.record("created", path)
+	return errors.New("handler failed")
+}
+
+func startWatcher(t *testing.T) (string, recorder, func()) {
+	return startWatcherWithHandler(t, func(r recorder) EventHandler { return r })
+}
+
+func startWatcherWithHandler(t *testing.T, handler func(recorder) EventHandler) (string, recorder, func()) {
+	dir, err := ioutil.TempDir("", "fswatch")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	events := make(recorder, 16)
+	w := New(dir, handler(events))
+	done := make(chan error, 1)
+	go func() {
+		done <- w.Run()
+	}()
+	time.Sleep(time.Millisecond * 100)
+	return dir, events, func() {
+		w.Stop()
+		if err := <-done; err != nil {
+			t.Errorf("watcher failed: %v", err)
+		}
+		os.RemoveAll(dir)
+	}
+}
+
+func TestWatcherRecursive(t *testing.T) {
+	dir, events, stop := startWatcher(t)
+	defer stop()
+
+	sub := filepath.Join(dir, "a", "b")
+	if err := os.MkdirAll(sub, 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	file := filepath.Join(sub, "file.txt")
+	if err := ioutil.WriteFile(file, []byte("content"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	events.expect(t, "created", file)
+
+	if err := ioutil.WriteFile(file, []byte("new content"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	events.expect(t, "modified", file)
+
+	if err := os.Chmod(file, 0600); err != nil {
+		t.Fatalf("failed to chmod file: %v", err)
+	}
+	events.expect(t, "mode", file)
+
+	renamed := filepath.Join(dir, "renamed.txt")
+	if err := os.Rename(file, renamed); err != nil {
+		t.Fatalf("failed to rename file: %v", err)
+	}
+	events.expect(t, "renamed", file)
+	events.expect(t, "created", renamed)
+
+	if err := os.Remove(renamed); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	events.expect(t, "deleted", renamed)
+}
+
+func TestWatcherWriteComplete(t *testing.T) {
+	dir, events, stop := startWatcher(t)
+	defer stop()
+
+	file := filepath.Join(dir, "large.bin")
+	f, err := os.Create(file)
+	if err != nil {
+		t.Fatalf("failed to create file: %v", err)
+	}
+	chunk := bytes.Repeat([]byte("z"), 1<<16)
+	for i := 0; i < 10; i++ {
+		if _, err := f.Write(chunk); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		time.Sleep(settleInterval)
+	}
+	f.Close()
+
+	got := events.expect(t, "created", file)
+	if want := int64(len(chunk) * 10); got.size != want {
+		t.Fatalf("file reported before write was completed, got size: %v want: %v", got.size, want)
+	}
+	events.expectNone(t)
+}
+
+func TestWatcherHandlerError(t *testing.T) {
+	dir, events, stop := startWatcherWithHandler(t, func(r recorder) EventHandler {
+		return failingHandler{r}
+	})
+	defer stop()
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(name), 0644); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+		events.expect(t, "created", file)
+	}
+}
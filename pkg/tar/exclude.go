@@ -0,0 +1,161 @@
+package tar
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/scanner"
+)
+
+// excluder matches paths with .dockerignore patterns the same way as docker build
+// does with github.com/docker/docker/pkg/fileutils. The package isn't imported, since
+// it imports github.com/Sirupsen/logrus colliding with github.com/sirupsen/logrus.
+type excluder struct {
+	// patterns are cleaned patterns, exceptions start with '!'.
+	patterns    []string
+	patternDirs [][]string
+	exceptions  bool
+}
+
+// newExcluder cleans patterns like fileutils.CleanPatterns.
+func newExcluder(patterns []string) (*excluder, error) {
+	e := &excluder{}
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if exclusion(p) {
+			if len(p) == 1 {
+				return nil, errors.New("illegal exclusion pattern: !")
+			}
+			e.exceptions = true
+		}
+		p = path.Clean(p)
+		e.patterns = append(e.patterns, p)
+		if exclusion(p) {
+			p = p[1:]
+		}
+		e.patternDirs = append(e.patternDirs, strings.Split(p, "/"))
+	}
+	return e, nil
+}
+
+func exclusion(pattern string) bool {
+	return pattern[0] == '!'
+}
+
+// excluded checks if slash separated path relative to the dir is excluded like
+// fileutils.OptimizedMatches, path is excluded also when one of its parent dirs
+// matches a pattern.
+func (e *excluder) excluded(rel string) (bool, error) {
+	var matched bool
+	parent := path.Dir(rel)
+	parentDirs := strings.Split(parent, "/")
+	for i, pattern := range e.patterns {
+		negative := exclusion(pattern)
+		if negative {
+			pattern = pattern[1:]
+		}
+		match, err := regexpMatch(pattern, rel)
+		if err != nil {
+			return false, fmt.Errorf("error in pattern (%s): %v", pattern, err)
+		}
+		if !match && parent != "." && len(e.patternDirs[i]) <= len(parentDirs) {
+			match, _ = regexpMatch(strings.Join(e.patternDirs[i], "/"),
+				strings.Join(parentDirs[:len(e.patternDirs[i])], "/"))
+		}
+		if match {
+			matched = !negative
+		}
+	}
+	return matched, nil
+}
+
+// skipDir checks if excluded dir can be skipped, it can't when one of the exceptions
+// could match a path inside the dir.
+func (e *excluder) skipDir(rel string) bool {
+	if !e.exceptions {
+		return true
+	}
+	dirs := strings.Split(rel, "/")
+	for i, pattern := range e.patterns {
+		if exclusion(pattern) && matchesInside(e.patternDirs[i], dirs) {
+			return false
+		}
+	}
+	return true
+}
+
+// matchesInside checks if pattern split into path segments could match a path inside the dir.
+func matchesInside(patternDirs, dirs []string) bool {
+	for i, dir := range dirs {
+		if strings.Contains(patternDirs[i], "**") {
+			return true
+		}
+		if i == len(patternDirs)-1 {
+			// Pattern ends at the dir depth.
+			return false
+		}
+		if ok, _ := path.Match(patternDirs[i], dir); !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// regexpMatch is fileutils.regexpMatch for slash separated paths. It matches like
+// filepath.Match except that "**" matches any number of dirs.
+func regexpMatch(pattern, p string) (bool, error) {
+	if _, err := path.Match(pattern, p); err != nil {
+		return false, err
+	}
+
+	regStr := "^"
+	var scan scanner.Scanner
+	scan.Init(strings.NewReader(pattern))
+	for scan.Peek() != scanner.EOF {
+		ch := scan.Next()
+		switch {
+		case ch == '*':
+			if scan.Peek() != '*' {
+				// "*" matches anything but "/".
+				regStr += "[^/]*"
+				continue
+			}
+			scan.Next()
+			if scan.Peek() == scanner.EOF {
+				// "**" at the end matches everything like in .gitignore.
+				regStr += ".*"
+			} else {
+				regStr += "((.*/)|([^/]*))"
+			}
+			// "**/" is treated as "**".
+			if scan.Peek() == '/' {
+				scan.Next()
+			}
+		case ch == '?':
+			regStr += "[^/]"
+		case ch == '.' || ch == '$':
+			regStr += `\` + string(ch)
+		case ch == '\\':
+			if scan.Peek() != scanner.EOF {
+				regStr += `\` + string(scan.Next())
+			} else {
+				regStr += `\`
+			}
+		default:
+			regStr += string(ch)
+		}
+	}
+	regStr += "$"
+
+	res, err := regexp.MatchString(regStr, p)
+	if err != nil {
+		err = filepath.ErrBadPattern
+	}
+	return res, err
+}
@@ -9,8 +9,9 @@ import (
 	"path/filepath"
 )
 
-// DirReader tar input dir and returns the io.Reader to dir content.
-func DirReader(dir string) (io.Reader, error) {
+// DirReader tar input dir and returns the io.Reader to dir content. Files matching
+// .dockerignore style excludes patterns relative to the dir are skipped.
+func DirReader(dir string, excludes ...string) (io.Reader, error) {
 	if _, err := os.Stat(dir); err != nil {
 		return nil, err
 	}
@@ -19,32 +20,70 @@ func DirReader(dir string) (io.Reader, error) {
 	if _, err := os.Stat(dir); err != nil {
 		return nil, fmt.Errorf("failed to stat file: %v", err)
 	}
+	e, err := newExcluder(excludes)
+	if err != nil {
+		return nil, fmt.Errorf("invalid excludes: %v", err)
+	}
 	tw := tar.NewWriter(&buff)
 	defer tw.Close()
 
-	if err := filepath.Walk(dir, tarWalkFn(tw)); err != nil {
+	if err := filepath.Walk(dir, tarWalkFn(tw, dir, e)); err != nil {
 		return nil, err
 	}
 
 	return &buff, nil
 }
 
-func tarWalkFn(w *tar.Writer) filepath.WalkFunc {
+// tarWalkFn writes walked files into the tar with paths relative to the dir.
+func tarWalkFn(w *tar.Writer, dir string, e *excluder) filepath.WalkFunc {
 	return func(file string, fi os.FileInfo, err error) error {
-		header, err := tar.FileInfoHeader(fi, file)
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		if rel == "." {
+			return nil
+		}
+		rel = filepath.ToSlash(rel)
+		excluded, err := e.excluded(rel)
+		if err != nil {
+			return err
+		}
+		if excluded {
+			if fi.IsDir() && e.skipDir(rel) {
+				return filepath.SkipDir
+			}
+			// Excluded dir is still walked, since exception can include its files.
+			return nil
+		}
+		var link string
+		if fi.Mode()&os.ModeSymlink != 0 {
+			if link, err = os.Readlink(file); err != nil {
+				return fmt.Errorf("failed to read link '%v': %v", file, err)
+			}
+		}
+		header, err := tar.FileInfoHeader(fi, link)
 		if err != nil {
 			return fmt.Errorf("failed to get file header: %v", err)
 		}
+		header.Name = rel
 		if err := w.WriteHeader(header); err != nil {
 			return fmt.Errorf("failed to write file header: %v", err)
 		}
 		if fi.IsDir() {
 			return nil
 		}
+		if !fi.Mode().IsRegular() {
+			return nil
+		}
 		data, err := os.Open(file)
 		if err != nil {
 			return fmt.Errorf("failed read file '%v': %v", file, err)
 		}
+		defer data.Close()
 		if _, err := io.Copy(w, data); err != nil {
 			return fmt.Errorf("failed to copy file content to buff: %v", err)
 		}
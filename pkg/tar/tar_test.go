@@ -0,0 +1,144 @@
+package tar
+
+import (
+	"archive/tar"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDirReader(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tar")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "cmd"), 0777); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	files := map[string]string{
+		"Dockerfile":  "FROM scratch",
+		"cmd/main.go": "package main",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0666); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+
+	r, err := DirReader(dir)
+	if err != nil {
+		t.Fatalf("failed to tar dir: %v", err)
+	}
+	got := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read tar: %v", err)
+		}
+		if h.Typeflag == tar.TypeDir {
+			continue
+		}
+		buff, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatalf("failed to read %q file: %v", h.Name, err)
+		}
+		got[h.Name] = string(buff)
+	}
+	if !reflect.DeepEqual(got, files) {
+		t.Fatalf("got %v, want %v", got, files)
+	}
+}
+
+func TestDirReaderExcludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "tar")
+	if err != nil {
+		t.Fatalf("failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	for _, name := range []string{
+		"Dockerfile",
+		"main.go",
+		"main_test.go",
+		".git/config",
+		"docs/a.md",
+		"docs/keep.md",
+		"testdata/sub/b.txt",
+	} {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			t.Fatalf("failed to create dir: %v", err)
+		}
+		if err := ioutil.WriteFile(path, []byte(name), 0666); err != nil {
+			t.Fatalf("failed to write file: %v", err)
+		}
+	}
+	if err := os.Symlink("main.go", filepath.Join(dir, "link.go")); err != nil {
+		t.Fatalf("failed to create symlink: %v", err)
+	}
+
+	r, err := DirReader(dir, ".git", "*_test.go", "docs", "!docs/keep.md", "**/*.txt")
+	if err != nil {
+		t.Fatalf("failed to tar dir: %v", err)
+	}
+	got := make(map[string]string)
+	tr := tar.NewReader(r)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatalf("failed to read tar: %v", err)
+		}
+		switch h.Typeflag {
+		case tar.TypeDir:
+		case tar.TypeSymlink:
+			got[h.Name] = "-> " + h.Linkname
+		default:
+			got[h.Name] = h.Name
+		}
+	}
+	want := map[string]string{
+		"Dockerfile":   "Dockerfile",
+		"main.go":      "main.go",
+		"docs/keep.md": "docs/keep.md",
+		"link.go":      "-> main.go",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestExcluderSkipDir(t *testing.T) {
+	e, err := newExcluder([]string{"vendor", "docs", "build", "!Dockerfile", "!.dockerignore", "!docs/keep.md", "!build/*/out.bin"})
+	if err != nil {
+		t.Fatalf("failed to create excluder: %v", err)
+	}
+	for dir, want := range map[string]bool{
+		"vendor":    true,
+		"docs":      false,
+		"build":     false,
+		"build/x":   false,
+		"build/x/y": true,
+	} {
+		if got := e.skipDir(dir); got != want {
+			t.Fatalf("%q dir skip mismatch, got: %v want: %v", dir, got, want)
+		}
+	}
+
+	e, err = newExcluder([]string{"vendor", "!**/LICENSE"})
+	if err != nil {
+		t.Fatalf("failed to create excluder: %v", err)
+	}
+	if e.skipDir("vendor") {
+		t.Fatalf("dir matched by ** exception was skipped")
+	}
+}
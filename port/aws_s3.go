@@ -0,0 +1,248 @@
+package port
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/pkg/errors"
+	"github.com/smallinsky/mtf/fake/fakes3"
+)
+
+func newS3Port() *S3Storage {
+	return &S3Storage{
+		exchanger: newExchanger("s3", s3ErrorResponse, func(code int, msg string) error {
+			return &fakes3.Error{
+				Code:    code,
+				Message: msg,
+			}
+		}),
+	}
+}
+
+// NewS3Port creates S3 port where each SUT call needs to be received and answered by the test.
+// Multipart uploads are assembled by the port and received as a single S3PutObjectRequest.
+func NewS3Port(opts ...Opt) (*Port, error) {
+	startHTTP()
+	ht.s3.setOptions(opts...)
+	return &Port{
+		impl: ht.s3,
+	}, nil
+}
+
+type S3Storage struct {
+	*exchanger
+}
+
+func (s *S3Storage) setOptions(opts ...Opt) {
+	options := defaultPortOpts
+	for _, o := range opts {
+		o(&options)
+	}
+	s.setTimeout(options.timeout)
+}
+
+func (s *S3Storage) onPutObject(bo fakes3.BucketObject, r io.Reader) error {
+	buff, err := ioutil.ReadAll(r)
+	if err != nil {
+		return errors.Wrapf(err, "failed to read object content")
+	}
+	msg, err := s.exchange(&S3PutObjectRequest{
+		Bucket:  bo.Bucket,
+		Key:     bo.Key,
+		Content: buff,
+	})
+	if err != nil {
+		return err
+	}
+	if _, ok := msg.(*S3PutObjectResponse); !ok {
+		return s.fail(http.StatusInternalServerError, "expected *S3PutObjectResponse but got %T", msg)
+	}
+	return nil
+}
+
+func (s *S3Storage) onGetObject(bo fakes3.BucketObject, w io.Writer) error {
+	msg, err := s.exchange(&S3GetObjectRequest{
+		Bucket: bo.Bucket,
+		Key:    bo.Key,
+	})
+	if err != nil {
+		return err
+	}
+	resp, ok := msg.(*S3GetObjectResponse)
+	if !ok {
+		return s.fail(http.StatusInternalServerError, "expected *S3GetObjectResponse but got %T", msg)
+	}
+	_, err = io.Copy(w, bytes.NewReader(resp.Content))
+	return err
+}
+
+func (s *S3Storage) onHeadObject(bo fakes3.BucketObject) (*fakes3.ObjectAttrs, error) {
+	msg, err := s.exchange(&S3HeadObjectRequest{
+		Bucket: bo.Bucket,
+		Key:    bo.Key,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, ok := msg.(*S3HeadObjectResponse)
+	if !ok {
+		return nil, s.fail(http.StatusInternalServerError, "expected *S3HeadObjectResponse but got %T", msg)
+	}
+	attrs := resp.Attrs.toFake(bo.Bucket)
+	if attrs.Key == "" {
+		attrs.Key = bo.Key
+	}
+	return &attrs, nil
+}
+
+func (s *S3Storage) onListObjects(q fakes3.ListQuery) ([]fakes3.ObjectAttrs, error) {
+	msg, err := s.exchange(&S3ListObjectsRequest{
+		Bucket:    q.Bucket,
+		Prefix:    q.Prefix,
+		Delimiter: q.Delimiter,
+	})
+	if err != nil {
+		return nil, err
+	}
+	resp, ok := msg.(*S3ListObjectsResponse)
+	if !ok {
+		return nil, s.fail(http.StatusInternalServerError, "expected *S3ListObjectsResponse but got %T", msg)
+	}
+	var out []fakes3.ObjectAttrs
+	for _, o := range resp.Objects {
+		out = append(out, o.toFake(q.Bucket))
+	}
+	return out, nil
+}
+
+func (s *S3Storage) onDeleteObject(bo fakes3.BucketObject) error {
+	msg, err := s.exchange(&S3DeleteObjectRequest{
+		Bucket: bo.Bucket,
+		Key:    bo.Key,
+	})
+	if err != nil {
+		return err
+	}
+	if _, ok := msg.(*S3DeleteObjectResponse); !ok {
+		return s.fail(http.StatusInternalServerError, "expected *S3DeleteObjectResponse but got %T", msg)
+	}
+	return nil
+}
+
+func (s *S3Storage) registerRouter(r *mux.Router) {
+	fs3 := &fakes3.S3{
+		OnPutObject:    s.onPutObject,
+		OnGetObject:    s.onGetObject,
+		OnHeadObject:   s.onHeadObject,
+		OnListObjects:  s.onListObjects,
+		OnDeleteObject: s.onDeleteObject,
+	}
+	fs3.AddMuxRoute(r)
+}
+
+type S3PutObjectRequest struct {
+	Bucket  string
+	Key     string
+	Content []byte
+}
+
+type S3PutObjectResponse struct {
+}
+
+type S3GetObjectRequest struct {
+	Bucket string
+	Key    string
+}
+
+type S3GetObjectResponse struct {
+	Content []byte
+}
+
+type S3HeadObjectRequest struct {
+	Bucket string
+	Key    string
+}
+
+type S3HeadObjectResponse struct {
+	Attrs S3ObjectAttrs
+}
+
+type S3ListObjectsRequest struct {
+	Bucket    string
+	Prefix    string
+	Delimiter string
+}
+
+// S3ListObjectsResponse contains all bucket objects, prefix, delimiter and paging
+// are applied by the port.
+type S3ListObjectsResponse struct {
+	Objects []S3ObjectAttrs
+}
+
+type S3DeleteObjectRequest struct {
+	Bucket string
+	Key    string
+}
+
+type S3DeleteObjectResponse struct {
+}
+
+// S3ErrorResponse can be sent in response to any S3 request to make
+// SUT call fail with given HTTP status code and S3 error.
+type S3ErrorResponse struct {
+	Code    int
+	Message string
+	// S3Code is S3 error code e.g. "NoSuchKey", derived from Code when empty.
+	S3Code string
+}
+
+func (e *S3ErrorResponse) toFake() error {
+	return &fakes3.Error{
+		Code:    e.Code,
+		Message: e.Message,
+		S3Code:  e.S3Code,
+	}
+}
+
+// s3ErrorResponse converts S3ErrorResponse sent by the test to error returned to SUT.
+func s3ErrorResponse(msg interface{}) error {
+	if e, ok := msg.(*S3ErrorResponse); ok {
+		return e.toFake()
+	}
+	return nil
+}
+
+// S3ObjectAttrs describes object metadata returned to SUT.
+type S3ObjectAttrs struct {
+	Key          string
+	ContentType  string
+	Size         int64
+	ETag         string
+	Metadata     map[string]string
+	LastModified time.Time
+}
+
+func (a S3ObjectAttrs) toFake(bucket string) fakes3.ObjectAttrs {
+	return fakes3.ObjectAttrs{
+		Bucket:       bucket,
+		Key:          a.Key,
+		ContentType:  a.ContentType,
+		Size:         a.Size,
+		ETag:         a.ETag,
+		Metadata:     a.Metadata,
+		LastModified: a.LastModified,
+	}
+}
+
+func (s *S3Storage) Send(ctx context.Context, i interface{}) error {
+	return s.send(i)
+}
+
+func (s *S3Storage) Receive(ctx context.Context) (interface{}, error) {
+	return s.receive()
+}
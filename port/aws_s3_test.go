@@ -0,0 +1,73 @@
+package port
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/mux"
+)
+
+func TestS3Storage(t *testing.T) {
+	port := newS3Port()
+	r := mux.NewRouter()
+	port.registerRouter(r)
+	cst := httptest.NewServer(r)
+	defer cst.Close()
+
+	get := func() (int, string) {
+		req, err := http.NewRequest(http.MethodGet, cst.URL+"/dir/file.txt", nil)
+		if err != nil {
+			t.Fatalf("failed to create request: %v", err)
+		}
+		req.Host = "bucket.s3.amazonaws.com"
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		defer resp.Body.Close()
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+
+	type result struct {
+		code int
+		body string
+	}
+	results := make(chan result, 1)
+
+	go func() {
+		code, body := get()
+		results <- result{code, body}
+	}()
+	msg, err := port.receive()
+	if err != nil {
+		t.Fatalf("failed to receive message: %v", err)
+	}
+	if want := (&S3GetObjectRequest{Bucket: "bucket", Key: "dir/file.txt"}); !reflect.DeepEqual(msg, want) {
+		t.Fatalf("request mismatch, got: %+v want: %+v", msg, want)
+	}
+	if err := port.send(&S3GetObjectResponse{Content: []byte("content")}); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	if got := <-results; got.code != http.StatusOK || got.body != "content" {
+		t.Fatalf("unexpected response: %+v", got)
+	}
+
+	go func() {
+		code, body := get()
+		results <- result{code, body}
+	}()
+	if _, err := port.receive(); err != nil {
+		t.Fatalf("failed to receive message: %v", err)
+	}
+	if err := port.send(&S3ErrorResponse{Code: http.StatusNotFound}); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	if got := <-results; got.code != http.StatusNotFound || !strings.Contains(got.body, "<Code>NoSuchKey</Code>") {
+		t.Fatalf("unexpected response: %+v", got)
+	}
+}
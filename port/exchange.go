@@ -0,0 +1,101 @@
+package port
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+	mtfctx "github.com/smallinsky/mtf/framework/context"
+)
+
+// exchanger passes SUT calls served by the fake HTTP handlers to the test and
+// the test responses back to SUT.
+type exchanger struct {
+	name     string
+	inEvent  chan interface{}
+	outEvent chan interface{}
+	// toError converts the test error response to error returned to SUT,
+	// it returns nil for other responses.
+	toError func(msg interface{}) error
+	// newError creates error returned to SUT when the exchange fails.
+	newError func(code int, msg string) error
+
+	mtx     sync.Mutex
+	timeout time.Duration
+}
+
+func newExchanger(name string, toError func(interface{}) error, newError func(int, string) error) *exchanger {
+	return &exchanger{
+		name:     name,
+		inEvent:  make(chan interface{}),
+		outEvent: make(chan interface{}),
+		toError:  toError,
+		newError: newError,
+		timeout:  defaultPortOpts.timeout,
+	}
+}
+
+func (e *exchanger) setTimeout(timeout time.Duration) {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	e.timeout = timeout
+}
+
+func (e *exchanger) getTimeout() time.Duration {
+	e.mtx.Lock()
+	defer e.mtx.Unlock()
+	return e.timeout
+}
+
+// exchange passes SUT request to the test and waits for the test response.
+func (e *exchanger) exchange(req interface{}) (interface{}, error) {
+	timeout := e.getTimeout()
+	select {
+	case e.inEvent <- req:
+	case <-time.After(timeout):
+		return nil, e.fail(http.StatusGatewayTimeout, "%T was not received by the test within %v", req, timeout)
+	}
+
+	select {
+	case msg := <-e.outEvent:
+		if err := e.toError(msg); err != nil {
+			return nil, err
+		}
+		return msg, nil
+	case <-time.After(timeout):
+		return nil, e.fail(http.StatusGatewayTimeout, "response for %T was not sent by the test within %v", req, timeout)
+	}
+}
+
+// fail reports port failure in the current test and returns error that
+// is passed back to SUT.
+func (e *exchanger) fail(code int, format string, args ...interface{}) error {
+	msg := fmt.Sprintf(e.name+" port: "+format, args...)
+	if c := mtfctx.Current(); c != nil {
+		c.Fail(msg)
+	} else {
+		log.Printf("[ERR] %s", msg)
+	}
+	return e.newError(code, msg)
+}
+
+func (e *exchanger) receive() (interface{}, error) {
+	select {
+	case <-time.After(e.getTimeout()):
+		return nil, errors.Errorf("failed to receive message, deadline exceeded")
+	case msg := <-e.inEvent:
+		return msg, nil
+	}
+}
+
+func (e *exchanger) send(msg interface{}) error {
+	select {
+	case e.outEvent <- msg:
+		return nil
+	case <-time.After(e.getTimeout()):
+		return errors.Errorf("failed to send message, deadline exceeded")
+	}
+}
@@ -0,0 +1,222 @@
+package port
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/smallinsky/mtf/pkg/fswatch"
+)
+
+// FSAction describes file change observed in the FS port dir.
+type FSAction int
+
+const (
+	FSCreated FSAction = iota
+	FSModified
+	// FSDeleted is also received when file is moved out of its path.
+	FSDeleted
+)
+
+func (a FSAction) String() string {
+	switch a {
+	case FSCreated:
+		return "created"
+	case FSModified:
+		return "modified"
+	case FSDeleted:
+		return "deleted"
+	}
+	return fmt.Sprintf("FSAction(%d)", int(a))
+}
+
+// FSEvent is received when SUT changes a file in the FS port dir, Path is relative
+// to the dir and Payload contains the whole file once the write is completed.
+// Sending FSEvent with FSCreated or FSModified action writes the Payload to the file,
+// FSDeleted removes it. Changes made by the test are not received back.
+type FSEvent struct {
+	Path    string
+	Action  FSAction
+	Payload []byte
+}
+
+// NewFSPort creates port watching the host dir that can be mounted into SUT
+// container with framework.SutSettings Mounts. The dir is created if it doesn't exist,
+// Port Close stops watching it.
+func NewFSPort(dir string, opts ...Opt) (*Port, error) {
+	p, err := NewFS(dir, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Port{
+		impl: p,
+	}, nil
+}
+
+func NewFS(dir string, opts ...Opt) (*FSPort, error) {
+	options := defaultPortOpts
+	for _, o := range opts {
+		o(&options)
+	}
+
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(dir, 0777); err != nil {
+		return nil, fmt.Errorf("failed to create %q dir: %v", dir, err)
+	}
+
+	p := &FSPort{
+		dir:     dir,
+		timeout: options.timeout,
+		eventC:  make(chan *FSEvent, 64),
+		sent:    make(map[string]*FSEvent),
+	}
+	p.watcher = fswatch.New(dir, p)
+	if err := p.watcher.Start(); err != nil {
+		return nil, fmt.Errorf("failed to watch %q dir: %v", dir, err)
+	}
+	return p, nil
+}
+
+type FSPort struct {
+	dir     string
+	timeout time.Duration
+	watcher *fswatch.Watcher
+	eventC  chan *FSEvent
+
+	mtx sync.Mutex
+	// sent keeps changes made by the test, so they are not received back as SUT changes.
+	sent map[string]*FSEvent
+}
+
+// Close stops watching the port dir.
+func (p *FSPort) Close() {
+	p.watcher.Stop()
+}
+
+func (p *FSPort) Send(ctx context.Context, i interface{}) error {
+	msg, ok := i.(*FSEvent)
+	if !ok {
+		return fmt.Errorf("FSPort send doesn't support %T type", i)
+	}
+	path, err := p.path(msg.Path)
+	if err != nil {
+		return err
+	}
+
+	p.mtx.Lock()
+	p.sent[path] = msg
+	p.mtx.Unlock()
+
+	switch msg.Action {
+	case FSCreated, FSModified:
+		if err := os.MkdirAll(filepath.Dir(path), 0777); err != nil {
+			return fmt.Errorf("failed to create %q dir: %v", filepath.Dir(path), err)
+		}
+		if err := ioutil.WriteFile(path, msg.Payload, 0666); err != nil {
+			return fmt.Errorf("failed to write %q file: %v", msg.Path, err)
+		}
+	case FSDeleted:
+		if err := os.Remove(path); err != nil {
+			return fmt.Errorf("failed to remove %q file: %v", msg.Path, err)
+		}
+	default:
+		return fmt.Errorf("unsupported %v action", msg.Action)
+	}
+	return nil
+}
+
+func (p *FSPort) Receive(ctx context.Context) (interface{}, error) {
+	select {
+	case msg := <-p.eventC:
+		return msg, nil
+	case <-time.After(p.timeout):
+		return nil, errors.Errorf("failed to receive message, deadline exceeded")
+	}
+}
+
+// path returns absolute path of the file making sure it is placed in the port dir.
+func (p *FSPort) path(rel string) (string, error) {
+	path := filepath.Join(p.dir, rel)
+	if path != p.dir && !strings.HasPrefix(path, p.dir+string(filepath.Separator)) {
+		return "", fmt.Errorf("path %q is outside of the %q dir", rel, p.dir)
+	}
+	return path, nil
+}
+
+func (p *FSPort) OnFileCreated(path string) error {
+	return p.onFileWritten(path, FSCreated)
+}
+
+func (p *FSPort) OnFileModified(path string) error {
+	return p.onFileWritten(path, FSModified)
+}
+
+func (p *FSPort) OnFileDeleted(path string) error {
+	return p.push(&FSEvent{Action: FSDeleted}, path)
+}
+
+func (p *FSPort) OnFileRenamed(path string) error {
+	return p.push(&FSEvent{Action: FSDeleted}, path)
+}
+
+func (p *FSPort) OnFileModeChanged(path string) error {
+	return nil
+}
+
+func (p *FSPort) onFileWritten(path string, action FSAction) error {
+	buff, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		// File was removed before it was reported, removal event will follow.
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read file %q: %v", path, err)
+	}
+	return p.push(&FSEvent{Action: action, Payload: buff}, path)
+}
+
+func (p *FSPort) push(event *FSEvent, path string) error {
+	if p.sentByTest(event, path) {
+		return nil
+	}
+	rel, err := filepath.Rel(p.dir, path)
+	if err != nil {
+		return err
+	}
+	event.Path = filepath.ToSlash(rel)
+	// Blocking handler would stall the watcher, so events are dropped instead when
+	// the test doesn't receive them.
+	select {
+	case p.eventC <- event:
+	default:
+		log.Printf("[ERR] %q file %v event dropped, too many events waiting to be received", event.Path, event.Action)
+	}
+	return nil
+}
+
+// sentByTest checks if the event is caused by the test Send call.
+func (p *FSPort) sentByTest(event *FSEvent, path string) bool {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	sent, ok := p.sent[path]
+	if !ok {
+		return false
+	}
+	delete(p.sent, path)
+	if event.Action == FSDeleted {
+		return sent.Action == FSDeleted
+	}
+	return sent.Action != FSDeleted && bytes.Equal(sent.Payload, event.Payload)
+}
@@ -0,0 +1,104 @@
+package port
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestFSPort(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsport")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	p, err := NewFS(dir, WithTimeout(time.Second*2))
+	if err != nil {
+		t.Fatalf("failed to create port: %v", err)
+	}
+	defer p.Close()
+	ctx := context.Background()
+
+	receive := func(want *FSEvent) {
+		t.Helper()
+		msg, err := p.Receive(ctx)
+		if err != nil {
+			t.Fatalf("failed to receive message: %v", err)
+		}
+		if !reflect.DeepEqual(msg, want) {
+			t.Fatalf("event mismatch, got: %+v want: %+v", msg, want)
+		}
+	}
+
+	// File written by SUT.
+	if err := os.MkdirAll(filepath.Join(dir, "reports"), 0755); err != nil {
+		t.Fatalf("failed to create dir: %v", err)
+	}
+	report := filepath.Join(dir, "reports", "report.csv")
+	if err := ioutil.WriteFile(report, []byte("a,b"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	receive(&FSEvent{Path: "reports/report.csv", Action: FSCreated, Payload: []byte("a,b")})
+
+	if err := ioutil.WriteFile(report, []byte("a,b,c"), 0644); err != nil {
+		t.Fatalf("failed to write file: %v", err)
+	}
+	receive(&FSEvent{Path: "reports/report.csv", Action: FSModified, Payload: []byte("a,b,c")})
+
+	// Files written by the test are not received back.
+	if err := p.Send(ctx, &FSEvent{Path: "input/data.txt", Action: FSCreated, Payload: []byte("data")}); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+	if b, err := ioutil.ReadFile(filepath.Join(dir, "input", "data.txt")); err != nil || string(b) != "data" {
+		t.Fatalf("file wasn't written, content: %q err: %v", b, err)
+	}
+	if err := p.Send(ctx, &FSEvent{Path: "input/data.txt", Action: FSDeleted}); err != nil {
+		t.Fatalf("failed to send message: %v", err)
+	}
+
+	if err := os.Remove(report); err != nil {
+		t.Fatalf("failed to remove file: %v", err)
+	}
+	receive(&FSEvent{Path: "reports/report.csv", Action: FSDeleted})
+
+	if err := p.Send(ctx, &FSEvent{Path: "../outside.txt", Action: FSCreated}); err == nil {
+		t.Fatalf("expected error when writing outside of the port dir")
+	}
+}
+
+func TestFSPortDropsEvents(t *testing.T) {
+	dir, err := ioutil.TempDir("", "fsport")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	port, err := NewFSPort(dir, WithTimeout(time.Millisecond*100))
+	if err != nil {
+		t.Fatalf("failed to create port: %v", err)
+	}
+	defer port.Close()
+	p := port.impl.(*FSPort)
+	p.eventC = make(chan *FSEvent, 1)
+
+	for _, name := range []string{"a.txt", "b.txt"} {
+		if err := p.OnFileDeleted(filepath.Join(dir, name)); err != nil {
+			t.Fatalf("failed to handle event: %v", err)
+		}
+	}
+	msg, err := p.Receive(context.Background())
+	if err != nil {
+		t.Fatalf("failed to receive message: %v", err)
+	}
+	if want := (&FSEvent{Path: "a.txt", Action: FSDeleted}); !reflect.DeepEqual(msg, want) {
+		t.Fatalf("event mismatch, got: %+v want: %+v", msg, want)
+	}
+	if msg, err := p.Receive(context.Background()); err == nil {
+		t.Fatalf("expected dropped event, got: %+v", msg)
+	}
+}
@@ -0,0 +1,57 @@
+package port
+
+import (
+	"sync"
+
+	"github.com/smallinsky/mtf/pkg/fswatch"
+)
+
+// defaultFSWatchAddr is an addr where fswatch publishers running in MTF components send events.
+const defaultFSWatchAddr = ":4441"
+
+var fswatchSubs = struct {
+	sync.Mutex
+	m map[string]*sharedSubscriber
+}{
+	m: make(map[string]*sharedSubscriber),
+}
+
+type sharedSubscriber struct {
+	sub  *fswatch.Subscriber
+	refs int
+}
+
+// subscribeFSWatch registers handler on the fswatch subscriber shared by all ports using
+// the same addr. Returned func unregisters the handler and stops the subscriber once
+// it isn't used by any port.
+func subscribeFSWatch(addr string, h fswatch.Handler) (func() error, error) {
+	fswatchSubs.Lock()
+	defer fswatchSubs.Unlock()
+
+	s, ok := fswatchSubs.m[addr]
+	if !ok {
+		sub := fswatch.NewSubscriber(addr)
+		if err := sub.Start(); err != nil {
+			return nil, err
+		}
+		s = &sharedSubscriber{sub: sub}
+		fswatchSubs.m[addr] = s
+	}
+	s.refs++
+	remove := s.sub.AddHandler(h)
+
+	var once sync.Once
+	return func() error {
+		var err error
+		once.Do(func() {
+			remove()
+			fswatchSubs.Lock()
+			defer fswatchSubs.Unlock()
+			if s.refs--; s.refs == 0 {
+				delete(fswatchSubs.m, addr)
+				err = s.sub.Stop()
+			}
+		})
+		return err
+	}, nil
+}
@@ -0,0 +1,61 @@
+package port
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/smallinsky/mtf/proto/fswatch"
+)
+
+func TestSubscribeFSWatch(t *testing.T) {
+	const addr = "localhost:11135"
+	first, second := make(chan *pb.EventRequest, 1), make(chan *pb.EventRequest, 1)
+
+	unsubFirst, err := subscribeFSWatch(addr, func(e *pb.EventRequest) { first <- e })
+	if err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	unsubSecond, err := subscribeFSWatch(addr, func(e *pb.EventRequest) { second <- e })
+	if err != nil {
+		t.Fatalf("failed to subscribe on shared addr: %v", err)
+	}
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	if _, err := pb.NewWatcherClient(conn).Event(ctx, &pb.EventRequest{Path: "file"}); err != nil {
+		t.Fatalf("failed to send event: %v", err)
+	}
+	for _, c := range []chan *pb.EventRequest{first, second} {
+		if got := <-c; got.GetPath() != "file" {
+			t.Fatalf("unexpected event: %+v", got)
+		}
+	}
+
+	if err := unsubFirst(); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	if _, ok := fswatchSubs.m[addr]; !ok {
+		t.Fatalf("subscriber stopped while still used")
+	}
+	if err := unsubSecond(); err != nil {
+		t.Fatalf("failed to unsubscribe: %v", err)
+	}
+	if _, ok := fswatchSubs.m[addr]; ok {
+		t.Fatalf("subscriber wasn't stopped")
+	}
+
+	// Addr is released once all ports unsubscribed.
+	unsub, err := subscribeFSWatch(addr, func(*pb.EventRequest) {})
+	if err != nil {
+		t.Fatalf("failed to subscribe again: %v", err)
+	}
+	unsub()
+}
@@ -4,23 +4,36 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"io/ioutil"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/jlaffaye/ftp"
 	"github.com/pkg/errors"
 
-	"github.com/smallinsky/mtf/pkg/fswatch"
 	pb "github.com/smallinsky/mtf/proto/fswatch"
 )
 
+const (
+	defaultFTPAddr = "localhost:21"
+	defaultFTPUser = "test"
+	defaultFTPPass = "test"
+)
+
 type FTPPort struct {
-	ftpEventC chan *pb.EventRequest
-	conn      *ftp.ServerConn
+	ftpEventC chan *FTPEvent
+	// results keeps responses of list and download requests until they are received by the test.
+	results     *resultQueue
+	conn        *ftp.ServerConn
+	unsubscribe func() error
+	closed      chan struct{}
+	closeOnce   sync.Once
+	closeErr    error
 }
 
-func NewFTPPort(addr, user, pass string) (*Port, error) {
-	p, err := NewFTP(addr, user, pass)
+func NewFTPPort(addr, user, pass string, opts ...Opt) (*Port, error) {
+	p, err := NewFTP(addr, user, pass, opts...)
 	if err != nil {
 		return nil, err
 	}
@@ -30,55 +43,236 @@ func NewFTPPort(addr, user, pass string) (*Port, error) {
 	}, nil
 }
 
-func NewFTP(addr, user, pass string) (*FTPPort, error) {
-	conn, err := dialFTP("localhost:21", "test", "test")
+// NewFTP connects to the FTP server, empty addr, user and pass are replaced
+// with the MTF FTP component defaults. File events are received on the addr set
+// by WithFSWatchAddr, which can be shared by many ports.
+//
+// The port speaks plain FTP only, FTPS component is used without TLS since it still
+// accepts plain connections. SFTP component isn't supported by the port, though its
+// file events are received by the fswatch subscriber.
+func NewFTP(addr, user, pass string, opts ...Opt) (*FTPPort, error) {
+	options := defaultPortOpts
+	for _, o := range opts {
+		o(&options)
+	}
+
+	if addr == "" {
+		addr = defaultFTPAddr
+	}
+	if user == "" {
+		user = defaultFTPUser
+	}
+	if pass == "" {
+		pass = defaultFTPPass
+	}
+	conn, err := dialFTP(addr, user, pass)
 	if err != nil {
 		return nil, fmt.Errorf("failed to dial ftp: %v", err)
 	}
 
 	ftpPort := &FTPPort{
-		ftpEventC: make(chan *pb.EventRequest),
+		ftpEventC: make(chan *FTPEvent, 64),
+		results:   newResultQueue("ftp"),
 		conn:      conn,
+		closed:    make(chan struct{}),
 	}
 
-	wg := sync.WaitGroup{}
-	wg.Add(1)
-	go func() {
-		wg.Done()
-		fswatch.Subscriber(":4441", func(event *pb.EventRequest) {
-			ftpPort.ftpEventC <- event
-		})
-	}()
-	wg.Wait()
+	ftpPort.unsubscribe, err = subscribeFSWatch(options.fswatchAddr, func(req *pb.EventRequest) {
+		event, ok := newFTPEvent(req)
+		if !ok {
+			return
+		}
+		select {
+		case ftpPort.ftpEventC <- event:
+		case <-ftpPort.closed:
+		}
+	})
+	if err != nil {
+		conn.Quit()
+		return nil, fmt.Errorf("failed to subscribe for ftp events: %v", err)
+	}
 
 	return ftpPort, nil
 }
 
+// Close stops receiving file events and closes FTP connection, subsequent calls
+// return the result of the first one.
+func (p *FTPPort) Close() error {
+	p.closeOnce.Do(func() {
+		close(p.closed)
+		if err := p.unsubscribe(); err != nil {
+			p.closeErr = err
+			return
+		}
+		p.closeErr = p.conn.Quit()
+	})
+	return p.closeErr
+}
+
+// FTPEvent is received when file on the FTP server is changed, Payload contains
+// the file content of FSCreated and FSModified events. Renamed file is received as
+// FSDeleted event of its old path. Sending FTPEvent with FSCreated or FSModified
+// action uploads the file, FSDeleted removes it.
 type FTPEvent struct {
 	Path    string
+	Action  FSAction
 	Payload []byte
 }
 
+// newFTPEvent converts fswatch event to FTPEvent, false is returned for events
+// not received by the test like file mode changes.
+func newFTPEvent(req *pb.EventRequest) (*FTPEvent, bool) {
+	event := &FTPEvent{
+		Path:    req.GetPath(),
+		Payload: req.GetContent(),
+	}
+	switch req.GetAction() {
+	case pb.Action_ADDED:
+		event.Action = FSCreated
+	case pb.Action_EDITED:
+		event.Action = FSModified
+	case pb.Action_REMOVED, pb.Action_RENAMED:
+		event.Action = FSDeleted
+	default:
+		return nil, false
+	}
+	return event, true
+}
+
+// FTPListRequest lists directory, the result is received as FTPListResponse.
+type FTPListRequest struct {
+	Path string
+}
+
+type FTPListResponse struct {
+	Path string
+	// Entries are sorted by name.
+	Entries []FTPEntry
+}
+
+type FTPEntry struct {
+	Name  string
+	Size  int64
+	IsDir bool
+}
+
+// FTPDownloadRequest downloads file, the result is received as FTPDownloadResponse.
+type FTPDownloadRequest struct {
+	Path string
+}
+
+type FTPDownloadResponse struct {
+	Path    string
+	Payload []byte
+}
+
+type FTPRenameRequest struct {
+	From string
+	To   string
+}
+
+type FTPDeleteRequest struct {
+	Path string
+}
+
+type FTPMkdirRequest struct {
+	Path string
+}
+
 func (p *FTPPort) Send(ctx context.Context, i interface{}) error {
-	event, ok := i.(*FTPEvent)
-	if !ok {
-		return fmt.Errorf("FTPPort send supports only FTPEvent type")
+	switch msg := i.(type) {
+	case *FTPEvent:
+		switch msg.Action {
+		case FSCreated, FSModified:
+			if err := p.conn.Stor(msg.Path, bytes.NewBuffer(msg.Payload)); err != nil {
+				return fmt.Errorf("ftp file upload failed %v", err)
+			}
+		case FSDeleted:
+			if err := p.conn.Delete(msg.Path); err != nil {
+				return fmt.Errorf("ftp delete of %q failed: %v", msg.Path, err)
+			}
+		default:
+			return fmt.Errorf("unsupported %v action", msg.Action)
+		}
+	case *FTPListRequest:
+		resp, err := p.list(msg.Path)
+		if err != nil {
+			return err
+		}
+		return p.results.push(resp)
+	case *FTPDownloadRequest:
+		resp, err := p.download(msg.Path)
+		if err != nil {
+			return err
+		}
+		return p.results.push(resp)
+	case *FTPRenameRequest:
+		if err := p.conn.Rename(msg.From, msg.To); err != nil {
+			return fmt.Errorf("ftp rename of %q to %q failed: %v", msg.From, msg.To, err)
+		}
+	case *FTPDeleteRequest:
+		if err := p.conn.Delete(msg.Path); err != nil {
+			return fmt.Errorf("ftp delete of %q failed: %v", msg.Path, err)
+		}
+	case *FTPMkdirRequest:
+		if err := p.conn.MakeDir(msg.Path); err != nil {
+			return fmt.Errorf("ftp mkdir of %q failed: %v", msg.Path, err)
+		}
+	default:
+		return fmt.Errorf("FTPPort send doesn't support %T type", i)
 	}
+	return nil
+}
 
-	if err := p.conn.Stor(event.Path, bytes.NewBuffer(event.Payload)); err != nil {
-		return fmt.Errorf("ftp file upload failed %v", err)
+func (p *FTPPort) list(path string) (*FTPListResponse, error) {
+	entries, err := p.conn.List(path)
+	if err != nil {
+		return nil, fmt.Errorf("ftp list of %q failed: %v", path, err)
 	}
+	resp := &FTPListResponse{
+		Path: path,
+	}
+	for _, e := range entries {
+		if e.Name == "." || e.Name == ".." {
+			continue
+		}
+		resp.Entries = append(resp.Entries, FTPEntry{
+			Name:  e.Name,
+			Size:  int64(e.Size),
+			IsDir: e.Type == ftp.EntryTypeFolder,
+		})
+	}
+	sort.Slice(resp.Entries, func(i, j int) bool {
+		return resp.Entries[i].Name < resp.Entries[j].Name
+	})
+	return resp, nil
+}
 
-	return nil
+func (p *FTPPort) download(path string) (*FTPDownloadResponse, error) {
+	r, err := p.conn.Retr(path)
+	if err != nil {
+		return nil, fmt.Errorf("ftp download of %q failed: %v", path, err)
+	}
+	defer r.Close()
+	buff, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, fmt.Errorf("ftp download of %q failed: %v", path, err)
+	}
+	return &FTPDownloadResponse{
+		Path:    path,
+		Payload: buff,
+	}, nil
 }
 
+// Receive returns pending list or download result first, otherwise waits for file event.
 func (p *FTPPort) Receive(ctx context.Context) (interface{}, error) {
+	if msg, ok := p.results.pop(); ok {
+		return msg, nil
+	}
+
 	select {
 	case msg := <-p.ftpEventC:
-		return &FTPEvent{
-			Path:    msg.GetPath(),
-			Payload: msg.GetContent(),
-		}, nil
+		return msg, nil
 	case <-time.NewTimer(time.Second * 7).C:
 		return nil, errors.Errorf("failed to receive message, deadline exceeded")
 	}
@@ -0,0 +1,263 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/textproto"
+	"path"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+
+	pb "github.com/smallinsky/mtf/proto/fswatch"
+)
+
+// ftpServer is an in memory FTP server handling commands used by the FTP port.
+type ftpServer struct {
+	ln    net.Listener
+	mtx   sync.Mutex
+	files map[string][]byte
+	dirs  map[string]bool
+}
+
+func startFTPServer(t *testing.T) *ftpServer {
+	ln, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatalf("failed to listen: %v", err)
+	}
+	s := &ftpServer{
+		ln:    ln,
+		files: make(map[string][]byte),
+		dirs:  map[string]bool{"/": true},
+	}
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go s.serve(conn)
+		}
+	}()
+	return s
+}
+
+func (s *ftpServer) Close() {
+	s.ln.Close()
+}
+
+func (s *ftpServer) serve(c net.Conn) {
+	conn := textproto.NewConn(c)
+	defer conn.Close()
+	var (
+		data     net.Listener
+		renameOf string
+	)
+	// transfer sends data command response and passes data connection to fn.
+	transfer := func(fn func(net.Conn)) {
+		if data == nil {
+			conn.PrintfLine("425 use EPSV first")
+			return
+		}
+		defer func() {
+			data.Close()
+			data = nil
+		}()
+		conn.PrintfLine("150 opening data connection")
+		dc, err := data.Accept()
+		if err != nil {
+			conn.PrintfLine("425 %v", err)
+			return
+		}
+		fn(dc)
+		dc.Close()
+		conn.PrintfLine("226 transfer complete")
+	}
+
+	conn.PrintfLine("220 ready")
+	for {
+		line, err := conn.ReadLine()
+		if err != nil {
+			return
+		}
+		parts := strings.SplitN(line, " ", 2)
+		var arg string
+		if len(parts) == 2 {
+			arg = path.Clean("/" + parts[1])
+		}
+
+		s.mtx.Lock()
+		switch parts[0] {
+		case "FEAT":
+			conn.PrintfLine("211-Features:\r\n MLST type*;size*;\r\n211 End")
+		case "USER":
+			conn.PrintfLine("331 password required")
+		case "PASS":
+			conn.PrintfLine("230 logged in")
+		case "TYPE":
+			conn.PrintfLine("200 type set")
+		case "EPSV":
+			if data, err = net.Listen("tcp", "localhost:0"); err != nil {
+				conn.PrintfLine("425 %v", err)
+				break
+			}
+			conn.PrintfLine("229 Entering Extended Passive Mode (|||%d|)", data.Addr().(*net.TCPAddr).Port)
+		case "MLSD":
+			transfer(func(dc net.Conn) {
+				for name := range s.dirs {
+					if name != "/" && path.Dir(name) == arg {
+						fmt.Fprintf(dc, "type=dir; %s\r\n", path.Base(name))
+					}
+				}
+				for name, content := range s.files {
+					if path.Dir(name) == arg {
+						fmt.Fprintf(dc, "type=file;size=%d; %s\r\n", len(content), path.Base(name))
+					}
+				}
+			})
+		case "RETR":
+			content, ok := s.files[arg]
+			if !ok {
+				conn.PrintfLine("550 file not found")
+				break
+			}
+			transfer(func(dc net.Conn) { dc.Write(content) })
+		case "STOR":
+			transfer(func(dc net.Conn) { s.files[arg], _ = ioutil.ReadAll(dc) })
+		case "RNFR":
+			renameOf = arg
+			conn.PrintfLine("350 ready for RNTO")
+		case "RNTO":
+			s.files[arg] = s.files[renameOf]
+			delete(s.files, renameOf)
+			conn.PrintfLine("250 renamed")
+		case "DELE":
+			if _, ok := s.files[arg]; !ok {
+				conn.PrintfLine("550 file not found")
+				break
+			}
+			delete(s.files, arg)
+			conn.PrintfLine("250 deleted")
+		case "MKD":
+			s.dirs[arg] = true
+			conn.PrintfLine("257 %q created", arg)
+		case "QUIT":
+			conn.PrintfLine("221 bye")
+			s.mtx.Unlock()
+			return
+		default:
+			conn.PrintfLine("502 not implemented")
+		}
+		s.mtx.Unlock()
+	}
+}
+
+func TestFTPPort(t *testing.T) {
+	srv := startFTPServer(t)
+	defer srv.Close()
+	p, err := NewFTP(srv.ln.Addr().String(), "", "", WithFSWatchAddr("localhost:11136"))
+	if err != nil {
+		t.Fatalf("failed to create port: %v", err)
+	}
+	defer p.Close()
+	ctx := context.Background()
+
+	send := func(msg interface{}) {
+		t.Helper()
+		if err := p.Send(ctx, msg); err != nil {
+			t.Fatalf("failed to send %T: %v", msg, err)
+		}
+	}
+	receive := func(want interface{}) {
+		t.Helper()
+		got, err := p.Receive(ctx)
+		if err != nil {
+			t.Fatalf("failed to receive message: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("message mismatch, got: %+v want: %+v", got, want)
+		}
+	}
+
+	send(&FTPMkdirRequest{Path: "/inbox"})
+	send(&FTPEvent{Path: "/inbox/report.csv", Action: FSCreated, Payload: []byte("a,b")})
+	send(&FTPEvent{Path: "/inbox/old.csv", Action: FSCreated, Payload: []byte("a")})
+	send(&FTPRenameRequest{From: "/inbox/report.csv", To: "/report.csv"})
+	send(&FTPDeleteRequest{Path: "/inbox/old.csv"})
+
+	send(&FTPListRequest{Path: "/"})
+	receive(&FTPListResponse{
+		Path: "/",
+		Entries: []FTPEntry{
+			{Name: "inbox", IsDir: true},
+			{Name: "report.csv", Size: 3},
+		},
+	})
+	send(&FTPListRequest{Path: "/inbox"})
+	receive(&FTPListResponse{Path: "/inbox"})
+	send(&FTPDownloadRequest{Path: "/report.csv"})
+	receive(&FTPDownloadResponse{Path: "/report.csv", Payload: []byte("a,b")})
+
+	send(&FTPEvent{Path: "/report.csv", Action: FSDeleted})
+	if err := p.Send(ctx, &FTPDownloadRequest{Path: "/report.csv"}); err == nil {
+		t.Fatalf("expected removed file download error")
+	}
+
+	if err := p.Close(); err != nil {
+		t.Fatalf("failed to close port: %v", err)
+	}
+	if err := p.Close(); err != nil {
+		t.Fatalf("failed to close port again: %v", err)
+	}
+}
+
+func TestFTPPortEvents(t *testing.T) {
+	const addr = "localhost:11137"
+	srv := startFTPServer(t)
+	defer srv.Close()
+	p, err := NewFTP(srv.ln.Addr().String(), "", "", WithFSWatchAddr(addr))
+	if err != nil {
+		t.Fatalf("failed to create port: %v", err)
+	}
+	defer p.Close()
+
+	conn, err := grpc.Dial(addr, grpc.WithInsecure())
+	if err != nil {
+		t.Fatalf("failed to dial: %v", err)
+	}
+	defer conn.Close()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*3)
+	defer cancel()
+	for _, req := range []*pb.EventRequest{
+		{Path: "/a.txt", Action: pb.Action_ADDED, Content: []byte("a")},
+		{Path: "/a.txt", Action: pb.Action_MODE_CHANGED},
+		{Path: "/a.txt", Action: pb.Action_EDITED, Content: []byte("ab")},
+		{Path: "/a.txt", Action: pb.Action_RENAMED},
+		{Path: "/b.txt", Action: pb.Action_REMOVED},
+	} {
+		if _, err := pb.NewWatcherClient(conn).Event(ctx, req); err != nil {
+			t.Fatalf("failed to send event: %v", err)
+		}
+	}
+
+	for _, want := range []*FTPEvent{
+		{Path: "/a.txt", Action: FSCreated, Payload: []byte("a")},
+		{Path: "/a.txt", Action: FSModified, Payload: []byte("ab")},
+		{Path: "/a.txt", Action: FSDeleted},
+		{Path: "/b.txt", Action: FSDeleted},
+	} {
+		got, err := p.Receive(ctx)
+		if err != nil {
+			t.Fatalf("failed to receive event: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("event mismatch, got: %+v want: %+v", got, want)
+		}
+	}
+}
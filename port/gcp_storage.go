@@ -3,9 +3,11 @@ package port
 import (
 	"bytes"
 	"context"
+	"crypto/rsa"
 	"io"
 	"io/ioutil"
-	"log"
+	"net/http"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -15,78 +17,351 @@ import (
 
 func NewGCStoragePort() *GCStorage {
 	return &GCStorage{
-		inEvent:  make(chan interface{}),
-		outEvent: make(chan interface{}),
+		exchanger: newExchanger("gcs", storageErrorResponse, func(code int, msg string) error {
+			return &fakegcs.Error{
+				Code:    code,
+				Message: msg,
+			}
+		}),
+		ops: newOpLog(),
 	}
 }
 
-func (s GCStorage) onObjectInsert(bo fakegcs.BucketObject, r io.Reader) error {
+// NewGCSPort creates GCS port where each SUT call needs to be received and answered by the test.
+// WithTimeout option sets how long SUT call waits for the test, after that the call fails
+// and the failure is reported in the current test. SUT calls are served by a single handler,
+// so options of the last created GCS port apply to all of them.
+func NewGCSPort(opts ...Opt) (*Port, error) {
+	startHTTP()
+	ht.gcs.setOptions(opts...)
+	ht.gcs.setStore(nil)
+	registerReset(ht.gcs)
+	return &Port{
+		impl: ht.gcs,
+	}, nil
+}
+
+// NewGCSStorePort creates GCS port in stateful mode where SUT calls are served from the store
+// without test interaction. Port Receive returns log of SUT operations. Like with NewGCSPort
+// the store and options replace the ones of previously created GCS port.
+func NewGCSStorePort(store *fakegcs.Store, opts ...Opt) (*Port, error) {
+	startHTTP()
+	ht.gcs.setOptions(opts...)
+	ht.gcs.setStore(store)
+	registerReset(ht.gcs)
+	return &Port{
+		impl: ht.gcs,
+	}, nil
+}
+
+func (s *GCStorage) setStore(store *fakegcs.Store) {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.store = store
+	s.ops.reset()
+}
+
+// Reset drops SUT operations not received by previous test case.
+func (s *GCStorage) Reset(ctx context.Context) error {
+	s.ops.reset()
+	return nil
+}
+
+func (s *GCStorage) getStore() *fakegcs.Store {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.store
+}
+
+func (s *GCStorage) setOptions(opts ...Opt) {
+	options := defaultPortOpts
+	for _, o := range opts {
+		o(&options)
+	}
+	s.setTimeout(options.timeout)
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	s.signingKey = options.signingKey
+}
+
+func (s *GCStorage) getSigningKey(accessID string) *rsa.PublicKey {
+	s.mtx.Lock()
+	defer s.mtx.Unlock()
+	return s.signingKey
+}
+
+// onObjectInsert writes the object to the store if its preconditions are met, in interactive
+// mode preconditions are ignored like in onPreconditions.
+func (s *GCStorage) onObjectInsert(attrs fakegcs.ObjectAttrs, conds fakegcs.Conditions, r io.Reader) (*fakegcs.ObjectAttrs, error) {
 	buff, err := ioutil.ReadAll(r)
 	if err != nil {
-		log.Fatalf("[ERR] failed to read object content: %v", err)
+		return nil, errors.Wrapf(err, "failed to read object content")
 	}
 	req := &StorageInsertRequest{
-		Bucket:  bo.Bucket,
-		Object:  bo.Object,
+		Bucket:  attrs.Bucket,
+		Object:  attrs.Name,
 		Content: buff,
 	}
 
-	select {
-	case s.inEvent <- req:
-	case <-time.Tick(time.Second * 3):
-		log.Fatalf("gcs response not provided 1")
+	if store := s.getStore(); store != nil {
+		stored, err := store.PutIf(attrs.Bucket, attrs.Name, conds, attrs.ContentType, buff)
+		if err != nil {
+			return nil, err
+		}
+		s.ops.push(req)
+		return &stored, nil
 	}
 
-	select {
-	case <-s.outEvent:
-		return nil
-	case <-time.Tick(time.Second * 3):
-		log.Fatalf("gcs response not provided 2")
-		return nil
+	msg, err := s.exchange(req)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := msg.(*StorageInsertResponse); !ok {
+		return nil, s.fail(http.StatusInternalServerError, "expected *StorageInsertResponse but got %T", msg)
 	}
+	return nil, nil
 }
 
-func (s GCStorage) onObjectGet(bo fakegcs.BucketObject, w io.Writer) error {
+func (s *GCStorage) onObjectGet(bo fakegcs.BucketObject, w io.Writer) error {
 	req := &StorageGetRequest{
 		Bucket: bo.Bucket,
 		Object: bo.Object,
 	}
 
-	select {
-	case s.inEvent <- req:
-	case <-time.Tick(time.Second * 3):
-		log.Fatalf("gcs response not provided 3")
-		return nil
+	if store := s.getStore(); store != nil {
+		s.ops.push(req)
+		obj, ok := store.Object(bo.Bucket, bo.Object)
+		if !ok {
+			return fakegcs.ErrObjectNotExist
+		}
+		_, err := w.Write(obj.Content)
+		return err
 	}
 
-	select {
-	case msg := <-s.outEvent:
-		r, ok := msg.(*StorageGetResponse)
+	msg, err := s.exchange(req)
+	if err != nil {
+		return err
+	}
+	resp, ok := msg.(*StorageGetResponse)
+	if !ok {
+		return s.fail(http.StatusInternalServerError, "expected *StorageGetResponse but got %T", msg)
+	}
+	_, err = io.Copy(w, bytes.NewReader(resp.Content))
+	return err
+}
+
+func (s *GCStorage) onObjectList(q fakegcs.ListQuery) ([]fakegcs.ObjectAttrs, error) {
+	req := &StorageListRequest{
+		Bucket:    q.Bucket,
+		Prefix:    q.Prefix,
+		Delimiter: q.Delimiter,
+	}
+	if store := s.getStore(); store != nil {
+		s.ops.push(req)
+		return store.Objects(q.Bucket), nil
+	}
+
+	msg, err := s.exchange(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, ok := msg.(*StorageListResponse)
+	if !ok {
+		return nil, s.fail(http.StatusInternalServerError, "expected *StorageListResponse but got %T", msg)
+	}
+	var out []fakegcs.ObjectAttrs
+	for _, o := range resp.Objects {
+		out = append(out, o.toFake(q.Bucket))
+	}
+	return out, nil
+}
+
+func (s *GCStorage) onObjectAttrs(bo fakegcs.BucketObject) (*fakegcs.ObjectAttrs, error) {
+	req := &StorageAttrsRequest{
+		Bucket: bo.Bucket,
+		Object: bo.Object,
+	}
+	if store := s.getStore(); store != nil {
+		s.ops.push(req)
+		obj, ok := store.Object(bo.Bucket, bo.Object)
 		if !ok {
-			log.Fatalf("faield to receive event aa %T", msg)
+			return nil, fakegcs.ErrObjectNotExist
 		}
+		return &obj.Attrs, nil
+	}
+
+	msg, err := s.exchange(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, ok := msg.(*StorageAttrsResponse)
+	if !ok {
+		return nil, s.fail(http.StatusInternalServerError, "expected *StorageAttrsResponse but got %T", msg)
+	}
+	attrs := resp.Attrs.toFake(bo.Bucket)
+	return &attrs, nil
+}
+
+func (s *GCStorage) onObjectDelete(bo fakegcs.BucketObject) error {
+	req := &StorageDeleteRequest{
+		Bucket: bo.Bucket,
+		Object: bo.Object,
+	}
+	if store := s.getStore(); store != nil {
+		s.ops.push(req)
+		return store.Delete(bo.Bucket, bo.Object)
+	}
 
-		_, err := io.Copy(w, bytes.NewReader(r.Content))
+	msg, err := s.exchange(req)
+	if err != nil {
+		return err
+	}
+	if _, ok := msg.(*StorageDeleteResponse); !ok {
+		return s.fail(http.StatusInternalServerError, "expected *StorageDeleteResponse but got %T", msg)
+	}
+	return nil
+}
+
+func (s *GCStorage) onObjectCopy(src, dst fakegcs.BucketObject) (*fakegcs.ObjectAttrs, error) {
+	req := &StorageCopyRequest{
+		SrcBucket: src.Bucket,
+		SrcObject: src.Object,
+		DstBucket: dst.Bucket,
+		DstObject: dst.Object,
+	}
+	if store := s.getStore(); store != nil {
+		s.ops.push(req)
+		attrs, err := store.Copy(src, dst)
 		if err != nil {
-			log.Fatalf("faield to write content")
+			return nil, err
 		}
-	case <-time.Tick(time.Second * 3):
-		log.Fatalf("gcs response not provided 4")
+		return &attrs, nil
 	}
-	return nil
+
+	msg, err := s.exchange(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, ok := msg.(*StorageCopyResponse)
+	if !ok {
+		return nil, s.fail(http.StatusInternalServerError, "expected *StorageCopyResponse but got %T", msg)
+	}
+	attrs := resp.Attrs.toFake(dst.Bucket)
+	return &attrs, nil
+}
+
+func (s *GCStorage) onObjectCompose(dst fakegcs.BucketObject, srcs []fakegcs.BucketObject) (*fakegcs.ObjectAttrs, error) {
+	req := &StorageComposeRequest{
+		Bucket: dst.Bucket,
+		Object: dst.Object,
+	}
+	for _, src := range srcs {
+		req.Sources = append(req.Sources, src.Object)
+	}
+	if store := s.getStore(); store != nil {
+		s.ops.push(req)
+		attrs, err := store.Compose(dst, srcs)
+		if err != nil {
+			return nil, err
+		}
+		return &attrs, nil
+	}
+
+	msg, err := s.exchange(req)
+	if err != nil {
+		return nil, err
+	}
+	resp, ok := msg.(*StorageComposeResponse)
+	if !ok {
+		return nil, s.fail(http.StatusInternalServerError, "expected *StorageComposeResponse but got %T", msg)
+	}
+	attrs := resp.Attrs.toFake(dst.Bucket)
+	return &attrs, nil
 }
 
-func (s GCStorage) registerRouter(r *mux.Router) {
+// onPreconditions validates write preconditions against the store, in interactive mode
+// preconditions are ignored and test can respond with StorageErrorResponse instead.
+func (s *GCStorage) onPreconditions(bo fakegcs.BucketObject, c fakegcs.Conditions) error {
+	store := s.getStore()
+	if store == nil {
+		return nil
+	}
+	return store.CheckConditions(bo, c)
+}
+
+func (s *GCStorage) registerRouter(r *mux.Router) {
 	fgcs := &fakegcs.GCStorage{
-		OnObjectInsert: s.onObjectInsert,
-		OnObjectGet:    s.onObjectGet,
+		OnObjectInsertIf: s.onObjectInsert,
+		OnObjectGet:      s.onObjectGet,
+		OnObjectList:     s.onObjectList,
+		OnObjectAttrs:    s.onObjectAttrs,
+		OnObjectDelete:   s.onObjectDelete,
+		OnObjectCopy:     s.onObjectCopy,
+		OnObjectCompose:  s.onObjectCompose,
+		OnPreconditions:  s.onPreconditions,
+		SigningKey:       s.getSigningKey,
 	}
 	fgcs.AddMuxRoute(r)
 }
 
 type GCStorage struct {
-	inEvent  chan interface{}
-	outEvent chan interface{}
+	*exchanger
+
+	mtx   sync.Mutex
+	store *fakegcs.Store
+	ops   *opLog
+	// signingKey verifies signed URLs issued by any service account.
+	signingKey *rsa.PublicKey
+}
+
+// opLog is an unbounded queue of SUT operations served in stateful mode.
+type opLog struct {
+	mtx    sync.Mutex
+	ops    []interface{}
+	notify chan struct{}
+}
+
+func newOpLog() *opLog {
+	return &opLog{
+		notify: make(chan struct{}, 1),
+	}
+}
+
+func (l *opLog) push(op interface{}) {
+	l.mtx.Lock()
+	l.ops = append(l.ops, op)
+	l.mtx.Unlock()
+
+	select {
+	case l.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (l *opLog) reset() {
+	l.mtx.Lock()
+	defer l.mtx.Unlock()
+	l.ops = nil
+}
+
+func (l *opLog) pop(timeout time.Duration) (interface{}, error) {
+	deadline := time.After(timeout)
+	for {
+		l.mtx.Lock()
+		if len(l.ops) != 0 {
+			op := l.ops[0]
+			l.ops = l.ops[1:]
+			l.mtx.Unlock()
+			return op, nil
+		}
+		l.mtx.Unlock()
+
+		select {
+		case <-l.notify:
+		case <-deadline:
+			return nil, errors.Errorf("failed to receive message, deadline exceeded")
+		}
+	}
 }
 
 type StorageInsertRequest struct {
@@ -107,22 +382,118 @@ type StorageGetResponse struct {
 	Content []byte
 }
 
-func (s *GCStorage) receive(opts ...Opt) (interface{}, error) {
-	select {
-	case <-time.Tick(time.Second * 3):
-		return nil, errors.Errorf("failed to receive  message, deadline exceeded")
-	case msg := <-s.inEvent:
-		return msg, nil
+// StorageErrorResponse can be sent in response to any storage request to make
+// SUT call fail with given HTTP status code and GCS error message.
+type StorageErrorResponse struct {
+	Code    int
+	Message string
+	// Reason is GCS error reason e.g. "notFound", derived from Code when empty.
+	Reason string
+}
+
+func (e *StorageErrorResponse) toFake() error {
+	return &fakegcs.Error{
+		Code:    e.Code,
+		Message: e.Message,
+		Reason:  e.Reason,
 	}
 }
 
-func (s *GCStorage) send(msg interface{}, opts ...PortOpt) error {
-	select {
-	case s.outEvent <- msg:
-		return nil
-	case <-time.Tick(time.Second * 3):
-		return errors.Errorf("failed to receive  message, deadline exceeded")
+// storageErrorResponse converts StorageErrorResponse sent by the test to error returned to SUT.
+func storageErrorResponse(msg interface{}) error {
+	if e, ok := msg.(*StorageErrorResponse); ok {
+		return e.toFake()
+	}
+	return nil
+}
+
+// StorageObjectAttrs describes object metadata returned to SUT.
+type StorageObjectAttrs struct {
+	Name        string
+	ContentType string
+	Size        int64
+	MD5         []byte
+	Generation  int64
+	Metadata    map[string]string
+	Updated     time.Time
+}
+
+func (a StorageObjectAttrs) toFake(bucket string) fakegcs.ObjectAttrs {
+	return fakegcs.ObjectAttrs{
+		Bucket:      bucket,
+		Name:        a.Name,
+		ContentType: a.ContentType,
+		Size:        a.Size,
+		MD5:         a.MD5,
+		Generation:  a.Generation,
+		Metadata:    a.Metadata,
+		Updated:     a.Updated,
+	}
+}
+
+type StorageListRequest struct {
+	Bucket    string
+	Prefix    string
+	Delimiter string
+}
+
+// StorageListResponse contains all bucket objects, prefix, delimiter and paging
+// are applied by the port.
+type StorageListResponse struct {
+	Objects []StorageObjectAttrs
+}
+
+type StorageAttrsRequest struct {
+	Bucket string
+	Object string
+}
+
+type StorageAttrsResponse struct {
+	Attrs StorageObjectAttrs
+}
+
+type StorageDeleteRequest struct {
+	Bucket string
+	Object string
+}
+
+type StorageDeleteResponse struct {
+}
+
+// StorageCopyRequest is received for both objects copy and rewrite calls.
+type StorageCopyRequest struct {
+	SrcBucket string
+	SrcObject string
+	DstBucket string
+	DstObject string
+}
+
+type StorageCopyResponse struct {
+	Attrs StorageObjectAttrs
+}
+
+type StorageComposeRequest struct {
+	Bucket  string
+	Object  string
+	Sources []string
+}
+
+type StorageComposeResponse struct {
+	Attrs StorageObjectAttrs
+}
+
+func (s *GCStorage) receive() (interface{}, error) {
+	if s.getStore() != nil {
+		return s.ops.pop(s.getTimeout())
+	}
+	return s.exchanger.receive()
+}
+
+func (s *GCStorage) send(msg interface{}) error {
+	if s.getStore() != nil {
+		return errors.Errorf("gcs port in stateful mode doesn't accept %T, use store to change objects", msg)
 	}
+	return s.exchanger.send(msg)
 }
 
 func (s *GCStorage) Send(ctx context.Context, i interface{}) error {
@@ -6,12 +6,15 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"reflect"
 	"strings"
 	"testing"
 	"time"
 
 	"cloud.google.com/go/storage"
 	"github.com/gorilla/mux"
+	"github.com/smallinsky/mtf/fake/fakegcs"
+	mtfctx "github.com/smallinsky/mtf/framework/context"
 	"golang.org/x/oauth2"
 	"google.golang.org/api/option"
 )
@@ -19,28 +22,10 @@ import (
 func TestGCStorage(t *testing.T) {
 	t.Skip()
 	port := NewGCStoragePort()
-	r := mux.NewRouter()
-	port.registerRouter(r)
-
-	cst := httptest.NewServer(r)
-
-	readHostEnv := strings.Replace(cst.URL, "http://", "", -1)
-	if err := os.Setenv("STORAGE_EMULATOR_HOST", readHostEnv); err != nil {
-		t.Fatalf("failed to set readHost env: %v", err)
-	}
-	hc := &http.Client{
-		Transport: &oauth2.Transport{
-			Source: new(tokenSupplier),
-		},
-	}
+	_, sc, cleanup := startGCSTestServer(t, port)
+	defer cleanup()
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	opts := []option.ClientOption{option.WithHTTPClient(hc), option.WithEndpoint(cst.URL)}
-	sc, err := storage.NewClient(ctx, opts...)
-	if err != nil {
-		t.Fatalf("Failed to create storage client: %v", err)
-	}
-	defer sc.Close()
 
 	t.Run("ObjectGet", func(t *testing.T) {
 		go func() {
@@ -90,7 +75,7 @@ func TestGCStorage(t *testing.T) {
 		obj := sc.Bucket("bucket/a/b").Object("somefile.txt")
 		w := obj.NewWriter(ctx)
 
-		_, err = w.Write([]byte("write content"))
+		_, err := w.Write([]byte("write content"))
 		if err != nil {
 			t.Fatalf("got write error: %v", err)
 		}
@@ -101,6 +86,45 @@ func TestGCStorage(t *testing.T) {
 
 }
 
+// startGCSTestServer serves the port on a test server accepting any host and returns
+// storage client calling it, cleanup restores fakegcs.StorageHost.
+func startGCSTestServer(t *testing.T, port *GCStorage) (*httptest.Server, *storage.Client, func()) {
+	host := fakegcs.StorageHost
+	emulatorHost, emulatorSet := os.LookupEnv("STORAGE_EMULATOR_HOST")
+	fakegcs.StorageHost = "{[0-9]:.+}"
+	r := mux.NewRouter()
+	port.registerRouter(r)
+	cst := httptest.NewServer(r)
+
+	cleanup := func() {
+		cst.Close()
+		fakegcs.StorageHost = host
+		if emulatorSet {
+			os.Setenv("STORAGE_EMULATOR_HOST", emulatorHost)
+		} else {
+			os.Unsetenv("STORAGE_EMULATOR_HOST")
+		}
+	}
+	if err := os.Setenv("STORAGE_EMULATOR_HOST", strings.TrimPrefix(cst.URL, "http://")); err != nil {
+		cleanup()
+		t.Fatalf("failed to set readHost env: %v", err)
+	}
+	hc := &http.Client{
+		Transport: &oauth2.Transport{
+			Source: new(tokenSupplier),
+		},
+	}
+	sc, err := storage.NewClient(context.Background(), option.WithHTTPClient(hc), option.WithEndpoint(cst.URL))
+	if err != nil {
+		cleanup()
+		t.Fatalf("Failed to create storage client: %v", err)
+	}
+	return cst, sc, func() {
+		sc.Close()
+		cleanup()
+	}
+}
+
 type tokenSupplier int
 
 func (ts *tokenSupplier) Token() (*oauth2.Token, error) {
@@ -111,3 +135,140 @@ func (ts *tokenSupplier) Token() (*oauth2.Token, error) {
 		Expiry:       time.Now().Add(time.Hour),
 	}, nil
 }
+
+func TestGCStorageStore(t *testing.T) {
+	store := fakegcs.NewStore()
+	store.Put("bucket", "in.txt", "text/plain", []byte("seeded content"))
+
+	port := NewGCStoragePort()
+	port.setStore(store)
+	_, sc, cleanup := startGCSTestServer(t, port)
+	defer cleanup()
+	ctx := context.Background()
+
+	reader, err := sc.Bucket("bucket").Object("in.txt").NewReader(ctx)
+	if err != nil {
+		t.Fatalf("got err during new reader call %v", err)
+	}
+	content, err := ioutil.ReadAll(reader)
+	if err != nil {
+		t.Fatalf("failed to read: %v", err)
+	}
+	reader.Close()
+
+	w := sc.Bucket("bucket").Object("out.txt").NewWriter(ctx)
+	w.ContentType = "text/plain"
+	if _, err := w.Write(content); err != nil {
+		t.Fatalf("got write error: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("got close error: %v", err)
+	}
+
+	obj, ok := store.Object("bucket", "out.txt")
+	if !ok {
+		t.Fatalf("object was not written to the store")
+	}
+	if got, want := string(obj.Content), "seeded content"; got != want {
+		t.Fatalf("content mismatch, got: %q want: %q", got, want)
+	}
+	if obj.Attrs.ContentType != "text/plain" {
+		t.Fatalf("content type mismatch, got: %q", obj.Attrs.ContentType)
+	}
+	if attrs := w.Attrs(); attrs.Generation != obj.Attrs.Generation {
+		t.Fatalf("generation mismatch, got: %v want: %v", attrs.Generation, obj.Attrs.Generation)
+	}
+
+	if _, err := sc.Bucket("bucket").Object("missing.txt").NewReader(ctx); err != storage.ErrObjectNotExist {
+		t.Fatalf("expected object not exist error, got: %v", err)
+	}
+
+	for _, want := range []interface{}{
+		&StorageGetRequest{Bucket: "bucket", Object: "in.txt"},
+		&StorageInsertRequest{Bucket: "bucket", Object: "out.txt", Content: []byte("seeded content")},
+		&StorageGetRequest{Bucket: "bucket", Object: "missing.txt"},
+	} {
+		got, err := port.receive()
+		if err != nil {
+			t.Fatalf("failed to receive operation: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("operation mismatch, got: %+v want: %+v", got, want)
+		}
+	}
+}
+
+func TestGCStorageErrorResponse(t *testing.T) {
+	port := NewGCStoragePort()
+	_, sc, cleanup := startGCSTestServer(t, port)
+	defer cleanup()
+	ctx := context.Background()
+
+	go func() {
+		if _, err := port.receive(); err != nil {
+			t.Errorf("failed to receive message: %v", err)
+			return
+		}
+		if err := port.send(&StorageErrorResponse{Code: http.StatusNotFound, Message: "No such object"}); err != nil {
+			t.Errorf("failed to send message: %v", err)
+		}
+	}()
+
+	if _, err := sc.Bucket("bucket").Object("missing.txt").Attrs(ctx); err != storage.ErrObjectNotExist {
+		t.Fatalf("expected object not exist error, got: %v", err)
+	}
+}
+
+func TestGCStorageHandlerTimeout(t *testing.T) {
+	port := NewGCStoragePort()
+	port.setOptions(WithTimeout(time.Millisecond * 100))
+	cst, _, cleanup := startGCSTestServer(t, port)
+	defer cleanup()
+
+	// Test context logs are written to the runlogs dir in the working dir.
+	dir, err := ioutil.TempDir("", "gcsport")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatalf("failed to get working dir: %v", err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatalf("failed to change working dir: %v", err)
+	}
+	defer os.Chdir(wd)
+	mtfctx.CreateDirectory()
+	mtfctx.CreateTestContext(t)
+	defer mtfctx.RemoveTextContext(t)
+
+	resp, err := http.Get(cst.URL + "/b/bucket/o/file.txt")
+	if err != nil {
+		t.Fatalf("failed to send request: %v", err)
+	}
+	defer resp.Body.Close()
+	if got, want := resp.StatusCode, http.StatusGatewayTimeout; got != want {
+		t.Fatalf("status code mismatch, got: %v want: %v", got, want)
+	}
+
+	failures := mtfctx.Get(t).Failures()
+	if len(failures) != 1 || !strings.Contains(failures[0], "*port.StorageAttrsRequest was not received by the test") {
+		t.Fatalf("unexpected test context failures: %q", failures)
+	}
+}
+
+func TestGCStorageReset(t *testing.T) {
+	port := NewGCStoragePort()
+	port.setOptions(WithTimeout(time.Millisecond * 100))
+	port.setStore(fakegcs.NewStore())
+	port.ops.push(&StorageGetRequest{Bucket: "bucket", Object: "file.txt"})
+
+	registerReset(port)
+	if err := Reset(context.Background()); err != nil {
+		t.Fatalf("failed to reset ports: %v", err)
+	}
+	if op, err := port.receive(); err == nil {
+		t.Fatalf("expected empty operations log, got: %+v", op)
+	}
+}
@@ -18,12 +18,6 @@ func NewHTTPPort() *Port {
 	}
 }
 
-func NewGCSPort() (*Port, error) {
-	startHTTP()
-	return &Port{
-		impl: ht.gcs,
-	}, nil
-}
 func newHTTPPort() *HTTPPort {
 	return &HTTPPort{
 		reqC:  make(chan *HTTPRequest),
@@ -21,6 +21,7 @@ type httpserver struct {
 
 	httpPort *HTTPPort
 	gcs      *GCStorage
+	s3       *S3Storage
 }
 
 func startHTTP() {
@@ -29,11 +30,13 @@ func startHTTP() {
 			router:   mux.NewRouter(),
 			httpPort: newHTTPPort(),
 			gcs:      NewGCStoragePort(),
+			s3:       newS3Port(),
 		}
 		ht.httpPort.Register(ht.router)
 		if ht.gcs != nil {
 			ht.gcs.registerRouter(ht.router)
 		}
+		ht.s3.registerRouter(ht.router)
 		if err := ht.run(); err != nil {
 			panic(err)
 
@@ -1,6 +1,7 @@
 package port
 
 import (
+	"crypto/rsa"
 	"testing"
 	"time"
 
@@ -27,6 +28,29 @@ func WithTimeout(timout time.Duration) Opt {
 	}
 }
 
+// WithSigningKey sets public key used by GCS port to verify signed URL signatures,
+// signed URLs are rejected without it.
+func WithSigningKey(key *rsa.PublicKey) Opt {
+	return func(o *portOpts) {
+		o.signingKey = key
+	}
+}
+
+// WithFSWatchAddr sets addr on which FTP port receives file events, defaults to ":4441".
+func WithFSWatchAddr(addr string) Opt {
+	return func(o *portOpts) {
+		o.fswatchAddr = addr
+	}
+}
+
+// WithSQLDriver sets database/sql driver name used by SQL port, defaults to "mysql".
+// The driver needs to be imported by the test.
+func WithSQLDriver(name string) Opt {
+	return func(o *portOpts) {
+		o.sqlDriver = name
+	}
+}
+
 type portOpts struct {
 	clientCertPath string
 
@@ -37,6 +61,11 @@ type portOpts struct {
 	err     error
 	timeout time.Duration
 
+	signingKey *rsa.PublicKey
+
+	fswatchAddr string
+	sqlDriver   string
+
 	t *testing.T
 }
 
@@ -51,5 +80,7 @@ func WithTLS() PortOpt {
 }
 
 var defaultPortOpts = portOpts{
-	timeout: time.Second * 3,
+	timeout:     time.Second * 3,
+	fswatchAddr: defaultFSWatchAddr,
+	sqlDriver:   "mysql",
 }
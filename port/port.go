@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"strings"
+	"sync"
 	"testing"
 
 	mtfctx "github.com/smallinsky/mtf/framework/context"
@@ -51,6 +52,49 @@ func (p *Port) Send(t *testing.T, i interface{}, opts ...SendOption) error {
 	return nil
 }
 
+// Close releases port resources like connections or dir watchers, it is no-op for
+// ports that don't hold any.
+func (p *Port) Close() error {
+	switch c := p.impl.(type) {
+	case interface{ Close() error }:
+		return c.Close()
+	case interface{ Close() }:
+		c.Close()
+	}
+	return nil
+}
+
+// resettable is implemented by ports keeping state between test cases.
+type resettable interface {
+	Reset(context.Context) error
+}
+
+var resetPorts = struct {
+	sync.Mutex
+	ports map[resettable]bool
+}{
+	ports: make(map[resettable]bool),
+}
+
+func registerReset(p resettable) {
+	resetPorts.Lock()
+	defer resetPorts.Unlock()
+	resetPorts.ports[p] = true
+}
+
+// Reset removes state left by previous test case in created ports, framework.Run
+// calls it before each test case.
+func Reset(ctx context.Context) error {
+	resetPorts.Lock()
+	defer resetPorts.Unlock()
+	for p := range resetPorts.ports {
+		if err := p.Reset(ctx); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func getPortName(i interface{}) string {
 	name := fmt.Sprintf("%T", i)
 	return fmt.Sprintf("%s", strings.ToLower(name))
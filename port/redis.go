@@ -0,0 +1,288 @@
+package port
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis"
+	"github.com/pkg/errors"
+)
+
+const (
+	defaultRedisAddr = "localhost:6379"
+	// redisResetChannel is published by Reset after keyspace events of components reset.
+	redisResetChannel = "mtf:reset"
+)
+
+type RedisPort struct {
+	client  *redis.Client
+	timeout time.Duration
+	// results keeps responses of key requests until they are received by the test.
+	results *resultQueue
+	eventC  chan *RedisKeyEvent
+	resetC  chan struct{}
+
+	mtx    sync.Mutex
+	pubsub *redis.PubSub
+}
+
+// NewRedisPort connects to the redis server, empty addr defaults to the MTF redis component.
+func NewRedisPort(addr, pass string, opts ...Opt) (*Port, error) {
+	p, err := NewRedis(addr, pass, opts...)
+	if err != nil {
+		return nil, err
+	}
+	registerReset(p)
+	return &Port{
+		impl: p,
+	}, nil
+}
+
+func NewRedis(addr, pass string, opts ...Opt) (*RedisPort, error) {
+	options := defaultPortOpts
+	for _, o := range opts {
+		o(&options)
+	}
+	if addr == "" {
+		addr = defaultRedisAddr
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     addr,
+		Password: pass,
+	})
+	if err := client.Ping().Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to connect to redis %q: %v", addr, err)
+	}
+
+	return &RedisPort{
+		client:  client,
+		timeout: options.timeout,
+		results: newResultQueue("redis"),
+		eventC:  make(chan *RedisKeyEvent, 64),
+		resetC:  make(chan struct{}, 1),
+	}, nil
+}
+
+// RedisCommand executes redis command e.g. []string{"SET", "key", "value"}.
+type RedisCommand struct {
+	Args []string
+}
+
+// RedisKeyRequest reads the key, the result is received as RedisKey.
+type RedisKeyRequest struct {
+	Key string
+}
+
+type RedisKey struct {
+	Key string
+	// Type is one of string, hash, list, set, zset or none if the key doesn't exist.
+	Type string
+	// Value is a string for string key, map[string]string for hash, []string for list,
+	// sorted []string for set and []string ordered by score for zset.
+	Value interface{}
+	// TTL is rounded to seconds, it is 0 when the key doesn't expire.
+	TTL time.Duration
+}
+
+// RedisWatchRequest subscribes for keyspace notifications of keys matching the
+// glob Pattern, each key change is received as RedisKeyEvent.
+type RedisWatchRequest struct {
+	Pattern string
+}
+
+type RedisKeyEvent struct {
+	Key string
+	// Event is a name of command or event that changed the key e.g. set, hset, del or expired.
+	Event string
+}
+
+func (p *RedisPort) Send(ctx context.Context, i interface{}) error {
+	switch msg := i.(type) {
+	case *RedisCommand:
+		args := make([]interface{}, len(msg.Args))
+		for i, a := range msg.Args {
+			args[i] = a
+		}
+		if err := p.client.Do(args...).Err(); err != nil && err != redis.Nil {
+			return fmt.Errorf("redis command %v failed: %v", msg.Args, err)
+		}
+	case *RedisKeyRequest:
+		resp, err := p.key(msg.Key)
+		if err != nil {
+			return err
+		}
+		return p.results.push(resp)
+	case *RedisWatchRequest:
+		return p.watch(msg.Pattern)
+	default:
+		return fmt.Errorf("RedisPort send doesn't support %T type", i)
+	}
+	return nil
+}
+
+func (p *RedisPort) key(key string) (*RedisKey, error) {
+	typ, err := p.client.Type(key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %q key type: %v", key, err)
+	}
+	resp := &RedisKey{
+		Key:  key,
+		Type: typ,
+	}
+	switch typ {
+	case "none":
+		return resp, nil
+	case "string":
+		resp.Value, err = p.client.Get(key).Result()
+	case "hash":
+		resp.Value, err = p.client.HGetAll(key).Result()
+	case "list":
+		resp.Value, err = p.client.LRange(key, 0, -1).Result()
+	case "set":
+		var members []string
+		members, err = p.client.SMembers(key).Result()
+		sort.Strings(members)
+		resp.Value = members
+	case "zset":
+		resp.Value, err = p.client.ZRange(key, 0, -1).Result()
+	default:
+		return nil, fmt.Errorf("unsupported %q key type %q", key, typ)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %q key: %v", key, err)
+	}
+
+	ttl, err := p.client.TTL(key).Result()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %q key ttl: %v", key, err)
+	}
+	if ttl > 0 {
+		resp.TTL = ttl.Round(time.Second)
+	}
+	return resp, nil
+}
+
+func (p *RedisPort) watch(pattern string) error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+
+	if err := p.client.ConfigSet("notify-keyspace-events", "KA").Err(); err != nil {
+		return fmt.Errorf("failed to enable keyspace notifications: %v", err)
+	}
+	channel := "__keyspace@*__:" + pattern
+	if p.pubsub != nil {
+		return p.pubsub.PSubscribe(channel)
+	}
+
+	pubsub := p.client.PSubscribe(channel)
+	// Wait for subscriptions confirmation, so changes made after Send are not missed.
+	if _, err := pubsub.Receive(); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe for %q keys events: %v", pattern, err)
+	}
+	if err := pubsub.Subscribe(redisResetChannel); err != nil {
+		pubsub.Close()
+		return err
+	}
+	if _, err := pubsub.Receive(); err != nil {
+		pubsub.Close()
+		return fmt.Errorf("failed to subscribe for reset events: %v", err)
+	}
+	p.pubsub = pubsub
+	go p.forwardEvents(pubsub.Channel())
+	return nil
+}
+
+// forwardEvents passes keyspace notifications to Receive, events are dropped when
+// the test doesn't receive them, so pubsub connection is never blocked.
+func (p *RedisPort) forwardEvents(ch <-chan *redis.Message) {
+	for msg := range ch {
+		if msg.Channel == redisResetChannel {
+			select {
+			case p.resetC <- struct{}{}:
+			default:
+			}
+			continue
+		}
+		i := strings.Index(msg.Channel, "__:")
+		if i < 0 {
+			continue
+		}
+		event := &RedisKeyEvent{
+			Key:   msg.Channel[i+len("__:"):],
+			Event: msg.Payload,
+		}
+		select {
+		case p.eventC <- event:
+		default:
+			log.Printf("[ERR] redis %q key %s event dropped, too many events waiting to be received", event.Key, event.Event)
+		}
+	}
+}
+
+// Reset drops key results and events not received by previous test case. Redis
+// delivers messages in order, so once the reset message published after components
+// reset, e.g. fixture load, is received, events of the reset were already forwarded.
+func (p *RedisPort) Reset(ctx context.Context) error {
+	p.mtx.Lock()
+	watching := p.pubsub != nil
+	p.mtx.Unlock()
+
+	if watching {
+		// Drop reset message left by previous Reset that timed out.
+		select {
+		case <-p.resetC:
+		default:
+		}
+		if err := p.client.Publish(redisResetChannel, "").Err(); err != nil {
+			return fmt.Errorf("failed to publish redis reset message: %v", err)
+		}
+		select {
+		case <-p.resetC:
+		case <-time.After(p.timeout):
+			return errors.Errorf("redis reset message wasn't received, deadline exceeded")
+		}
+	}
+	p.results.reset()
+	for {
+		select {
+		case <-p.eventC:
+		default:
+			return nil
+		}
+	}
+}
+
+// Receive returns pending key result first, otherwise waits for keyspace event.
+func (p *RedisPort) Receive(ctx context.Context) (interface{}, error) {
+	if msg, ok := p.results.pop(); ok {
+		return msg, nil
+	}
+
+	select {
+	case msg := <-p.eventC:
+		return msg, nil
+	case <-time.After(p.timeout):
+		return nil, errors.Errorf("failed to receive message, deadline exceeded")
+	}
+}
+
+// Close stops keyspace notifications and closes redis connection.
+func (p *RedisPort) Close() error {
+	p.mtx.Lock()
+	defer p.mtx.Unlock()
+	if p.pubsub != nil {
+		if err := p.pubsub.Close(); err != nil {
+			return err
+		}
+		p.pubsub = nil
+	}
+	return p.client.Close()
+}
@@ -0,0 +1,98 @@
+package port
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis"
+	"github.com/go-redis/redis"
+)
+
+func TestRedisKey(t *testing.T) {
+	s, err := miniredis.Run()
+	if err != nil {
+		t.Fatalf("failed to start redis: %v", err)
+	}
+	defer s.Close()
+
+	port, err := NewRedis(s.Addr(), "", WithTimeout(time.Millisecond*100))
+	if err != nil {
+		t.Fatalf("failed to create redis port: %v", err)
+	}
+	defer port.Close()
+	ctx := context.Background()
+
+	for _, args := range [][]string{
+		{"SET", "user:1", "bob"},
+		{"EXPIRE", "user:1", "30"},
+		{"HSET", "session:1", "user", "bob"},
+		{"RPUSH", "queue", "a", "b"},
+		{"SADD", "tags", "y", "x"},
+		{"ZADD", "rank", "2", "b", "1", "a"},
+	} {
+		if err := port.Send(ctx, &RedisCommand{Args: args}); err != nil {
+			t.Fatalf("failed to send command: %v", err)
+		}
+	}
+
+	for _, want := range []*RedisKey{
+		{Key: "user:1", Type: "string", Value: "bob", TTL: time.Second * 30},
+		{Key: "session:1", Type: "hash", Value: map[string]string{"user": "bob"}},
+		{Key: "queue", Type: "list", Value: []string{"a", "b"}},
+		{Key: "tags", Type: "set", Value: []string{"x", "y"}},
+		{Key: "rank", Type: "zset", Value: []string{"a", "b"}},
+		{Key: "missing", Type: "none"},
+	} {
+		if err := port.Send(ctx, &RedisKeyRequest{Key: want.Key}); err != nil {
+			t.Fatalf("failed to send key request: %v", err)
+		}
+		got, err := port.Receive(ctx)
+		if err != nil {
+			t.Fatalf("failed to receive key: %v", err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("key mismatch, got: %+v want: %+v", got, want)
+		}
+	}
+}
+
+func TestRedisEvents(t *testing.T) {
+	port := &RedisPort{
+		timeout: time.Millisecond * 100,
+		results: newResultQueue("redis"),
+		eventC:  make(chan *RedisKeyEvent, 1),
+		resetC:  make(chan struct{}, 1),
+	}
+
+	ch := make(chan *redis.Message, 3)
+	ch <- &redis.Message{Channel: "__keyspace@0__:user:1", Payload: "set"}
+	// Dropped, since the first event wasn't received.
+	ch <- &redis.Message{Channel: "__keyspace@0__:user:2", Payload: "set"}
+	ch <- &redis.Message{Channel: redisResetChannel}
+	close(ch)
+	port.forwardEvents(ch)
+
+	select {
+	case <-port.resetC:
+	default:
+		t.Fatalf("reset message wasn't forwarded")
+	}
+	got, err := port.Receive(context.Background())
+	if err != nil {
+		t.Fatalf("failed to receive event: %v", err)
+	}
+	if want := (&RedisKeyEvent{Key: "user:1", Event: "set"}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("event mismatch, got: %+v want: %+v", got, want)
+	}
+
+	port.eventC <- &RedisKeyEvent{Key: "user:3", Event: "del"}
+	port.results.push(&RedisKey{Key: "user:3", Type: "none"})
+	if err := port.Reset(context.Background()); err != nil {
+		t.Fatalf("failed to reset port: %v", err)
+	}
+	if got, err := port.Receive(context.Background()); err == nil {
+		t.Fatalf("expected no events after reset, got: %+v", got)
+	}
+}
@@ -0,0 +1,47 @@
+package port
+
+import (
+	"fmt"
+)
+
+// resultQueue keeps responses of port requests until they are received by the test.
+type resultQueue struct {
+	name string
+	c    chan interface{}
+}
+
+func newResultQueue(name string) *resultQueue {
+	return &resultQueue{
+		name: name,
+		c:    make(chan interface{}, 16),
+	}
+}
+
+// push fails if too many responses are waiting to be received.
+func (q *resultQueue) push(msg interface{}) error {
+	select {
+	case q.c <- msg:
+		return nil
+	default:
+		return fmt.Errorf("too many %s results waiting to be received", q.name)
+	}
+}
+
+// pop returns the oldest response without waiting, ok is false if there is none.
+func (q *resultQueue) pop() (msg interface{}, ok bool) {
+	select {
+	case msg := <-q.c:
+		return msg, true
+	default:
+		return nil, false
+	}
+}
+
+// reset drops responses not received by the test.
+func (q *resultQueue) reset() {
+	for {
+		if _, ok := q.pop(); !ok {
+			return
+		}
+	}
+}
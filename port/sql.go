@@ -0,0 +1,341 @@
+package port
+
+import (
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// SQLNull is a CSV fixture value inserted as NULL.
+const SQLNull = `\N`
+
+type SQLPort struct {
+	db *sql.DB
+	// placeholder returns query arg placeholder for the driver.
+	placeholder func(n int) string
+	// results keeps responses of select requests until they are received by the test.
+	results *resultQueue
+}
+
+// NewSQLPort connects to the database, the driver is set by WithSQLDriver and defaults to mysql.
+// The driver isn't imported by the port, the test needs to import it e.g.
+// _ "github.com/go-sql-driver/mysql".
+func NewSQLPort(dsn string, opts ...Opt) (*Port, error) {
+	p, err := NewSQL(dsn, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &Port{
+		impl: p,
+	}, nil
+}
+
+func NewSQL(dsn string, opts ...Opt) (*SQLPort, error) {
+	options := defaultPortOpts
+	for _, o := range opts {
+		o(&options)
+	}
+	db, err := sql.Open(options.sqlDriver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s db: %v", options.sqlDriver, err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), options.timeout)
+	defer cancel()
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to connect to %s db: %v", options.sqlDriver, err)
+	}
+	return newSQLPort(db, options.sqlDriver), nil
+}
+
+func newSQLPort(db *sql.DB, driver string) *SQLPort {
+	placeholder := func(int) string { return "?" }
+	if driver == "postgres" || driver == "pgx" {
+		placeholder = func(n int) string { return "$" + strconv.Itoa(n) }
+	}
+	return &SQLPort{
+		db:          db,
+		placeholder: placeholder,
+		results:     newResultQueue("sql"),
+	}
+}
+
+// SQLExec executes the statement, e.g. to insert fixture rows.
+type SQLExec struct {
+	Query string
+	Args  []interface{}
+}
+
+// SQLFixture loads rows from the YAML or CSV File in a single transaction.
+// YAML file maps table names to lists of rows:
+//
+//	users:
+//	  - id: 1
+//	    name: bob
+//
+// CSV file header contains column names, SQLNull values are inserted as NULL.
+type SQLFixture struct {
+	File string
+	// Table is used for CSV file, defaults to the file name without extension.
+	Table string
+}
+
+// SQLSelectRequest queries the Table, the result is received as SQLRows.
+// Query can be used instead of Table to run custom select statement.
+type SQLSelectRequest struct {
+	Table   string
+	Columns []string
+	// Where is a condition with Args placeholders, e.g. "user_id = ?".
+	Where   string
+	OrderBy string
+	Query   string
+	Args    []interface{}
+}
+
+type SQLRows struct {
+	Table string
+	Rows  []SQLRow
+}
+
+// SQLRow maps column names to values, NULL is represented as nil and all other
+// values as strings in the database text format.
+type SQLRow map[string]interface{}
+
+func (p *SQLPort) Send(ctx context.Context, i interface{}) error {
+	switch msg := i.(type) {
+	case *SQLExec:
+		if _, err := p.db.ExecContext(ctx, msg.Query, msg.Args...); err != nil {
+			return fmt.Errorf("failed to exec %q: %v", msg.Query, err)
+		}
+	case *SQLFixture:
+		return p.loadFixture(ctx, msg)
+	case *SQLSelectRequest:
+		resp, err := p.query(ctx, msg)
+		if err != nil {
+			return err
+		}
+		return p.results.push(resp)
+	default:
+		return fmt.Errorf("SQLPort send doesn't support %T type", i)
+	}
+	return nil
+}
+
+// Receive returns result of the oldest select request.
+func (p *SQLPort) Receive(ctx context.Context) (interface{}, error) {
+	msg, ok := p.results.pop()
+	if !ok {
+		return nil, errors.Errorf("failed to receive message, SQLSelectRequest wasn't sent")
+	}
+	return msg, nil
+}
+
+// Close closes the database connection.
+func (p *SQLPort) Close() error {
+	return p.db.Close()
+}
+
+func (p *SQLPort) query(ctx context.Context, req *SQLSelectRequest) (*SQLRows, error) {
+	query := req.Query
+	if query == "" {
+		if req.Table == "" {
+			return nil, fmt.Errorf("either Table or Query needs to be set")
+		}
+		columns := "*"
+		if len(req.Columns) != 0 {
+			columns = strings.Join(req.Columns, ", ")
+		}
+		query = fmt.Sprintf("SELECT %s FROM %s", columns, req.Table)
+		if req.Where != "" {
+			query += " WHERE " + req.Where
+		}
+		if req.OrderBy != "" {
+			query += " ORDER BY " + req.OrderBy
+		}
+	}
+
+	rows, err := p.db.QueryContext(ctx, query, req.Args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %q: %v", query, err)
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	resp := &SQLRows{
+		Table: req.Table,
+	}
+	for rows.Next() {
+		values := make([]interface{}, len(columns))
+		ptrs := make([]interface{}, len(columns))
+		for i := range values {
+			ptrs[i] = &values[i]
+		}
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, fmt.Errorf("failed to scan %q row: %v", query, err)
+		}
+		row := make(SQLRow, len(columns))
+		for i, c := range columns {
+			row[c] = sqlText(values[i])
+		}
+		resp.Rows = append(resp.Rows, row)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %q rows: %v", query, err)
+	}
+	return resp, nil
+}
+
+func sqlText(v interface{}) interface{} {
+	switch v := v.(type) {
+	case nil:
+		return nil
+	case []byte:
+		return string(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// fixtureTable contains rows inserted into a single table.
+type fixtureTable struct {
+	name    string
+	columns []string
+	rows    [][]interface{}
+}
+
+func (p *SQLPort) loadFixture(ctx context.Context, f *SQLFixture) error {
+	var (
+		tables []fixtureTable
+		err    error
+	)
+	switch ext := strings.ToLower(filepath.Ext(f.File)); ext {
+	case ".yaml", ".yml":
+		tables, err = readYAMLFixture(f.File)
+	case ".csv":
+		table := f.Table
+		if table == "" {
+			table = strings.TrimSuffix(filepath.Base(f.File), filepath.Ext(f.File))
+		}
+		tables, err = readCSVFixture(f.File, table)
+	default:
+		return fmt.Errorf("unsupported %q fixture file format", ext)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read %q fixture: %v", f.File, err)
+	}
+
+	tx, err := p.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	for _, t := range tables {
+		for _, row := range t.rows {
+			if _, err := tx.ExecContext(ctx, p.insertQuery(t), row...); err != nil {
+				tx.Rollback()
+				return fmt.Errorf("failed to insert %q fixture row: %v", t.name, err)
+			}
+		}
+	}
+	return tx.Commit()
+}
+
+func (p *SQLPort) insertQuery(t fixtureTable) string {
+	placeholders := make([]string, len(t.columns))
+	for i := range placeholders {
+		placeholders[i] = p.placeholder(i + 1)
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
+		t.name, strings.Join(t.columns, ", "), strings.Join(placeholders, ", "))
+}
+
+func readYAMLFixture(path string) ([]fixtureTable, error) {
+	buff, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	// MapSlice keeps tables order, so rows referenced by foreign keys can be inserted first.
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(buff, &doc); err != nil {
+		return nil, err
+	}
+
+	var tables []fixtureTable
+	for _, item := range doc {
+		rows, ok := item.Value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v table rows should be a list", item.Key)
+		}
+		for _, r := range rows {
+			row, ok := r.(yaml.MapSlice)
+			if !ok {
+				return nil, fmt.Errorf("%v table row should be a map", item.Key)
+			}
+			t := fixtureTable{
+				name: fmt.Sprint(item.Key),
+			}
+			// Columns are sorted to make insert statements deterministic.
+			sort.Slice(row, func(i, j int) bool {
+				return fmt.Sprint(row[i].Key) < fmt.Sprint(row[j].Key)
+			})
+			var values []interface{}
+			for _, c := range row {
+				t.columns = append(t.columns, fmt.Sprint(c.Key))
+				values = append(values, c.Value)
+			}
+			t.rows = [][]interface{}{values}
+			tables = append(tables, t)
+		}
+	}
+	return tables, nil
+}
+
+func readCSVFixture(path, table string) ([]fixtureTable, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	records, err := csv.NewReader(f).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("missing header")
+	}
+	t := fixtureTable{
+		name:    table,
+		columns: records[0],
+	}
+	for _, record := range records[1:] {
+		values := make([]interface{}, len(record))
+		for i, v := range record {
+			if v != SQLNull {
+				values[i] = v
+			}
+		}
+		t.rows = append(t.rows, values)
+	}
+	return []fixtureTable{t}, nil
+}
@@ -0,0 +1,73 @@
+package port
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestSQLPort(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("failed to create db mock: %v", err)
+	}
+	p := newSQLPort(db, "mysql")
+	defer p.Close()
+	ctx := context.Background()
+
+	dir, err := ioutil.TempDir("", "sqlport")
+	if err != nil {
+		t.Fatalf("failed to create tmp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	yamlFile := filepath.Join(dir, "fixture.yaml")
+	ioutil.WriteFile(yamlFile, []byte("users:\n  - name: bob\n    id: 1\norders:\n  - id: 10\n    user_id: 1\n"), 0644)
+	csvFile := filepath.Join(dir, "items.csv")
+	ioutil.WriteFile(csvFile, []byte("id,note\n1,\\N\n2,fragile\n"), 0644)
+
+	mock.ExpectExec("DELETE FROM users").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO users (id, name) VALUES (?, ?)").WithArgs(1, "bob").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO orders (id, user_id) VALUES (?, ?)").WithArgs(10, 1).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec("INSERT INTO items (id, note) VALUES (?, ?)").WithArgs("1", nil).WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectExec("INSERT INTO items (id, note) VALUES (?, ?)").WithArgs("2", "fragile").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+	mock.ExpectQuery("SELECT id, name FROM users WHERE id = ? ORDER BY id").WithArgs(1).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "name"}).AddRow([]byte("1"), []byte("bob")).AddRow(int64(2), nil))
+
+	for _, msg := range []interface{}{
+		&SQLExec{Query: "DELETE FROM users"},
+		&SQLFixture{File: yamlFile},
+		&SQLFixture{File: csvFile},
+		&SQLSelectRequest{Table: "users", Columns: []string{"id", "name"}, Where: "id = ?", OrderBy: "id", Args: []interface{}{1}},
+	} {
+		if err := p.Send(ctx, msg); err != nil {
+			t.Fatalf("failed to send %T: %v", msg, err)
+		}
+	}
+
+	got, err := p.Receive(ctx)
+	if err != nil {
+		t.Fatalf("failed to receive rows: %v", err)
+	}
+	want := &SQLRows{
+		Table: "users",
+		Rows: []SQLRow{
+			{"id": "1", "name": "bob"},
+			{"id": "2", "name": nil},
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("rows mismatch, got: %+v want: %+v", got, want)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet db expectations: %v", err)
+	}
+}
@@ -9,6 +9,8 @@ import (
 	proto "github.com/golang/protobuf/proto"
 	empty "github.com/golang/protobuf/ptypes/empty"
 	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
 	math "math"
 )
 
@@ -26,10 +28,12 @@ const _ = proto.ProtoPackageIsVersion3 // please upgrade the proto package
 type Action int32
 
 const (
-	Action_UNKNOWN Action = 0
-	Action_ADDED   Action = 1
-	Action_REMOVED Action = 2
-	Action_EDITED  Action = 3
+	Action_UNKNOWN      Action = 0
+	Action_ADDED        Action = 1
+	Action_REMOVED      Action = 2
+	Action_EDITED       Action = 3
+	Action_RENAMED      Action = 4
+	Action_MODE_CHANGED Action = 5
 )
 
 var Action_name = map[int32]string{
@@ -37,13 +41,17 @@ var Action_name = map[int32]string{
 	1: "ADDED",
 	2: "REMOVED",
 	3: "EDITED",
+	4: "RENAMED",
+	5: "MODE_CHANGED",
 }
 
 var Action_value = map[string]int32{
-	"UNKNOWN": 0,
-	"ADDED":   1,
-	"REMOVED": 2,
-	"EDITED":  3,
+	"UNKNOWN":      0,
+	"ADDED":        1,
+	"REMOVED":      2,
+	"EDITED":       3,
+	"RENAMED":      4,
+	"MODE_CHANGED": 5,
 }
 
 func (x Action) String() string {
@@ -58,6 +66,7 @@ type EventRequest struct {
 	Path                 string   `protobuf:"bytes,1,opt,name=path,proto3" json:"path,omitempty"`
 	Action               Action   `protobuf:"varint,2,opt,name=action,proto3,enum=watcher.Action" json:"action,omitempty"`
 	Content              []byte   `protobuf:"bytes,3,opt,name=content,proto3" json:"content,omitempty"`
+	Seq                  uint64   `protobuf:"varint,4,opt,name=seq,proto3" json:"seq,omitempty"`
 	XXX_NoUnkeyedLiteral struct{} `json:"-"`
 	XXX_unrecognized     []byte   `json:"-"`
 	XXX_sizecache        int32    `json:"-"`
@@ -109,30 +118,132 @@ func (m *EventRequest) GetContent() []byte {
 	return nil
 }
 
+func (m *EventRequest) GetSeq() uint64 {
+	if m != nil {
+		return m.Seq
+	}
+	return 0
+}
+
+type EventBatch struct {
+	PublisherId          string          `protobuf:"bytes,1,opt,name=publisher_id,json=publisherId,proto3" json:"publisher_id,omitempty"`
+	Events               []*EventRequest `protobuf:"bytes,2,rep,name=events,proto3" json:"events,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}        `json:"-"`
+	XXX_unrecognized     []byte          `json:"-"`
+	XXX_sizecache        int32           `json:"-"`
+}
+
+func (m *EventBatch) Reset()         { *m = EventBatch{} }
+func (m *EventBatch) String() string { return proto.CompactTextString(m) }
+func (*EventBatch) ProtoMessage()    {}
+func (*EventBatch) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f367c5ca26d9ac38, []int{1}
+}
+
+func (m *EventBatch) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_EventBatch.Unmarshal(m, b)
+}
+func (m *EventBatch) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_EventBatch.Marshal(b, m, deterministic)
+}
+func (m *EventBatch) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_EventBatch.Merge(m, src)
+}
+func (m *EventBatch) XXX_Size() int {
+	return xxx_messageInfo_EventBatch.Size(m)
+}
+func (m *EventBatch) XXX_DiscardUnknown() {
+	xxx_messageInfo_EventBatch.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_EventBatch proto.InternalMessageInfo
+
+func (m *EventBatch) GetPublisherId() string {
+	if m != nil {
+		return m.PublisherId
+	}
+	return ""
+}
+
+func (m *EventBatch) GetEvents() []*EventRequest {
+	if m != nil {
+		return m.Events
+	}
+	return nil
+}
+
+type Ack struct {
+	Seq                  uint64   `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Ack) Reset()         { *m = Ack{} }
+func (m *Ack) String() string { return proto.CompactTextString(m) }
+func (*Ack) ProtoMessage()    {}
+func (*Ack) Descriptor() ([]byte, []int) {
+	return fileDescriptor_f367c5ca26d9ac38, []int{2}
+}
+
+func (m *Ack) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Ack.Unmarshal(m, b)
+}
+func (m *Ack) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Ack.Marshal(b, m, deterministic)
+}
+func (m *Ack) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Ack.Merge(m, src)
+}
+func (m *Ack) XXX_Size() int {
+	return xxx_messageInfo_Ack.Size(m)
+}
+func (m *Ack) XXX_DiscardUnknown() {
+	xxx_messageInfo_Ack.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Ack proto.InternalMessageInfo
+
+func (m *Ack) GetSeq() uint64 {
+	if m != nil {
+		return m.Seq
+	}
+	return 0
+}
+
 func init() {
 	proto.RegisterEnum("watcher.Action", Action_name, Action_value)
 	proto.RegisterType((*EventRequest)(nil), "watcher.EventRequest")
+	proto.RegisterType((*EventBatch)(nil), "watcher.EventBatch")
+	proto.RegisterType((*Ack)(nil), "watcher.Ack")
 }
 
 func init() { proto.RegisterFile("watcher.proto", fileDescriptor_f367c5ca26d9ac38) }
 
 var fileDescriptor_f367c5ca26d9ac38 = []byte{
-	// 234 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x4c, 0x8f, 0x41, 0x4b, 0xc3, 0x40,
-	0x10, 0x85, 0xdd, 0xd6, 0x26, 0x74, 0xac, 0x1a, 0x06, 0x94, 0xa5, 0x5e, 0x82, 0x17, 0x83, 0x87,
-	0x2d, 0x54, 0x10, 0x3c, 0x06, 0x76, 0x0f, 0x22, 0xa6, 0xb0, 0xa8, 0x3d, 0xa7, 0x61, 0x6c, 0x05,
-	0xdd, 0x8d, 0x75, 0xaa, 0xf8, 0xef, 0xa5, 0xbb, 0x8d, 0xf4, 0x36, 0xef, 0xcd, 0x83, 0xf7, 0x3e,
-	0x38, 0xfe, 0xa9, 0xb9, 0x59, 0xd1, 0x5a, 0xb5, 0x6b, 0xcf, 0x1e, 0xd3, 0x9d, 0x1c, 0x5f, 0x2c,
-	0xbd, 0x5f, 0xbe, 0xd3, 0x24, 0xd8, 0x8b, 0xcd, 0xeb, 0x84, 0x3e, 0x5a, 0xfe, 0x8d, 0xa9, 0x4b,
-	0x82, 0x91, 0xf9, 0x26, 0xc7, 0x96, 0x3e, 0x37, 0xf4, 0xc5, 0x88, 0x70, 0xd8, 0xd6, 0xbc, 0x92,
-	0x22, 0x17, 0xc5, 0xd0, 0x86, 0x1b, 0xaf, 0x20, 0xa9, 0x1b, 0x7e, 0xf3, 0x4e, 0xf6, 0x72, 0x51,
-	0x9c, 0x4c, 0x4f, 0x55, 0xd7, 0x54, 0x06, 0xdb, 0xee, 0xde, 0x28, 0x21, 0x6d, 0xbc, 0x63, 0x72,
-	0x2c, 0xfb, 0xb9, 0x28, 0x46, 0xb6, 0x93, 0xd7, 0x77, 0x90, 0xc4, 0x2c, 0x1e, 0x41, 0xfa, 0x5c,
-	0x3d, 0x54, 0xb3, 0x79, 0x95, 0x1d, 0xe0, 0x10, 0x06, 0xa5, 0xd6, 0x46, 0x67, 0x62, 0xeb, 0x5b,
-	0xf3, 0x38, 0x7b, 0x31, 0x3a, 0xeb, 0x21, 0x40, 0x62, 0xf4, 0xfd, 0x93, 0xd1, 0x59, 0x7f, 0x5a,
-	0x42, 0x3a, 0x8f, 0x75, 0x78, 0x0b, 0x83, 0x30, 0x16, 0xcf, 0xfe, 0x17, 0xec, 0x8f, 0x1f, 0x9f,
-	0xab, 0x88, 0xaa, 0x3a, 0x54, 0x65, 0xb6, 0xa8, 0x8b, 0x24, 0xe8, 0x9b, 0xbf, 0x00, 0x00, 0x00,
-	0xff, 0xff, 0x35, 0xe4, 0xe5, 0xfd, 0x22, 0x01, 0x00, 0x00,
+	// 344 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x6c, 0x51, 0xdf, 0x4f, 0xe2, 0x40,
+	0x10, 0xbe, 0xa5, 0xbf, 0xc2, 0xd0, 0xbb, 0xdb, 0xcc, 0xe5, 0xee, 0x1a, 0xee, 0xa5, 0xc7, 0x8b,
+	0x8d, 0x89, 0xc5, 0x60, 0xe2, 0x7b, 0x75, 0x37, 0x4a, 0x0c, 0x25, 0x69, 0x54, 0x7c, 0x92, 0x40,
+	0x59, 0x81, 0x80, 0x6d, 0xa1, 0x8b, 0xc4, 0xff, 0xde, 0x74, 0xdb, 0x12, 0x4c, 0x7c, 0xdb, 0xf9,
+	0x66, 0xbe, 0xf9, 0xbe, 0x9d, 0x0f, 0xbe, 0xef, 0x27, 0x32, 0x5e, 0x88, 0xad, 0x9f, 0x6d, 0x53,
+	0x99, 0xa2, 0x55, 0x95, 0xed, 0x7f, 0xf3, 0x34, 0x9d, 0xaf, 0x45, 0x57, 0xc1, 0xd3, 0xdd, 0x4b,
+	0x57, 0xbc, 0x66, 0xf2, 0xbd, 0x9c, 0xea, 0xec, 0xc1, 0xe6, 0x6f, 0x22, 0x91, 0x91, 0xd8, 0xec,
+	0x44, 0x2e, 0x11, 0x41, 0xcf, 0x26, 0x72, 0xe1, 0x10, 0x97, 0x78, 0xcd, 0x48, 0xbd, 0xf1, 0x04,
+	0xcc, 0x49, 0x2c, 0x97, 0x69, 0xe2, 0x34, 0x5c, 0xe2, 0xfd, 0xe8, 0xfd, 0xf4, 0x6b, 0xa5, 0x40,
+	0xc1, 0x51, 0xd5, 0x46, 0x07, 0xac, 0x38, 0x4d, 0xa4, 0x48, 0xa4, 0xa3, 0xb9, 0xc4, 0xb3, 0xa3,
+	0xba, 0x44, 0x0a, 0x5a, 0x2e, 0x36, 0x8e, 0xee, 0x12, 0x4f, 0x8f, 0x8a, 0x67, 0xe7, 0x19, 0x40,
+	0x09, 0x5f, 0x15, 0xab, 0xf0, 0x3f, 0xd8, 0xd9, 0x6e, 0xba, 0x5e, 0xe6, 0x0b, 0xb1, 0x1d, 0x2f,
+	0x67, 0x95, 0x7c, 0xeb, 0x80, 0xf5, 0x67, 0x78, 0x06, 0xa6, 0x28, 0x08, 0xb9, 0xd3, 0x70, 0x35,
+	0xaf, 0xd5, 0xfb, 0x7d, 0x70, 0x71, 0xfc, 0x81, 0xa8, 0x1a, 0xea, 0xfc, 0x05, 0x2d, 0x88, 0x57,
+	0xb5, 0x30, 0x39, 0x08, 0x9f, 0x3e, 0x81, 0x59, 0xda, 0xc6, 0x16, 0x58, 0x0f, 0xe1, 0x5d, 0x38,
+	0x1c, 0x85, 0xf4, 0x1b, 0x36, 0xc1, 0x08, 0x18, 0xe3, 0x8c, 0x92, 0x02, 0x8f, 0xf8, 0x60, 0xf8,
+	0xc8, 0x19, 0x6d, 0x20, 0x80, 0xc9, 0x59, 0xff, 0x9e, 0x33, 0xaa, 0x95, 0x8d, 0x30, 0x18, 0x70,
+	0x46, 0x75, 0xa4, 0x60, 0x0f, 0x86, 0x8c, 0x8f, 0xaf, 0x6f, 0x83, 0xf0, 0x86, 0x33, 0x6a, 0xf4,
+	0x36, 0x60, 0x8d, 0x4a, 0x4b, 0x78, 0x09, 0x86, 0x72, 0x85, 0x5f, 0xbb, 0x6c, 0xff, 0xf1, 0xcb,
+	0x50, 0xfc, 0x3a, 0x14, 0x9f, 0x17, 0xa1, 0xa0, 0x0f, 0x86, 0x5a, 0x81, 0xbf, 0x3e, 0xf3, 0xd4,
+	0x95, 0xda, 0xf6, 0xd1, 0xe1, 0x57, 0x1e, 0x39, 0x27, 0x53, 0x53, 0xf1, 0x2f, 0x3e, 0x06, 0x00,
+	0x4b, 0x8a, 0xb6, 0x8b, 0xfc, 0x01, 0x00, 0x00,
 }
 
 // Reference imports to suppress errors if they are not otherwise used.
@@ -148,6 +259,7 @@ const _ = grpc.SupportPackageIsVersion4
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
 type WatcherClient interface {
 	Event(ctx context.Context, in *EventRequest, opts ...grpc.CallOption) (*empty.Empty, error)
+	Watch(ctx context.Context, opts ...grpc.CallOption) (Watcher_WatchClient, error)
 }
 
 type watcherClient struct {
@@ -167,9 +279,52 @@ func (c *watcherClient) Event(ctx context.Context, in *EventRequest, opts ...grp
 	return out, nil
 }
 
+func (c *watcherClient) Watch(ctx context.Context, opts ...grpc.CallOption) (Watcher_WatchClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_Watcher_serviceDesc.Streams[0], "/watcher.Watcher/Watch", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &watcherWatchClient{stream}
+	return x, nil
+}
+
+type Watcher_WatchClient interface {
+	Send(*EventBatch) error
+	Recv() (*Ack, error)
+	grpc.ClientStream
+}
+
+type watcherWatchClient struct {
+	grpc.ClientStream
+}
+
+func (x *watcherWatchClient) Send(m *EventBatch) error {
+	return x.ClientStream.SendMsg(m)
+}
+
+func (x *watcherWatchClient) Recv() (*Ack, error) {
+	m := new(Ack)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 // WatcherServer is the server API for Watcher service.
 type WatcherServer interface {
 	Event(context.Context, *EventRequest) (*empty.Empty, error)
+	Watch(Watcher_WatchServer) error
+}
+
+// UnimplementedWatcherServer can be embedded to have forward compatible implementations.
+type UnimplementedWatcherServer struct {
+}
+
+func (*UnimplementedWatcherServer) Event(ctx context.Context, req *EventRequest) (*empty.Empty, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Event not implemented")
+}
+func (*UnimplementedWatcherServer) Watch(srv Watcher_WatchServer) error {
+	return status.Errorf(codes.Unimplemented, "method Watch not implemented")
 }
 
 func RegisterWatcherServer(s *grpc.Server, srv WatcherServer) {
@@ -194,6 +349,32 @@ func _Watcher_Event_Handler(srv interface{}, ctx context.Context, dec func(inter
 	return interceptor(ctx, in, info, handler)
 }
 
+func _Watcher_Watch_Handler(srv interface{}, stream grpc.ServerStream) error {
+	return srv.(WatcherServer).Watch(&watcherWatchServer{stream})
+}
+
+type Watcher_WatchServer interface {
+	Send(*Ack) error
+	Recv() (*EventBatch, error)
+	grpc.ServerStream
+}
+
+type watcherWatchServer struct {
+	grpc.ServerStream
+}
+
+func (x *watcherWatchServer) Send(m *Ack) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+func (x *watcherWatchServer) Recv() (*EventBatch, error) {
+	m := new(EventBatch)
+	if err := x.ServerStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
 var _Watcher_serviceDesc = grpc.ServiceDesc{
 	ServiceName: "watcher.Watcher",
 	HandlerType: (*WatcherServer)(nil),
@@ -203,6 +384,13 @@ var _Watcher_serviceDesc = grpc.ServiceDesc{
 			Handler:    _Watcher_Event_Handler,
 		},
 	},
-	Streams:  []grpc.StreamDesc{},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "Watch",
+			Handler:       _Watcher_Watch_Handler,
+			ServerStreams: true,
+			ClientStreams: true,
+		},
+	},
 	Metadata: "watcher.proto",
 }